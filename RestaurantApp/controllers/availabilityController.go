@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+)
+
+// availabilityRequest marks an item unavailable or available again. Without an until time,
+// an item marked unavailable stays off for the rest of the current service.
+type availabilityRequest struct {
+	Available *bool      `json:"available"`
+	Until     *time.Time `json:"until"`
+	Reason    string     `json:"reason"`
+}
+
+// resolveUnavailableUntil validates an availability request and returns the time the item is unavailable until
+func resolveUnavailableUntil(c *gin.Context) (*time.Time, string, bool) {
+	var request availabilityRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.Available == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid availability data. The available flag is required."})
+		return nil, "", false
+	}
+
+	if *request.Available {
+		return nil, "", true
+	}
+
+	now := time.Now()
+	until := helpers.EndOfService(now)
+	if request.Until != nil {
+		if !request.Until.After(now) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The unavailable until time must be in the future"})
+			return nil, "", false
+		}
+		until = *request.Until
+	}
+
+	return &until, request.Reason, true
+}
+
+// UpdateFoodAvailability 86's a food until a given time or the end of service, or makes it available again (admin only)
+func UpdateFoodAvailability() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to change food availability"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		until, reason, ok := resolveUnavailableUntil(c)
+		if !ok {
+			return
+		}
+
+		var food models.Food
+		if err := databases.DB.WithContext(ctx).Where("food_id = ?", c.Param("food_id")).First(&food).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The food item you're trying to update could not be found"})
+			return
+		}
+
+		food.UnavailableUntil = until
+		food.UnavailableReason = reason
+		if err := databases.DB.WithContext(ctx).Model(&food).Select("unavailable_until", "unavailable_reason").Updates(&food).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update food availability. Please try again later."})
+			return
+		}
+
+		helpers.PublishAvailabilityChange(helpers.FoodAvailabilityChange(&food))
+
+		c.JSON(http.StatusOK, food)
+	}
+}
+
+// UpdateModifierAvailability 86's a modifier until a given time or the end of service, or makes it available again (admin only)
+func UpdateModifierAvailability() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to change modifier availability"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		until, reason, ok := resolveUnavailableUntil(c)
+		if !ok {
+			return
+		}
+
+		var modifier models.Modifier
+		if err := databases.DB.WithContext(ctx).Where("modifier_id = ?", c.Param("modifier_id")).First(&modifier).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The modifier you're trying to update could not be found"})
+			return
+		}
+
+		modifier.UnavailableUntil = until
+		modifier.UnavailableReason = reason
+		if err := databases.DB.WithContext(ctx).Model(&modifier).Select("unavailable_until", "unavailable_reason").Updates(&modifier).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update modifier availability. Please try again later."})
+			return
+		}
+
+		helpers.PublishAvailabilityChange(helpers.ModifierAvailabilityChange(&modifier))
+
+		c.JSON(http.StatusOK, modifier)
+	}
+}
+
+// loadUnavailableItems lists every food and modifier that cannot be ordered right now
+func loadUnavailableItems(ctx context.Context) ([]helpers.AvailabilityChange, error) {
+	now := time.Now()
+
+	var foods []models.Food
+	if err := helpers.FilterAvailableFoods(databases.DB.WithContext(ctx), false).Order("name ASC").Find(&foods).Error; err != nil {
+		return nil, err
+	}
+
+	var modifiers []models.Modifier
+	if err := databases.DB.WithContext(ctx).Where("unavailable_until > ?", now).Order("name ASC").Find(&modifiers).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]helpers.AvailabilityChange, 0, len(foods)+len(modifiers))
+	for i := range foods {
+		items = append(items, helpers.FoodAvailabilityChange(&foods[i]))
+	}
+	for i := range modifiers {
+		items = append(items, helpers.ModifierAvailabilityChange(&modifiers[i]))
+	}
+	return items, nil
+}
+
+// GetUnavailableItems lists the foods and modifiers that are currently 86'd or out of stock
+func GetUnavailableItems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		items, err := loadUnavailableItems(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve unavailable items. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, items)
+	}
+}
+
+// StreamAvailability streams availability changes using Server-Sent Events. Each connection starts
+// with a snapshot of everything currently unavailable, followed by live changes.
+func StreamAvailability() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Subscribe before taking the snapshot so no change committed in between is missed
+		live, unsubscribe := helpers.AvailabilityBroker.Subscribe()
+		defer unsubscribe()
+
+		ctx := c.Request.Context()
+
+		items, err := loadUnavailableItems(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve unavailable items. Please try again later."})
+			return
+		}
+
+		snapshot, err := helpers.NewAvailabilityEvent(helpers.AvailabilityEventSnapshot, items)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve unavailable items. Please try again later."})
+			return
+		}
+
+		c.Writer.Header().Set("Content-Type", "text/event-stream")
+		c.Writer.Header().Set("Cache-Control", "no-cache")
+		c.Writer.Header().Set("Connection", "keep-alive")
+		c.Writer.Header().Set("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		writeStreamEvent(c.Writer, snapshot)
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+				return true
+			case event, ok := <-live:
+				if !ok {
+					// Dropped for falling behind; the client reconnects and gets a fresh snapshot
+					return false
+				}
+				writeStreamEvent(w, event)
+				return true
+			}
+		})
+	}
+}
@@ -0,0 +1,420 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// preloadBundleSlots loads a bundle's slots and their options in the order they were defined
+func preloadBundleSlots(db *gorm.DB) *gorm.DB {
+	return db.Preload("Slots", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Preload("Slots.Options", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") })
+}
+
+// validateBundleSlots checks that every slot is named and offers only existing foods in the bundle's currency
+func validateBundleSlots(tx *gorm.DB, bundle *models.Bundle) error {
+	for i := range bundle.Slots {
+		slot := &bundle.Slots[i]
+		if slot.Name == "" || len(slot.Options) == 0 {
+			return helpers.NewRequestError(http.StatusBadRequest, "Every bundle slot needs a name and at least one option")
+		}
+
+		slot.SlotID = ""
+		slot.BundleID = bundle.BundleID
+		for j := range slot.Options {
+			option := &slot.Options[j]
+			option.OptionID = ""
+			option.SlotID = ""
+
+			var food models.Food
+			if err := tx.Preload("Variants").Where("food_id = ?", option.FoodID).First(&food).Error; err != nil {
+				return helpers.NewRequestError(http.StatusBadRequest, "A bundle option refers to a food item that does not exist")
+			}
+
+			if food.Currency != bundle.Currency {
+				return helpers.NewRequestError(http.StatusBadRequest, "Bundle options must be priced in the bundle's currency")
+			}
+
+			if option.VariantID != "" {
+				found := false
+				for _, variant := range food.Variants {
+					found = found || variant.VariantID == option.VariantID
+				}
+				if !found {
+					return helpers.NewRequestError(http.StatusBadRequest, "A bundle option refers to a variant that does not belong to its food item")
+				}
+			}
+			option.PriceDelta = option.PriceDelta.Round(bundle.Currency)
+		}
+	}
+	return nil
+}
+
+// deleteBundleSlots removes the slots and options of a bundle
+func deleteBundleSlots(tx *gorm.DB, bundleId string) error {
+	slotIds := tx.Model(&models.BundleSlot{}).Select("slot_id").Where("bundle_id = ?", bundleId)
+	if err := tx.Where("slot_id IN (?)", slotIds).Delete(&models.BundleSlotOption{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("bundle_id = ?", bundleId).Delete(&models.BundleSlot{}).Error
+}
+
+// GetBundles retrieves all bundles with their slots and options
+func GetBundles() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		pagination := helpers.GetPaginationParams(c)
+		offset := helpers.GetOffset(pagination.Page, pagination.Limit)
+
+		var bundles []models.Bundle
+		var total int64
+
+		if err := databases.DB.WithContext(ctx).Model(&models.Bundle{}).Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to count bundles"})
+			return
+		}
+
+		if err := preloadBundleSlots(databases.DB.WithContext(ctx)).
+			Order("name ASC").
+			Offset(offset).
+			Limit(pagination.Limit).
+			Find(&bundles).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve bundles. Please try again later."})
+			return
+		}
+
+		paginationInfo := helpers.CreatePaginationResponse(pagination.Page, pagination.Limit, total)
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":       bundles,
+			"pagination": paginationInfo,
+		})
+	}
+}
+
+// GetBundle retrieves a specific bundle by ID
+func GetBundle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var bundle models.Bundle
+		if err := preloadBundleSlots(databases.DB.WithContext(ctx)).Where("bundle_id = ?", c.Param("bundle_id")).First(&bundle).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested bundle could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, bundle)
+	}
+}
+
+// CreateBundle adds a bundle with its slots and options (admin only)
+func CreateBundle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to create bundles"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var bundle models.Bundle
+		if err := c.ShouldBindJSON(&bundle); err != nil || bundle.Name == "" || len(bundle.Slots) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bundle data provided. A name and at least one slot are required."})
+			return
+		}
+
+		if bundle.Price.IsNegative() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A bundle price cannot be negative"})
+			return
+		}
+
+		if bundle.Currency == "" {
+			bundle.Currency = models.DefaultCurrency()
+		}
+		bundle.BundleID = ""
+		bundle.Price = bundle.Price.Round(bundle.Currency)
+
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := validateBundleSlots(tx, &bundle); err != nil {
+				return err
+			}
+			return tx.Create(&bundle).Error
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to create bundle. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusCreated, bundle)
+	}
+}
+
+// UpdateBundle changes a bundle's name or price, and replaces its slots when they are given (admin only).
+// Bundles already ordered keep the prices and components captured on the order.
+func UpdateBundle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update bundles"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var updateData struct {
+			Name  *string             `json:"name"`
+			Price *models.Money       `json:"price"`
+			Slots []models.BundleSlot `json:"slots"`
+		}
+		if err := c.ShouldBindJSON(&updateData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bundle data provided. Please check your input."})
+			return
+		}
+
+		var bundle models.Bundle
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("bundle_id = ?", c.Param("bundle_id")).First(&bundle).Error; err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The bundle you're trying to update could not be found")
+			}
+
+			if updateData.Name != nil {
+				if *updateData.Name == "" {
+					return helpers.NewRequestError(http.StatusBadRequest, "A bundle needs a name")
+				}
+				bundle.Name = *updateData.Name
+			}
+
+			if updateData.Price != nil {
+				if updateData.Price.IsNegative() {
+					return helpers.NewRequestError(http.StatusBadRequest, "A bundle price cannot be negative")
+				}
+				bundle.Price = updateData.Price.Round(bundle.Currency)
+			}
+
+			if err := tx.Save(&bundle).Error; err != nil {
+				return err
+			}
+
+			if updateData.Slots != nil {
+				if len(updateData.Slots) == 0 {
+					return helpers.NewRequestError(http.StatusBadRequest, "A bundle needs at least one slot")
+				}
+
+				bundle.Slots = updateData.Slots
+				if err := validateBundleSlots(tx, &bundle); err != nil {
+					return err
+				}
+				if err := deleteBundleSlots(tx, bundle.BundleID); err != nil {
+					return err
+				}
+				if err := tx.Create(&bundle.Slots).Error; err != nil {
+					return err
+				}
+			}
+
+			return preloadBundleSlots(tx).Where("bundle_id = ?", bundle.BundleID).First(&bundle).Error
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to update bundle. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, bundle)
+	}
+}
+
+// DeleteBundle removes a bundle and its slots (admin only). Orders keep the bundle lines captured on them.
+func DeleteBundle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete bundles"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		bundleId := c.Param("bundle_id")
+
+		var result *gorm.DB
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := deleteBundleSlots(tx, bundleId); err != nil {
+				return err
+			}
+			result = tx.Where("bundle_id = ?", bundleId).Delete(&models.Bundle{})
+			return result.Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete bundle. Please try again later."})
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The bundle you're trying to delete could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Bundle has been successfully deleted"})
+	}
+}
+
+// GetOrderBundles retrieves the bundles ordered on an order with their component items
+func GetOrderBundles() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		orderId := c.Param("order_id")
+
+		var order models.Order
+		if err := databases.DB.WithContext(ctx).Where("order_id = ?", orderId).First(&order).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested order could not be found"})
+			return
+		}
+
+		if err := helpers.MatchUserTypeToUid(c, order.UserID); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only view bundles in your own orders"})
+			return
+		}
+
+		var lines []models.OrderBundle
+		if err := databases.DB.WithContext(ctx).Where("order_id = ?", orderId).Order("id ASC").Find(&lines).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve the order's bundles. Please try again later."})
+			return
+		}
+
+		for i := range lines {
+			if err := databases.DB.WithContext(ctx).Preload("Modifiers").
+				Where("bundle_line_id = ?", lines[i].BundleLineID).
+				Order("id ASC").
+				Find(&lines[i].Items).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve the order's bundles. Please try again later."})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, lines)
+	}
+}
+
+// lockOrderBundle loads an ordered bundle and locks its order, checking the caller may still change it
+func lockOrderBundle(c *gin.Context, tx *gorm.DB, bundleLineId string) (*models.OrderBundle, *models.Order, error) {
+	var line models.OrderBundle
+	if err := tx.Where("bundle_line_id = ?", bundleLineId).First(&line).Error; err != nil {
+		return nil, nil, helpers.NewRequestError(http.StatusNotFound, "The ordered bundle could not be found")
+	}
+
+	order, err := helpers.LockOrder(tx, line.OrderID)
+	if err != nil {
+		return nil, nil, helpers.NewRequestError(http.StatusNotFound, "The related order information could not be found")
+	}
+
+	if c.GetString("user_type") == "USER" {
+		if order.UserID != c.GetString("uid") {
+			return nil, nil, helpers.NewRequestError(http.StatusForbidden, "You can only change bundles in your own orders")
+		}
+
+		if !helpers.IsOrderEditable(order.OrderStatus) {
+			return nil, nil, helpers.NewRequestError(http.StatusBadRequest, "This order cannot be modified in its current state")
+		}
+	}
+
+	return &line, &order, nil
+}
+
+// UpdateOrderBundle changes the quantity of a bundle on an order
+func UpdateOrderBundle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var updateData struct {
+			Quantity int `json:"quantity"`
+		}
+		if err := c.ShouldBindJSON(&updateData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bundle data provided. Please check your input."})
+			return
+		}
+
+		var line *models.OrderBundle
+		var availabilityChanges []helpers.AvailabilityChange
+		var kitchenEvents []models.KitchenEvent
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var order *models.Order
+			var err error
+			if line, order, err = lockOrderBundle(c, tx, c.Param("bundle_line_id")); err != nil {
+				return err
+			}
+
+			if err := helpers.UpdateBundleQuantity(tx, line, updateData.Quantity); err != nil {
+				return err
+			}
+
+			if availabilityChanges, err = syncAcceptedOrderStock(tx, order, line.ComponentIDs(), c.GetString("uid")); err != nil {
+				return err
+			}
+
+			if kitchenEvents, err = helpers.SyncOrderItemTickets(tx, order, line.ComponentIDs(), c.GetString("uid")); err != nil {
+				return err
+			}
+
+			return helpers.RecalculateOrderTotal(tx, line.OrderID)
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to update the bundle. Please try again later.")
+			return
+		}
+
+		helpers.PublishAvailabilityChanges(availabilityChanges)
+		helpers.PublishKitchenEvents(kitchenEvents)
+
+		c.JSON(http.StatusOK, line)
+	}
+}
+
+// DeleteOrderBundle removes a bundle and its component items from an order
+func DeleteOrderBundle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var availabilityChanges []helpers.AvailabilityChange
+		var kitchenEvents []models.KitchenEvent
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			line, order, err := lockOrderBundle(c, tx, c.Param("bundle_line_id"))
+			if err != nil {
+				return err
+			}
+
+			if availabilityChanges, err = helpers.RemoveBundleFromOrder(tx, line, c.GetString("uid")); err != nil {
+				return err
+			}
+
+			if kitchenEvents, err = helpers.SyncOrderItemTickets(tx, order, line.ComponentIDs(), c.GetString("uid")); err != nil {
+				return err
+			}
+
+			return helpers.RecalculateOrderTotal(tx, line.OrderID)
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to remove the bundle from the order. Please try again later.")
+			return
+		}
+
+		helpers.PublishAvailabilityChanges(availabilityChanges)
+		helpers.PublishKitchenEvents(kitchenEvents)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Bundle has been successfully removed from the order"})
+	}
+}
@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RefundInvoice gives money back, in full or in part, against payments on an invoice and issues a
+// numbered credit note for it. Provider refunds are made once the credit note is committed; if the
+// provider cannot be reached they stay pending and can be retried (admin only)
+func RefundInvoice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to issue refunds"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			Reason  string               `json:"reason" binding:"required"`
+			Refunds []helpers.RefundLine `json:"refunds" binding:"required,dive"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid refund data provided. A reason and the payments to refund are required."})
+			return
+		}
+
+		var invoice models.Invoice
+		var note models.CreditNote
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if invoice, err = helpers.LockInvoice(tx, c.Param("invoice_id")); err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The requested invoice could not be found")
+			}
+
+			note, err = helpers.IssueRefund(tx, &invoice, request.Refunds, request.Reason, c.GetString("uid"))
+			return err
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to issue the refund. Please try again later.")
+			return
+		}
+
+		if err := helpers.SettleProviderRefunds(ctx, databases.DB.WithContext(ctx), &note); err != nil {
+			message := "Credit note " + note.Number + " was issued, but its provider refunds could not be recorded; retry them later"
+			var requestErr *helpers.RequestError
+			if errors.As(err, &requestErr) {
+				message = requestErr.Message
+			}
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error":       message,
+				"credit_note": note,
+				"invoice":     invoice,
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"credit_note": note,
+			"invoice":     invoice,
+		})
+	}
+}
+
+// RetryCreditNoteRefunds makes the provider refunds of a credit note that are still pending (admin only)
+func RetryCreditNoteRefunds() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to issue refunds"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var note models.CreditNote
+		if err := databases.DB.WithContext(ctx).Preload("Refunds").Where("credit_note_id = ?", c.Param("credit_note_id")).First(&note).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested credit note could not be found"})
+			return
+		}
+
+		if err := helpers.SettleProviderRefunds(ctx, databases.DB.WithContext(ctx), &note); err != nil {
+			helpers.RespondWithError(c, err, "Unable to retry the refunds. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, note)
+	}
+}
+
+// GetCreditNotes lists credit notes, newest first, optionally for one invoice (admin only)
+func GetCreditNotes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view credit notes"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		pagination := helpers.GetPaginationParams(c)
+		offset := helpers.GetOffset(pagination.Page, pagination.Limit)
+
+		query := databases.DB.WithContext(ctx).Model(&models.CreditNote{})
+		if invoiceId := c.Query("invoice_id"); invoiceId != "" {
+			query = query.Where("invoice_id = ?", invoiceId)
+		}
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to count credit notes"})
+			return
+		}
+
+		var notes []models.CreditNote
+		if err := query.Preload("Refunds").Order("issued_at DESC, id DESC").Offset(offset).Limit(pagination.Limit).Find(&notes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve credit notes. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":       notes,
+			"pagination": helpers.CreatePaginationResponse(pagination.Page, pagination.Limit, total),
+		})
+	}
+}
+
+// findCreditNote loads a credit note with its refunds and invoice, checking the caller is staff or
+// the customer whose order was invoiced
+func findCreditNote(c *gin.Context, db *gorm.DB) (models.CreditNote, models.Invoice, error) {
+	var note models.CreditNote
+	if err := db.Preload("Refunds").Where("credit_note_id = ?", c.Param("credit_note_id")).First(&note).Error; err != nil {
+		return note, models.Invoice{}, helpers.NewRequestError(http.StatusNotFound, "The requested credit note could not be found")
+	}
+
+	var invoice models.Invoice
+	if err := db.Where("invoice_id = ?", note.InvoiceID).First(&invoice).Error; err != nil {
+		return note, invoice, err
+	}
+
+	var order models.Order
+	if err := db.Where("order_id = ?", invoice.OrderID).First(&order).Error; err != nil {
+		return note, invoice, err
+	}
+	if err := helpers.MatchUserTypeToUid(c, order.UserID); err != nil {
+		return note, invoice, helpers.NewRequestError(http.StatusForbidden, "You don't have permission to view this credit note")
+	}
+	return note, invoice, nil
+}
+
+// GetCreditNote retrieves a credit note with its refunds (customers can only view their own)
+func GetCreditNote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		note, _, err := findCreditNote(c, databases.DB.WithContext(ctx))
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to retrieve the credit note. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, note)
+	}
+}
+
+// GetCreditNoteDocument downloads a credit note as a PDF (customers can only download their own)
+func GetCreditNoteDocument() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		note, invoice, err := findCreditNote(c, databases.DB.WithContext(ctx))
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to render the credit note. Please try again later.")
+			return
+		}
+
+		filename := fmt.Sprintf("credit-note-%s.pdf", note.Number)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Data(http.StatusOK, "application/pdf", helpers.CreditNotePDF(&note, &invoice))
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/cloudinary/cloudinary-go/v2"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // GetFoods retrieves all food items, with optional menu_id filtering and pagination
@@ -25,6 +26,11 @@ func GetFoods() gin.HandlerFunc {
 
 		// Get query parameters
 		menuId := c.Query("menu_id")
+		activeAt, err := helpers.GetActiveAt(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		pagination := helpers.GetPaginationParams(c)
 		offset := helpers.GetOffset(pagination.Page, pagination.Limit)
 
@@ -39,6 +45,15 @@ func GetFoods() gin.HandlerFunc {
 			query = query.Where("menu_id = ?", menuId)
 		}
 
+		// Only foods on menus being served at the given time
+		if activeAt != nil {
+			query = query.Where("foods.menu_id IN (?)", helpers.ActiveMenuIDs(query, *activeAt))
+		}
+
+		if available, ok := c.GetQuery("available"); ok {
+			query = helpers.FilterAvailableFoods(query, available == "true")
+		}
+
 		// Get total count
 		if err := query.Model(&models.Food{}).Count(&total).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to count food items"})
@@ -46,7 +61,7 @@ func GetFoods() gin.HandlerFunc {
 		}
 
 		// Get paginated results
-		if err := query.Offset(offset).Limit(pagination.Limit).Find(&foods).Error; err != nil {
+		if err := query.Preload("Variants").Preload("ModifierGroups.Modifiers").Offset(offset).Limit(pagination.Limit).Find(&foods).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve food items. Please try again later."})
 			return
 		}
@@ -71,7 +86,7 @@ func GetFood() gin.HandlerFunc {
 		foodId := c.Param("food_id")
 		var food models.Food
 
-		err := databases.DB.WithContext(ctx).Where("food_id = ?", foodId).First(&food).Error
+		err := databases.DB.WithContext(ctx).Preload("Variants").Preload("ModifierGroups.Modifiers").Where("food_id = ?", foodId).First(&food).Error
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "The requested food item could not be found"})
 			return
@@ -88,6 +103,11 @@ func GetFoodsByCategory() gin.HandlerFunc {
 		defer cancel()
 
 		category := c.Param("category")
+		activeAt, err := helpers.GetActiveAt(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
 		// Get pagination parameters
 		page := c.DefaultQuery("page", "1")
@@ -118,6 +138,14 @@ func GetFoodsByCategory() gin.HandlerFunc {
 			Joins("JOIN menus ON foods.menu_id = menus.menu_id").
 			Where("menus.category = ?", category)
 
+		if activeAt != nil {
+			query = query.Where("foods.menu_id IN (?)", helpers.ActiveMenuIDs(query, *activeAt))
+		}
+
+		if available, ok := c.GetQuery("available"); ok {
+			query = helpers.FilterAvailableFoods(query, available == "true")
+		}
+
 		// Get total count
 		if err := query.Model(&models.Food{}).Count(&total).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to count food items"})
@@ -125,7 +153,7 @@ func GetFoodsByCategory() gin.HandlerFunc {
 		}
 
 		// Get paginated results
-		if err := query.Offset(offset).Limit(limitInt).Find(&foods).Error; err != nil {
+		if err := query.Preload("Variants").Offset(offset).Limit(limitInt).Find(&foods).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve food items. Please try again later."})
 			return
 		}
@@ -164,6 +192,12 @@ func SearchFoods() gin.HandlerFunc {
 			return
 		}
 
+		activeAt, err := helpers.GetActiveAt(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		// Get pagination parameters
 		page := c.DefaultQuery("page", "1")
 		limit := c.DefaultQuery("limit", "10")
@@ -192,6 +226,14 @@ func SearchFoods() gin.HandlerFunc {
 		searchQuery := databases.DB.WithContext(ctx).
 			Where("name LIKE ?", "%"+query+"%")
 
+		if activeAt != nil {
+			searchQuery = searchQuery.Where("foods.menu_id IN (?)", helpers.ActiveMenuIDs(searchQuery, *activeAt))
+		}
+
+		if available, ok := c.GetQuery("available"); ok {
+			searchQuery = helpers.FilterAvailableFoods(searchQuery, available == "true")
+		}
+
 		// Get total count
 		if err := searchQuery.Model(&models.Food{}).Count(&total).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to count search results"})
@@ -199,7 +241,7 @@ func SearchFoods() gin.HandlerFunc {
 		}
 
 		// Get paginated results
-		if err := searchQuery.Offset(offset).Limit(limitInt).Find(&foods).Error; err != nil {
+		if err := searchQuery.Preload("Variants").Offset(offset).Limit(limitInt).Find(&foods).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to search food items. Please try again later."})
 			return
 		}
@@ -254,7 +296,31 @@ func CreateFood() gin.HandlerFunc {
 			return
 		}
 
-		err := databases.DB.WithContext(ctx).Create(&food).Error
+		if food.TaxCategoryID != "" {
+			var categoryExists int64
+			if err := databases.DB.WithContext(ctx).Model(&models.TaxCategory{}).Where("tax_category_id = ?", food.TaxCategoryID).Count(&categoryExists).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify tax category information. Please try again later."})
+				return
+			}
+
+			if categoryExists == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The tax category referenced does not exist"})
+				return
+			}
+		}
+
+		if food.Currency == "" {
+			food.Currency = models.DefaultCurrency()
+		}
+		food.Price = food.Price.Round(food.Currency)
+
+		// New foods start available; availability is changed through the availability endpoint
+		food.UnavailableUntil = nil
+		food.UnavailableReason = ""
+		food.StockedOut = false
+
+		// Variants and modifier groups are managed through their own endpoints
+		err := databases.DB.WithContext(ctx).Omit("Variants", "ModifierGroups").Create(&food).Error
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to add food item to the menu. Please try again later."})
 			return
@@ -264,7 +330,8 @@ func CreateFood() gin.HandlerFunc {
 	}
 }
 
-// UpdateFood modifies an existing food item (admin only)
+// UpdateFood modifies an existing food item (admin only). With ?preview=true the change is not
+// saved; instead the current and proposed margins are returned.
 func UpdateFood() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
@@ -307,10 +374,53 @@ func UpdateFood() gin.HandlerFunc {
 			}
 		}
 
+		if food.TaxCategoryID != "" && food.TaxCategoryID != existingFood.TaxCategoryID {
+			var categoryExists int64
+			if err := databases.DB.WithContext(ctx).Model(&models.TaxCategory{}).Where("tax_category_id = ?", food.TaxCategoryID).Count(&categoryExists).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify tax category information. Please try again later."})
+				return
+			}
+
+			if categoryExists == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The tax category referenced does not exist"})
+				return
+			}
+		}
+
 		// Preserve the primary key ID to ensure GORM performs an UPDATE, not an INSERT
 		food.ID = existingFood.ID
 
-		err := databases.DB.WithContext(ctx).Save(&food).Error
+		if food.Currency == "" {
+			food.Currency = existingFood.Currency
+		}
+		food.Price = food.Price.Round(food.Currency)
+
+		// Availability is changed through the availability endpoint
+		food.UnavailableUntil = existingFood.UnavailableUntil
+		food.UnavailableReason = existingFood.UnavailableReason
+		food.StockedOut = existingFood.StockedOut
+
+		// A preview shows how the change affects the margin without saving it
+		if c.Query("preview") == "true" {
+			plateCost, err := helpers.FoodUnitCost(databases.DB.WithContext(ctx), foodId, "")
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to cost the food item. Please try again later."})
+				return
+			}
+
+			current := helpers.NewFoodCosting(existingFood.Price, plateCost)
+			proposed := helpers.NewFoodCosting(food.Price, plateCost)
+			c.JSON(http.StatusOK, gin.H{
+				"food":                  food,
+				"current":               current,
+				"proposed":              proposed,
+				"margin_change":         proposed.Margin.Sub(current.Margin),
+				"margin_percent_change": math.Round((proposed.MarginPercent-current.MarginPercent)*100) / 100,
+			})
+			return
+		}
+
+		err := databases.DB.WithContext(ctx).Omit("Variants", "ModifierGroups").Save(&food).Error
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update food item. Please try again later."})
 			return
@@ -344,9 +454,37 @@ func DeleteFood() gin.HandlerFunc {
 			return
 		}
 
-		result := databases.DB.WithContext(ctx).Where("food_id = ?", foodId).Delete(&models.Food{})
+		var bundleOptionCount int64
+		if err := databases.DB.WithContext(ctx).Model(&models.BundleSlotOption{}).Where("food_id = ?", foodId).Count(&bundleOptionCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to check if this food item is used in bundles. Please try again later."})
+			return
+		}
+
+		if bundleOptionCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This food item cannot be deleted because it is part of a bundle"})
+			return
+		}
+
+		var result *gorm.DB
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			groupIds := tx.Model(&models.ModifierGroup{}).Select("group_id").Where("food_id = ?", foodId)
+			if err := tx.Where("group_id IN (?)", groupIds).Delete(&models.Modifier{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("food_id = ?", foodId).Delete(&models.ModifierGroup{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("food_id = ?", foodId).Delete(&models.Recipe{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("food_id = ?", foodId).Delete(&models.FoodVariant{}).Error; err != nil {
+				return err
+			}
+			result = tx.Where("food_id = ?", foodId).Delete(&models.Food{})
+			return result.Error
+		})
 
-		if result.Error != nil {
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove food item. Please try again later."})
 			return
 		}
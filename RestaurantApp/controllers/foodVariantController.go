@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// skuInUse reports whether another variant already uses the SKU
+func skuInUse(ctx context.Context, sku, variantId string) (bool, error) {
+	var count int64
+	err := databases.DB.WithContext(ctx).Model(&models.FoodVariant{}).
+		Where("sku = ? AND variant_id <> ?", sku, variantId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetFoodVariants retrieves the variants a food item is sold in
+func GetFoodVariants() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var variants []models.FoodVariant
+		if err := databases.DB.WithContext(ctx).Where("food_id = ?", c.Param("food_id")).Order("id ASC").Find(&variants).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve food variants. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, variants)
+	}
+}
+
+// CreateFoodVariant adds a variant, such as a size, to a food item (admin only)
+func CreateFoodVariant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage food variants"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var variant models.FoodVariant
+		if err := c.ShouldBindJSON(&variant); err != nil || variant.Name == "" || variant.SKU == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant data provided. A name and SKU are required."})
+			return
+		}
+
+		if variant.Price.IsNegative() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A variant price cannot be negative"})
+			return
+		}
+
+		var food models.Food
+		if err := databases.DB.WithContext(ctx).Where("food_id = ?", c.Param("food_id")).First(&food).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The food item referenced does not exist"})
+			return
+		}
+
+		inUse, err := skuInUse(ctx, variant.SKU, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify the SKU. Please try again later."})
+			return
+		}
+
+		if inUse {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This SKU is already used by another variant"})
+			return
+		}
+
+		variant.VariantID = ""
+		variant.FoodID = food.FoodID
+		variant.Price = variant.Price.Round(food.Currency)
+
+		if err := databases.DB.WithContext(ctx).Create(&variant).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create food variant. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusCreated, variant)
+	}
+}
+
+// UpdateFoodVariant changes a variant's name, SKU or price (admin only). Existing order items keep their captured price.
+func UpdateFoodVariant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage food variants"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var variant models.FoodVariant
+		if err := databases.DB.WithContext(ctx).Preload("Food").Where("variant_id = ?", c.Param("variant_id")).First(&variant).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The food variant you're trying to update could not be found"})
+			return
+		}
+
+		var updateData struct {
+			Name  *string       `json:"name"`
+			SKU   *string       `json:"sku"`
+			Price *models.Money `json:"price"`
+		}
+		if err := c.ShouldBindJSON(&updateData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant data provided. Please check your input."})
+			return
+		}
+
+		if updateData.Name != nil {
+			variant.Name = *updateData.Name
+		}
+
+		if updateData.SKU != nil && *updateData.SKU != variant.SKU {
+			inUse, err := skuInUse(ctx, *updateData.SKU, variant.VariantID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify the SKU. Please try again later."})
+				return
+			}
+
+			if inUse {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "This SKU is already used by another variant"})
+				return
+			}
+			variant.SKU = *updateData.SKU
+		}
+
+		if updateData.Price != nil {
+			if updateData.Price.IsNegative() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "A variant price cannot be negative"})
+				return
+			}
+			variant.Price = updateData.Price.Round(variant.Food.Currency)
+		}
+
+		if variant.Name == "" || variant.SKU == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A variant needs a name and SKU"})
+			return
+		}
+
+		if err := databases.DB.WithContext(ctx).Omit("Food").Save(&variant).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update food variant. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, variant)
+	}
+}
+
+// DeleteFoodVariant removes a variant that has never been ordered (admin only)
+func DeleteFoodVariant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage food variants"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		variantId := c.Param("variant_id")
+
+		var orderItemCount int64
+		if err := databases.DB.WithContext(ctx).Model(&models.OrderItem{}).Where("variant_id = ?", variantId).Count(&orderItemCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to check if this variant is used in orders. Please try again later."})
+			return
+		}
+
+		if orderItemCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This variant cannot be deleted because it is used in orders"})
+			return
+		}
+
+		var result *gorm.DB
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("variant_id = ?", variantId).Delete(&models.Recipe{}).Error; err != nil {
+				return err
+			}
+			result = tx.Where("variant_id = ?", variantId).Delete(&models.FoodVariant{})
+			return result.Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete food variant. Please try again later."})
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The food variant you're trying to delete could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Food variant has been successfully deleted"})
+	}
+}
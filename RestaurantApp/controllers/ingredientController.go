@@ -0,0 +1,496 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetIngredients retrieves all stocked ingredients (admin only)
+func GetIngredients() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view ingredients"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var ingredients []models.Ingredient
+		if err := databases.DB.WithContext(ctx).Order("name ASC").Find(&ingredients).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve ingredients. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, ingredients)
+	}
+}
+
+// GetIngredient retrieves a specific ingredient by ID (admin only)
+func GetIngredient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view ingredients"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var ingredient models.Ingredient
+		if err := databases.DB.WithContext(ctx).Where("ingredient_id = ?", c.Param("ingredient_id")).First(&ingredient).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested ingredient could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, ingredient)
+	}
+}
+
+// CreateIngredient adds a new stocked ingredient. An opening on-hand quantity is recorded as a stock adjustment (admin only)
+func CreateIngredient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to create ingredients"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var ingredient models.Ingredient
+		if err := c.ShouldBindJSON(&ingredient); err != nil || ingredient.Name == "" || ingredient.Unit == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ingredient data provided. A name and unit are required."})
+			return
+		}
+
+		if ingredient.OnHand.IsNegative() || ingredient.ReorderPoint.IsNegative() || ingredient.UnitCost.IsNegative() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Stock quantities and costs cannot be negative"})
+			return
+		}
+
+		openingStock := ingredient.OnHand
+		ingredient.OnHand = 0
+
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&ingredient).Error; err != nil {
+				return err
+			}
+
+			if err := helpers.AdjustStock(tx, &models.StockMovement{
+				IngredientID: ingredient.IngredientID,
+				Quantity:     openingStock,
+				SourceType:   models.StockSourceAdjustment,
+				Reason:       "opening stock",
+				ActorUID:     c.GetString("uid"),
+			}); err != nil {
+				return err
+			}
+
+			return tx.Where("ingredient_id = ?", ingredient.IngredientID).First(&ingredient).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create ingredient. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusCreated, ingredient)
+	}
+}
+
+// UpdateIngredient modifies an ingredient's details and cost. On-hand stock only changes through adjustments (admin only)
+func UpdateIngredient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update ingredients"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var ingredient models.Ingredient
+		if err := databases.DB.WithContext(ctx).Where("ingredient_id = ?", c.Param("ingredient_id")).First(&ingredient).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The ingredient you're trying to update could not be found"})
+			return
+		}
+
+		var updateData struct {
+			Name         string           `json:"name"`
+			Unit         string           `json:"unit"`
+			ReorderPoint *models.Quantity `json:"reorder_point"`
+			UnitCost     *models.Money    `json:"unit_cost"`
+		}
+		if err := c.ShouldBindJSON(&updateData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ingredient data provided. Please check your input."})
+			return
+		}
+
+		if updateData.Name != "" {
+			ingredient.Name = updateData.Name
+		}
+		if updateData.Unit != "" {
+			ingredient.Unit = updateData.Unit
+		}
+		if updateData.ReorderPoint != nil {
+			if updateData.ReorderPoint.IsNegative() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The reorder point cannot be negative"})
+				return
+			}
+			ingredient.ReorderPoint = *updateData.ReorderPoint
+		}
+		if updateData.UnitCost != nil {
+			if updateData.UnitCost.IsNegative() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The unit cost cannot be negative"})
+				return
+			}
+			ingredient.UnitCost = *updateData.UnitCost
+		}
+
+		if err := databases.DB.WithContext(ctx).Model(&ingredient).
+			Select("name", "unit", "reorder_point", "unit_cost").Updates(&ingredient).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update ingredient. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, ingredient)
+	}
+}
+
+// DeleteIngredient removes an ingredient that no recipe or stock item uses, together with its stock history (admin only)
+func DeleteIngredient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete ingredients"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		ingredientId := c.Param("ingredient_id")
+
+		var recipeCount int64
+		if err := databases.DB.WithContext(ctx).Model(&models.Recipe{}).Where("ingredient_id = ?", ingredientId).Count(&recipeCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to check the ingredient's recipes. Please try again later."})
+			return
+		}
+
+		if recipeCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This ingredient cannot be deleted because it is used in recipes"})
+			return
+		}
+
+		var stockItemCount int64
+		if err := databases.DB.WithContext(ctx).Model(&models.StockItem{}).Where("ingredient_id = ?", ingredientId).Count(&stockItemCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to check the ingredient's stock items. Please try again later."})
+			return
+		}
+
+		if stockItemCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This ingredient cannot be deleted because suppliers sell it as a stock item"})
+			return
+		}
+
+		var openCountLines int64
+		if err := databases.DB.WithContext(ctx).Model(&models.StockCountLine{}).
+			Joins("JOIN stock_counts ON stock_counts.stock_count_id = stock_count_lines.stock_count_id").
+			Where("stock_count_lines.ingredient_id = ? AND stock_counts.status = ?", ingredientId, models.StockCountStatusOpen).
+			Count(&openCountLines).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to check open stock counts. Please try again later."})
+			return
+		}
+
+		if openCountLines > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This ingredient cannot be deleted while it is being counted"})
+			return
+		}
+
+		var result *gorm.DB
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("ingredient_id = ?", ingredientId).Delete(&models.StockMovement{}).Error; err != nil {
+				return err
+			}
+			result = tx.Where("ingredient_id = ?", ingredientId).Delete(&models.Ingredient{})
+			return result.Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete ingredient. Please try again later."})
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The ingredient you're trying to delete could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Ingredient has been successfully deleted"})
+	}
+}
+
+// CreateStockAdjustment records a manual stock change such as a delivery or a count correction (admin only)
+func CreateStockAdjustment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to adjust stock"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			Quantity models.Quantity `json:"quantity"`
+			Reason   string          `json:"reason"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil || request.Quantity.IsZero() || request.Reason == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid adjustment data. A non-zero quantity and a reason are required."})
+			return
+		}
+
+		ingredientId := c.Param("ingredient_id")
+
+		var ingredient models.Ingredient
+		var movement models.StockMovement
+		var availabilityChanges []helpers.AvailabilityChange
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("ingredient_id = ?", ingredientId).First(&ingredient).Error; err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The ingredient you're trying to adjust could not be found")
+			}
+
+			movement = models.StockMovement{
+				IngredientID: ingredientId,
+				Quantity:     request.Quantity,
+				SourceType:   models.StockSourceAdjustment,
+				Reason:       request.Reason,
+				ActorUID:     c.GetString("uid"),
+			}
+			if err := helpers.AdjustStock(tx, &movement); err != nil {
+				return err
+			}
+
+			if err := tx.Where("ingredient_id = ?", ingredientId).First(&ingredient).Error; err != nil {
+				return err
+			}
+			if ingredient.OnHand.IsNegative() {
+				return helpers.NewRequestError(http.StatusBadRequest, "This adjustment would take the ingredient below zero")
+			}
+
+			var err error
+			availabilityChanges, err = helpers.RefreshStockAvailability(tx, []string{ingredientId})
+			return err
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to adjust stock. Please try again later.")
+			return
+		}
+
+		helpers.PublishAvailabilityChanges(availabilityChanges)
+
+		c.JSON(http.StatusCreated, gin.H{
+			"ingredient": ingredient,
+			"movement":   movement,
+		})
+	}
+}
+
+// GetStockMovements lists the stock movements of an ingredient, newest first (admin only)
+func GetStockMovements() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view stock movements"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		ingredientId := c.Param("ingredient_id")
+		pagination := helpers.GetPaginationParams(c)
+		offset := helpers.GetOffset(pagination.Page, pagination.Limit)
+
+		query := databases.DB.WithContext(ctx).Model(&models.StockMovement{}).Where("ingredient_id = ?", ingredientId)
+		if sourceType := c.Query("source_type"); sourceType != "" {
+			query = query.Where("source_type = ?", sourceType)
+		}
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to count stock movements"})
+			return
+		}
+
+		var movements []models.StockMovement
+		if err := query.Order("id DESC").Offset(offset).Limit(pagination.Limit).Find(&movements).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve stock movements. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":       movements,
+			"pagination": helpers.CreatePaginationResponse(pagination.Page, pagination.Limit, total),
+		})
+	}
+}
+
+// GetFoodRecipe lists the ingredients one portion of a food uses (admin only)
+func GetFoodRecipe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view recipes"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var recipe []models.Recipe
+		if err := databases.DB.WithContext(ctx).Where("food_id = ?", c.Param("food_id")).Order("id ASC").Find(&recipe).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve the recipe. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, recipe)
+	}
+}
+
+// GetFoodCosting shows the theoretical plate cost of a food from its recipe and ingredient costs,
+// and the resulting margin at its price or at the price of each variant (admin only)
+func GetFoodCosting() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view food costing"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		db := databases.DB.WithContext(ctx)
+
+		var food models.Food
+		if err := db.Preload("Variants").Where("food_id = ?", c.Param("food_id")).First(&food).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested food item could not be found"})
+			return
+		}
+
+		costings, err := helpers.CostFood(db, &food)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to cost the food item. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"food_id":  food.FoodID,
+			"name":     food.Name,
+			"currency": food.Currency,
+			"costing":  costings,
+		})
+	}
+}
+
+// ReplaceFoodRecipe sets the full recipe of a food, replacing any existing lines. Lines without a
+// variant apply to every portion; lines naming a variant are used for that variant only (admin only)
+func ReplaceFoodRecipe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to change recipes"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			Lines []models.Recipe `json:"lines"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recipe data provided. Please check your input."})
+			return
+		}
+
+		foodId := c.Param("food_id")
+
+		var availabilityChanges []helpers.AvailabilityChange
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var food models.Food
+			if err := tx.Preload("Variants").Where("food_id = ?", foodId).First(&food).Error; err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The requested food item could not be found")
+			}
+
+			if err := validateRecipeLines(tx, &food, request.Lines); err != nil {
+				return err
+			}
+
+			if err := tx.Where("food_id = ?", foodId).Delete(&models.Recipe{}).Error; err != nil {
+				return err
+			}
+
+			for i := range request.Lines {
+				request.Lines[i].ID = 0
+				request.Lines[i].RecipeID = ""
+				request.Lines[i].FoodID = foodId
+				if err := tx.Create(&request.Lines[i]).Error; err != nil {
+					return err
+				}
+			}
+
+			var err error
+			availabilityChanges, err = helpers.RefreshFoodStockAvailability(tx, foodId)
+			return err
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to update the recipe. Please try again later.")
+			return
+		}
+
+		helpers.PublishAvailabilityChanges(availabilityChanges)
+
+		if request.Lines == nil {
+			request.Lines = []models.Recipe{}
+		}
+		c.JSON(http.StatusOK, request.Lines)
+	}
+}
+
+// validateRecipeLines checks every recipe line names an existing ingredient, a variant of the food
+// if any, and a positive quantity, with each ingredient listed once per variant
+func validateRecipeLines(tx *gorm.DB, food *models.Food, lines []models.Recipe) error {
+	variants := make(map[string]bool)
+	for _, variant := range food.Variants {
+		variants[variant.VariantID] = true
+	}
+
+	seen := make(map[[2]string]bool)
+	for _, line := range lines {
+		if line.IngredientID == "" || line.Quantity.IsZero() || line.Quantity.IsNegative() {
+			return helpers.NewRequestError(http.StatusBadRequest, "Every recipe line needs an ingredient_id and a positive quantity")
+		}
+		if line.VariantID != "" && !variants[line.VariantID] {
+			return helpers.NewRequestError(http.StatusBadRequest, "A recipe line names a variant that does not belong to this food item")
+		}
+
+		key := [2]string{line.VariantID, line.IngredientID}
+		if seen[key] {
+			return helpers.NewRequestError(http.StatusBadRequest, "Each ingredient may only be listed once per variant")
+		}
+		seen[key] = true
+
+		var ingredient models.Ingredient
+		if err := tx.Where("ingredient_id = ?", line.IngredientID).First(&ingredient).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return helpers.NewRequestError(http.StatusBadRequest, "A recipe line names an ingredient that does not exist")
+			}
+			return err
+		}
+	}
+	return nil
+}
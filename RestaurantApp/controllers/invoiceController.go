@@ -9,6 +9,7 @@ import (
 	"github.com/RestaurantApp/helpers"
 	"github.com/RestaurantApp/models"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // GetInvoices retrieves all invoices (admin only)
@@ -35,6 +36,7 @@ func GetInvoices() gin.HandlerFunc {
 		}
 
 		if err := databases.DB.WithContext(ctx).
+			Preload("TaxLines").
 			Offset(offset).
 			Limit(pagination.Limit).
 			Find(&invoices).Error; err != nil {
@@ -60,7 +62,7 @@ func GetInvoice() gin.HandlerFunc {
 		invoiceId := c.Param("invoice_id")
 		var invoice models.Invoice
 
-		if err := databases.DB.WithContext(ctx).Where("invoice_id = ?", invoiceId).First(&invoice).Error; err != nil {
+		if err := databases.DB.WithContext(ctx).Preload("TaxLines").Where("invoice_id = ?", invoiceId).First(&invoice).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "The requested invoice could not be found"})
 			return
 		}
@@ -105,7 +107,7 @@ func GetUserInvoices() gin.HandlerFunc {
 		}
 
 		var invoices []models.Invoice
-		if err := databases.DB.WithContext(ctx).Where("order_id IN ?", orderIds).Find(&invoices).Error; err != nil {
+		if err := databases.DB.WithContext(ctx).Preload("TaxLines").Where("order_id IN ?", orderIds).Find(&invoices).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve your invoices. Please try again later."})
 			return
 		}
@@ -114,7 +116,7 @@ func GetUserInvoices() gin.HandlerFunc {
 	}
 }
 
-// CreateInvoice generates a new invoice for an order (admin only)
+// CreateInvoice generates a new invoice for an order, taxed at the current tax rates (admin only)
 func CreateInvoice() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
@@ -131,32 +133,83 @@ func CreateInvoice() gin.HandlerFunc {
 			return
 		}
 
-		var order models.Order
-		if err := databases.DB.WithContext(ctx).Where("order_id = ?", invoice.OrderID).First(&order).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "The order referenced in this invoice could not be found"})
-			return
-		}
+		// Payments are recorded against the invoice once it exists
+		invoice.PaymentStatus = models.InvoiceStatusPending
+		invoice.AmountPaid = 0
 
-		if invoice.PaymentStatus == "" {
-			invoice.PaymentStatus = "pending"
+		if invoice.PaymentDueDate.IsZero() {
+			invoice.PaymentDueDate = time.Now().AddDate(0, 0, 7)
 		}
 
-		if invoice.TotalAmount == 0 {
-			invoice.TotalAmount = order.OrderTotal
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Lock the order so its total cannot change while it is being invoiced
+			order, err := helpers.LockOrder(tx, invoice.OrderID)
+			if err != nil {
+				return helpers.NewRequestError(http.StatusBadRequest, "The order referenced in this invoice could not be found")
+			}
+
+			if !helpers.CanTransitionOrder(order.OrderStatus, models.OrderStatusInvoiced) {
+				return helpers.NewRequestError(http.StatusConflict, "Only orders that have been served can be invoiced")
+			}
+
+			// The subtotal, tax and total follow from the order's items and their tax categories
+			if err := helpers.TaxInvoice(tx, &invoice, &order); err != nil {
+				return err
+			}
+			invoice.BalanceDue = invoice.TotalAmount
+
+			if err := tx.Create(&invoice).Error; err != nil {
+				return err
+			}
+			return helpers.TransitionOrder(tx, &order, models.OrderStatusInvoiced, c.GetString("uid"), "invoice "+invoice.InvoiceID+" created")
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to create invoice. Please try again later.")
+			return
 		}
 
-		if invoice.PaymentDueDate.IsZero() {
-			invoice.PaymentDueDate = time.Now().AddDate(0, 0, 7)
+		c.JSON(http.StatusCreated, invoice)
+	}
+}
+
+// SplitInvoice invoices a served order as several invoices, split evenly N ways, by groups of
+// order items or by seat, whose subtotals, tax and totals add up exactly to a single invoice (admin only)
+func SplitInvoice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to create invoices"})
+			return
 		}
 
-		if err := databases.DB.WithContext(ctx).Create(&invoice).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create invoice. Please try again later."})
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			helpers.BillSplitRequest
+			OrderID string `json:"order_id" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid split data provided. An order_id and strategy are required."})
 			return
 		}
 
-		databases.DB.WithContext(ctx).Model(&order).Update("order_status", "invoiced")
+		var splits []helpers.SplitInvoice
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Lock the order so its items cannot change while the bill is split
+			order, err := helpers.LockOrder(tx, request.OrderID)
+			if err != nil {
+				return helpers.NewRequestError(http.StatusBadRequest, "The order referenced could not be found")
+			}
 
-		c.JSON(http.StatusCreated, invoice)
+			splits, err = helpers.SplitOrderBill(tx, &order, request.BillSplitRequest, c.GetString("uid"))
+			return err
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to split the bill. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusCreated, splits)
 	}
 }
 
@@ -172,12 +225,6 @@ func UpdateInvoice() gin.HandlerFunc {
 		defer cancel()
 
 		invoiceId := c.Param("invoice_id")
-		var invoice models.Invoice
-
-		if err := databases.DB.WithContext(ctx).Where("invoice_id = ?", invoiceId).First(&invoice).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "The invoice you're trying to update could not be found"})
-			return
-		}
 
 		var updateData models.Invoice
 		if err := c.ShouldBindJSON(&updateData); err != nil {
@@ -190,13 +237,46 @@ func UpdateInvoice() gin.HandlerFunc {
 			return
 		}
 
-		if err := databases.DB.WithContext(ctx).Where("invoice_id = ?", invoiceId).Updates(&updateData).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update invoice. Please try again later."})
+		// The payment status, amount paid and balance follow from the invoice's payments
+		if updateData.PaymentStatus != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The payment status follows from the invoice's payments and cannot be set by hand"})
+			return
+		}
+		updateData.AmountPaid, updateData.BalanceDue = 0, 0
+
+		// The amounts and tax breakdown were fixed when the invoice was issued, so later rate changes do not alter them
+		if !updateData.TotalAmount.IsZero() || !updateData.Subtotal.IsZero() || !updateData.TaxAmount.IsZero() || len(updateData.TaxLines) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The invoice amounts and tax were fixed when it was issued and cannot be changed"})
 			return
 		}
 
-		if err := databases.DB.WithContext(ctx).Where("invoice_id = ?", invoiceId).First(&invoice).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invoice was updated but could not be retrieved"})
+		var invoice models.Invoice
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			current, err := helpers.LockInvoice(tx, invoiceId)
+			if err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The invoice you're trying to update could not be found")
+			}
+
+			// The invoice's amounts and payments are for its order and in its currency, so neither can change
+			if updateData.OrderID != "" && updateData.OrderID != current.OrderID {
+				return helpers.NewRequestError(http.StatusBadRequest, "An invoice cannot be moved to another order")
+			}
+			if updateData.Currency != "" && updateData.Currency != current.Currency {
+				return helpers.NewRequestError(http.StatusBadRequest, "The currency of an invoice cannot be changed once it has been issued")
+			}
+
+			if err := tx.Where("invoice_id = ?", invoiceId).Omit("OrderID", "Currency", "PricesIncludeTax", "TaxRounding", "TaxLines").Updates(&updateData).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Preload("TaxLines").Where("invoice_id = ?", invoiceId).First(&invoice).Error; err != nil {
+				return err
+			}
+
+			return helpers.ApplyInvoicePayments(tx, &invoice)
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to update invoice. Please try again later.")
 			return
 		}
 
@@ -217,19 +297,57 @@ func DeleteInvoice() gin.HandlerFunc {
 
 		invoiceId := c.Param("invoice_id")
 
-		var invoice models.Invoice
-		if err := databases.DB.WithContext(ctx).Where("invoice_id = ?", invoiceId).First(&invoice).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "The invoice you're trying to delete could not be found"})
-			return
-		}
-
-		if invoice.PaymentStatus == "paid" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Paid invoices cannot be deleted"})
-			return
-		}
-
-		if err := databases.DB.WithContext(ctx).Where("invoice_id = ?", invoiceId).Delete(&invoice).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete invoice. Please try again later."})
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			invoice, err := helpers.LockInvoice(tx, invoiceId)
+			if err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The invoice you're trying to delete could not be found")
+			}
+
+			var paymentCount int64
+			if err := tx.Model(&models.Payment{}).
+				Where("invoice_id = ? AND status IN ?", invoiceId, []string{models.PaymentStatusCompleted, models.PaymentStatusRefunded}).
+				Count(&paymentCount).Error; err != nil {
+				return err
+			}
+			if paymentCount > 0 || invoice.PaymentStatus == models.InvoiceStatusPaid {
+				return helpers.NewRequestError(http.StatusBadRequest, "Invoices with payments cannot be deleted. Void the payments first.")
+			}
+
+			var creditNoteCount int64
+			if err := tx.Model(&models.CreditNote{}).Where("invoice_id = ?", invoiceId).Count(&creditNoteCount).Error; err != nil {
+				return err
+			}
+			if creditNoteCount > 0 {
+				return helpers.NewRequestError(http.StatusBadRequest, "Invoices with credit notes cannot be deleted")
+			}
+
+			if err := tx.Where("invoice_id = ?", invoiceId).Delete(&models.Payment{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("invoice_id = ?", invoiceId).Delete(&models.InvoiceTaxLine{}).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Where("invoice_id = ?", invoiceId).Delete(&invoice).Error; err != nil {
+				return err
+			}
+
+			// Once the last invoice of an invoiced order is gone, the order is served again so it can be billed anew
+			order, err := helpers.LockOrder(tx, invoice.OrderID)
+			if err != nil || order.OrderStatus != models.OrderStatusInvoiced {
+				return nil
+			}
+			var remaining int64
+			if err := tx.Model(&models.Invoice{}).Where("order_id = ?", order.OrderID).Count(&remaining).Error; err != nil {
+				return err
+			}
+			if remaining > 0 {
+				return nil
+			}
+			return helpers.TransitionOrder(tx, &order, models.OrderStatusServed, c.GetString("uid"), "invoice "+invoiceId+" deleted")
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to delete invoice. Please try again later.")
 			return
 		}
 
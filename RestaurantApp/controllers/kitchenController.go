@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// kitchenReplayBatch is how many stored events are replayed per query when a screen reconnects
+const kitchenReplayBatch = 500
+
+// streamHeartbeat keeps idle event streams open through proxies
+const streamHeartbeat = 20 * time.Second
+
+// kitchenFeedPoll is how often a kitchen screen's stream checks the feed for events it was not woken for
+const kitchenFeedPoll = 2 * time.Second
+
+// GetKitchenTickets retrieves kitchen tickets, defaulting to those still being worked on (admin only)
+func GetKitchenTickets() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view kitchen tickets"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		statuses := c.QueryArray("status")
+		if len(statuses) == 0 {
+			statuses = []string{models.TicketStatusQueued, models.TicketStatusInProgress}
+		}
+
+		var tickets []models.KitchenTicket
+		if err := databases.DB.WithContext(ctx).
+			Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+			Where("status IN ?", statuses).
+			Order("created_at ASC").
+			Find(&tickets).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve kitchen tickets. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, tickets)
+	}
+}
+
+// UpdateKitchenTicket bumps every item on a kitchen ticket to a new status (admin only)
+func UpdateKitchenTicket() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bumpKitchenTicket(c, nil)
+	}
+}
+
+// UpdateKitchenTicketItem bumps a single item on a kitchen ticket to a new status (admin only)
+func UpdateKitchenTicketItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bumpKitchenTicket(c, []string{c.Param("ticket_item_id")})
+	}
+}
+
+func bumpKitchenTicket(c *gin.Context, itemIds []string) {
+	if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update kitchen tickets"})
+		return
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var request struct {
+		Status string `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A ticket status is required"})
+		return
+	}
+
+	if request.Status != models.TicketStatusInProgress && request.Status != models.TicketStatusReady {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ticket items can only be bumped to in_progress or ready"})
+		return
+	}
+
+	var ticket models.KitchenTicket
+	var events []models.KitchenEvent
+	err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		ticket, events, err = helpers.BumpKitchenTicket(tx, c.Param("ticket_id"), itemIds, request.Status, c.GetString("uid"))
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "The requested kitchen ticket could not be found"})
+		return
+	}
+	if errors.Is(err, helpers.ErrInvalidTicketStatus) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This ticket item cannot move to the requested status"})
+		return
+	}
+	if err != nil {
+		helpers.RespondWithError(c, err, "Unable to update the kitchen ticket. Please try again later.")
+		return
+	}
+
+	helpers.PublishKitchenEvents(events)
+
+	c.JSON(http.StatusOK, ticket)
+}
+
+// StreamKitchenFeed streams kitchen ticket events to a kitchen screen using Server-Sent Events,
+// in the order they committed. Screens resume after a reconnect by sending the last feed position
+// they saw, either in the Last-Event-ID header or the since query parameter, and receive every
+// event after it first. A station_id query parameter limits the feed to a single station's tickets.
+func StreamKitchenFeed() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view the kitchen feed"})
+			return
+		}
+
+		since := c.GetHeader("Last-Event-ID")
+		if since == "" {
+			since = c.DefaultQuery("since", "0")
+		}
+		lastPosition, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The since position must be a non-negative number"})
+			return
+		}
+
+		stationId, filterStation := c.GetQuery("station_id")
+
+		// Subscribe before replaying so no event committed in between is missed
+		live, unsubscribe := helpers.KitchenBroker.Subscribe()
+		defer unsubscribe()
+
+		ctx := c.Request.Context()
+		db := databases.DB.WithContext(ctx)
+
+		// Live events only wake the stream; events are always read back from the feed in commit
+		// order after the last position sent, so none is skipped or sent twice
+		replay := func(w io.Writer) bool {
+			if err := helpers.PositionKitchenEvents(db); err != nil {
+				return false
+			}
+
+			for {
+				query := db.Where("position > ?", lastPosition)
+				if filterStation {
+					query = query.Where("station_id = ?", stationId)
+				}
+
+				var stored []models.KitchenEvent
+				if err := query.
+					Order("position ASC").
+					Limit(kitchenReplayBatch).
+					Find(&stored).Error; err != nil {
+					return false
+				}
+
+				for _, event := range stored {
+					writeStreamEvent(w, helpers.KitchenEventToBrokerEvent(event))
+					lastPosition = *event.Position
+				}
+
+				if len(stored) < kitchenReplayBatch {
+					return true
+				}
+			}
+		}
+
+		c.Writer.Header().Set("Content-Type", "text/event-stream")
+		c.Writer.Header().Set("Cache-Control", "no-cache")
+		c.Writer.Header().Set("Connection", "keep-alive")
+		c.Writer.Header().Set("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		if !replay(c.Writer) {
+			return
+		}
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		// Events committed by other servers wake no subscriber here, so the feed is also polled
+		poll := time.NewTicker(kitchenFeedPoll)
+		defer poll.Stop()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+				return true
+			case <-poll.C:
+				return replay(w)
+			case _, ok := <-live:
+				if !ok {
+					// Dropped for falling behind; the screen reconnects and replays from lastPosition
+					return false
+				}
+				return replay(w)
+			}
+		})
+	}
+}
+
+func writeStreamEvent(w io.Writer, event helpers.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Sequence, event.Type, event.Data)
+}
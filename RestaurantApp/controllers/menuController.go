@@ -11,14 +11,30 @@ import (
 	"github.com/RestaurantApp/models"
 )
 
-// GetMenus retrieves all restaurant menus
+// validMenuWindow reports whether a menu's date window, when set, ends after it starts
+func validMenuWindow(startDate, endDate *time.Time) bool {
+	return startDate == nil || endDate == nil || !endDate.Before(*startDate)
+}
+
+// GetMenus retrieves all restaurant menus, optionally only those being served at active_at
 func GetMenus() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
 		defer cancel()
 
+		activeAt, err := helpers.GetActiveAt(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		query := databases.DB.WithContext(ctx).Preload("Schedules")
+		if activeAt != nil {
+			query = query.Where("menu_id IN (?)", helpers.ActiveMenuIDs(query, *activeAt))
+		}
+
 		var menus []models.Menu
-		if err := databases.DB.WithContext(ctx).Find(&menus).Error; err != nil {
+		if err := query.Find(&menus).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve menus. Please try again later."})
 			return
 		}
@@ -36,7 +52,7 @@ func GetMenu() gin.HandlerFunc {
 		menuId := c.Param("menu_id")
 		var menu models.Menu
 
-		if err := databases.DB.WithContext(ctx).Where("menu_id = ?", menuId).First(&menu).Error; err != nil {
+		if err := databases.DB.WithContext(ctx).Preload("Schedules").Where("menu_id = ?", menuId).First(&menu).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "The requested menu could not be found"})
 			return
 		}
@@ -62,7 +78,13 @@ func CreateMenu() gin.HandlerFunc {
 			return
 		}
 
-		if err := databases.DB.WithContext(ctx).Create(&menu).Error; err != nil {
+		if !validMenuWindow(menu.StartDate, menu.EndDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The menu's end date must not be before its start date"})
+			return
+		}
+
+		// Schedules are managed through their own endpoints
+		if err := databases.DB.WithContext(ctx).Omit("Schedules").Create(&menu).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create menu. Please try again later."})
 			return
 		}
@@ -101,7 +123,20 @@ func UpdateMenu() gin.HandlerFunc {
 			return
 		}
 
-		if err := databases.DB.WithContext(ctx).Where("menu_id = ?", menuId).Updates(&updateData).Error; err != nil {
+		startDate, endDate := menu.StartDate, menu.EndDate
+		if updateData.StartDate != nil {
+			startDate = updateData.StartDate
+		}
+		if updateData.EndDate != nil {
+			endDate = updateData.EndDate
+		}
+
+		if !validMenuWindow(startDate, endDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The menu's end date must not be before its start date"})
+			return
+		}
+
+		if err := databases.DB.WithContext(ctx).Where("menu_id = ?", menuId).Omit("Schedules").Updates(&updateData).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update menu. Please try again later."})
 			return
 		}
@@ -155,6 +190,11 @@ func DeleteMenu() gin.HandlerFunc {
 			return
 		}
 
+		if err := databases.DB.WithContext(ctx).Where("menu_id = ?", menuId).Delete(&models.MenuSchedule{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete the menu's schedules. Please try again later."})
+			return
+		}
+
 		result := databases.DB.WithContext(ctx).Where("menu_id = ?", menuId).Delete(&models.Menu{})
 		if result.Error != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete menu. Please try again later."})
@@ -169,3 +209,94 @@ func DeleteMenu() gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"message": "Menu has been successfully deleted"})
 	}
 }
+
+// GetMenuSchedules retrieves the weekly serving windows of a menu
+func GetMenuSchedules() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var schedules []models.MenuSchedule
+		if err := databases.DB.WithContext(ctx).Where("menu_id = ?", c.Param("menu_id")).
+			Order("day_of_week ASC, start_time ASC").
+			Find(&schedules).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve menu schedules. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, schedules)
+	}
+}
+
+// CreateMenuSchedule adds a weekly serving window to a menu, e.g. breakfast 07:00-11:00 on weekdays (admin only)
+func CreateMenuSchedule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to change menu schedules"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var schedule models.MenuSchedule
+		if err := c.ShouldBindJSON(&schedule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule data provided. Please check your input."})
+			return
+		}
+
+		if err := helpers.ValidateMenuSchedule(&schedule); err != nil {
+			helpers.RespondWithError(c, err, "Invalid schedule data provided. Please check your input.")
+			return
+		}
+
+		var menuExists int64
+		if err := databases.DB.WithContext(ctx).Model(&models.Menu{}).Where("menu_id = ?", c.Param("menu_id")).Count(&menuExists).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify menu information. Please try again later."})
+			return
+		}
+
+		if menuExists == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The menu referenced does not exist"})
+			return
+		}
+
+		schedule.ScheduleID = ""
+		schedule.MenuID = c.Param("menu_id")
+
+		if err := databases.DB.WithContext(ctx).Create(&schedule).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create menu schedule. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusCreated, schedule)
+	}
+}
+
+// DeleteMenuSchedule removes a serving window from a menu (admin only)
+func DeleteMenuSchedule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to change menu schedules"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result := databases.DB.WithContext(ctx).
+			Where("menu_id = ? AND schedule_id = ?", c.Param("menu_id"), c.Param("schedule_id")).
+			Delete(&models.MenuSchedule{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete menu schedule. Please try again later."})
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The menu schedule you're trying to delete could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Menu schedule has been successfully deleted"})
+	}
+}
@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// validModifierGroup reports whether a group's selection limits are consistent
+func validModifierGroup(group *models.ModifierGroup) bool {
+	if group.Name == "" || group.MinSelections < 0 || group.MaxSelections < 0 {
+		return false
+	}
+	return group.MaxSelections == 0 || group.MinimumSelections() <= group.MaxSelections
+}
+
+// GetModifierGroups retrieves the modifier groups and options offered on a food item
+func GetModifierGroups() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var groups []models.ModifierGroup
+		if err := databases.DB.WithContext(ctx).Preload("Modifiers").
+			Where("food_id = ?", c.Param("food_id")).
+			Order("id ASC").
+			Find(&groups).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve modifier groups. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, groups)
+	}
+}
+
+// CreateModifierGroup adds a modifier group, optionally with its modifiers, to a food item (admin only)
+func CreateModifierGroup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage food modifiers"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var group models.ModifierGroup
+		if err := c.ShouldBindJSON(&group); err != nil || !validModifierGroup(&group) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid modifier group data. A name and consistent min/max selections are required."})
+			return
+		}
+
+		var food models.Food
+		if err := databases.DB.WithContext(ctx).Where("food_id = ?", c.Param("food_id")).First(&food).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The food item referenced does not exist"})
+			return
+		}
+
+		group.GroupID = ""
+		group.FoodID = food.FoodID
+		for i := range group.Modifiers {
+			if group.Modifiers[i].Name == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Every modifier needs a name"})
+				return
+			}
+			group.Modifiers[i].ModifierID = ""
+			group.Modifiers[i].PriceDelta = group.Modifiers[i].PriceDelta.Round(food.Currency)
+		}
+
+		if err := databases.DB.WithContext(ctx).Create(&group).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create modifier group. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusCreated, group)
+	}
+}
+
+// UpdateModifierGroup modifies a modifier group's name and selection rules (admin only)
+func UpdateModifierGroup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage food modifiers"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var group models.ModifierGroup
+		if err := databases.DB.WithContext(ctx).Where("group_id = ?", c.Param("group_id")).First(&group).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The modifier group you're trying to update could not be found"})
+			return
+		}
+
+		var updateData struct {
+			Name          *string `json:"name"`
+			Required      *bool   `json:"required"`
+			MinSelections *int    `json:"min_selections"`
+			MaxSelections *int    `json:"max_selections"`
+		}
+		if err := c.ShouldBindJSON(&updateData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid modifier group data. Please check your input."})
+			return
+		}
+
+		if updateData.Name != nil {
+			group.Name = *updateData.Name
+		}
+		if updateData.Required != nil {
+			group.Required = *updateData.Required
+		}
+		if updateData.MinSelections != nil {
+			group.MinSelections = *updateData.MinSelections
+		}
+		if updateData.MaxSelections != nil {
+			group.MaxSelections = *updateData.MaxSelections
+		}
+
+		if !validModifierGroup(&group) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A modifier group needs a name and consistent min/max selections"})
+			return
+		}
+
+		if err := databases.DB.WithContext(ctx).Save(&group).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update modifier group. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, group)
+	}
+}
+
+// DeleteModifierGroup removes a modifier group and its modifiers (admin only). Orders keep the modifiers captured on their items.
+func DeleteModifierGroup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage food modifiers"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		groupId := c.Param("group_id")
+
+		var result *gorm.DB
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("group_id = ?", groupId).Delete(&models.Modifier{}).Error; err != nil {
+				return err
+			}
+			result = tx.Where("group_id = ?", groupId).Delete(&models.ModifierGroup{})
+			return result.Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete modifier group. Please try again later."})
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The modifier group you're trying to delete could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Modifier group has been successfully deleted"})
+	}
+}
+
+// CreateModifier adds an option to a modifier group (admin only)
+func CreateModifier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage food modifiers"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var modifier models.Modifier
+		if err := c.ShouldBindJSON(&modifier); err != nil || modifier.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid modifier data. A name is required."})
+			return
+		}
+
+		var group models.ModifierGroup
+		if err := databases.DB.WithContext(ctx).Preload("Food").Where("group_id = ?", c.Param("group_id")).First(&group).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The modifier group referenced does not exist"})
+			return
+		}
+
+		modifier.ModifierID = ""
+		modifier.GroupID = group.GroupID
+		modifier.PriceDelta = modifier.PriceDelta.Round(group.Food.Currency)
+
+		if err := databases.DB.WithContext(ctx).Create(&modifier).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create modifier. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusCreated, modifier)
+	}
+}
+
+// UpdateModifier changes a modifier's name or price delta (admin only). Existing order items keep their captured price.
+func UpdateModifier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage food modifiers"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var modifier models.Modifier
+		if err := databases.DB.WithContext(ctx).Where("modifier_id = ?", c.Param("modifier_id")).First(&modifier).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The modifier you're trying to update could not be found"})
+			return
+		}
+
+		var updateData struct {
+			Name       *string       `json:"name"`
+			PriceDelta *models.Money `json:"price_delta"`
+		}
+		if err := c.ShouldBindJSON(&updateData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid modifier data. Please check your input."})
+			return
+		}
+
+		if updateData.Name != nil {
+			if *updateData.Name == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "A modifier needs a name"})
+				return
+			}
+			modifier.Name = *updateData.Name
+		}
+
+		if updateData.PriceDelta != nil {
+			var group models.ModifierGroup
+			if err := databases.DB.WithContext(ctx).Preload("Food").Where("group_id = ?", modifier.GroupID).First(&group).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to load the modifier's food item. Please try again later."})
+				return
+			}
+			modifier.PriceDelta = updateData.PriceDelta.Round(group.Food.Currency)
+		}
+
+		if err := databases.DB.WithContext(ctx).Save(&modifier).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update modifier. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, modifier)
+	}
+}
+
+// DeleteModifier removes an option from its modifier group (admin only)
+func DeleteModifier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage food modifiers"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result := databases.DB.WithContext(ctx).Where("modifier_id = ?", c.Param("modifier_id")).Delete(&models.Modifier{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete modifier. Please try again later."})
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The modifier you're trying to delete could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Modifier has been successfully deleted"})
+	}
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/RestaurantApp/helpers"
 	"github.com/RestaurantApp/models"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // GetOrders retrieves all orders in the system (admin only)
@@ -74,6 +76,14 @@ func GetOrder() gin.HandlerFunc {
 	}
 }
 
+// orderRequest is the payload for placing an order; staff seating a waiting party or a reservation
+// at the table kept for them give its waitlist_id or reservation_id
+type orderRequest struct {
+	models.Order
+	WaitlistID    string `json:"waitlist_id"`
+	ReservationID string `json:"reservation_id"`
+}
+
 // CreateOrder places a new order in the system
 func CreateOrder() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -83,12 +93,13 @@ func CreateOrder() gin.HandlerFunc {
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
 		defer cancel()
 
-		var order models.Order
-		if err := c.ShouldBindJSON(&order); err != nil {
+		var request orderRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order information. Please check your input."})
 			return
 		}
 
+		order := request.Order
 		if userType == "USER" {
 			order.UserID = userId
 		}
@@ -98,40 +109,79 @@ func CreateOrder() gin.HandlerFunc {
 			return
 		}
 
-		// Validate that the table exists
-		var tableExists int64
-		if err := databases.DB.WithContext(ctx).Model(&models.Table{}).Where("table_id = ?", order.TableID).Count(&tableExists).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify table information. Please try again later."})
-			return
-		}
-
-		if tableExists == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "The table referenced does not exist"})
-			return
-		}
-
-		// Check if table is already occupied by an active order
-		var activeOrderCount int64
-		if err := databases.DB.WithContext(ctx).Model(&models.Order{}).
-			Where("table_id = ? AND order_status NOT IN ?", order.TableID, []string{"completed", "cancelled"}).
-			Count(&activeOrderCount).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to check table availability. Please try again later."})
-			return
-		}
-
-		if activeOrderCount > 0 {
-			c.JSON(http.StatusConflict, gin.H{"error": "This table is already occupied by an active order. Please choose a different table."})
+		if (request.WaitlistID != "" || request.ReservationID != "") && helpers.CheckUserType(c, "ADMIN") != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to seat a waiting party or reservation"})
 			return
 		}
 
 		order.OrderDate = time.Now()
-		order.OrderStatus = "pending"
-
-		if err := databases.DB.WithContext(ctx).Create(&order).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create order. Please try again later."})
+		order.OrderStatus = models.OrderStatusPending
+
+		var notifications []helpers.Notification
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Lock the table, and the rest of its group, so two waiters cannot seat the same table at once
+			tables, err := helpers.LockTableWithGroup(tx, order.TableID)
+			if err != nil {
+				return helpers.NewRequestError(http.StatusBadRequest, "The table referenced does not exist")
+			}
+
+			// Check if the table or its group is already occupied by an active order
+			if err := helpers.CheckTablesFree(tx, tables, ""); err != nil {
+				return err
+			}
+
+			// A table offered to a waiting party or booked for an arriving reservation is only given to that party
+			if err := helpers.CheckTablesNotHeld(tx, tables, request.WaitlistID, request.ReservationID); err != nil {
+				return err
+			}
+
+			if err := tx.Create(&order).Error; err != nil {
+				return err
+			}
+			if err := helpers.RecordOrderCreated(tx, &order, userId); err != nil {
+				return err
+			}
+
+			if request.WaitlistID != "" {
+				entry, err := helpers.LockWaitlistEntry(tx, request.WaitlistID)
+				if err != nil {
+					return helpers.NewRequestError(http.StatusBadRequest, "The waitlist entry referenced does not exist")
+				}
+
+				// A party seated somewhere other than the table it was offered passes that table on
+				offeredTable := ""
+				if entry.Status == models.WaitlistStatusNotified && entry.TableID != order.TableID {
+					offeredTable = entry.TableID
+				}
+				if err := helpers.SeatWaitlistEntry(tx, &entry, order.TableID, order.OrderID); err != nil {
+					return err
+				}
+				if offeredTable != "" {
+					if notifications, err = helpers.OfferFreedTables(tx, []string{offeredTable}); err != nil {
+						return err
+					}
+				}
+			}
+
+			if request.ReservationID != "" {
+				reservation, err := helpers.LockReservation(tx, request.ReservationID)
+				if err != nil {
+					return helpers.NewRequestError(http.StatusBadRequest, "The reservation referenced does not exist")
+				}
+				if err := helpers.TransitionReservation(tx, &reservation, models.ReservationStatusSeated, "", order.OrderID); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to create order. Please try again later.")
 			return
 		}
 
+		helpers.SendNotifications(notifications)
+
 		c.JSON(http.StatusCreated, order)
 	}
 }
@@ -148,12 +198,6 @@ func UpdateOrder() gin.HandlerFunc {
 		defer cancel()
 
 		orderId := c.Param("order_id")
-		var order models.Order
-
-		if err := databases.DB.WithContext(ctx).Where("order_id = ?", orderId).First(&order).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "The requested order could not be found"})
-			return
-		}
 
 		var updateData models.Order
 		if err := c.ShouldBindJSON(&updateData); err != nil {
@@ -166,13 +210,42 @@ func UpdateOrder() gin.HandlerFunc {
 			return
 		}
 
-		if err := databases.DB.WithContext(ctx).Where("order_id = ?", orderId).Updates(&updateData).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update order. Please try again later."})
-			return
-		}
-
-		if err := databases.DB.WithContext(ctx).Where("order_id = ?", orderId).First(&order).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve updated order. Please try again later."})
+		var order models.Order
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if order, err = helpers.LockOrder(tx, orderId); err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The requested order could not be found")
+			}
+
+			if updateData.OrderStatus != "" && updateData.OrderStatus != order.OrderStatus {
+				return helpers.NewRequestError(http.StatusBadRequest, "Order status can only be changed through the order status endpoint")
+			}
+			updateData.OrderStatus = ""
+
+			if updateData.TableID != "" && updateData.TableID != order.TableID {
+				return helpers.NewRequestError(http.StatusBadRequest, "An order can only be moved to another table through the transfer endpoint")
+			}
+
+			// Items, invoices and payments are priced in the order's currency, so it cannot change once the order is placed
+			if updateData.Currency != "" && updateData.Currency != order.Currency {
+				return helpers.NewRequestError(http.StatusBadRequest, "The currency of an order cannot be changed once it has been placed")
+			}
+
+			if !updateData.OrderTotal.IsZero() && updateData.OrderTotal != order.OrderTotal {
+				return helpers.NewRequestError(http.StatusBadRequest, "The order total is worked out from its items and cannot be set directly")
+			}
+
+			// The total follows the items and the status follows the lifecycle, so neither is written here
+			if err := tx.Where("order_id = ?", orderId).
+				Omit("OrderTotal", "OrderStatus", "Currency", "OrderItems", "User", "Table").
+				Updates(&updateData).Error; err != nil {
+				return err
+			}
+
+			return tx.Where("order_id = ?", orderId).First(&order).Error
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to update order. Please try again later.")
 			return
 		}
 
@@ -193,32 +266,78 @@ func DeleteOrder() gin.HandlerFunc {
 
 		orderId := c.Param("order_id")
 
-		var order models.Order
-		if err := databases.DB.WithContext(ctx).Where("order_id = ?", orderId).First(&order).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "The requested order could not be found"})
-			return
-		}
-
-		var invoiceCount int64
-		if err := databases.DB.WithContext(ctx).Model(&models.Invoice{}).Where("order_id = ?", orderId).Count(&invoiceCount).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to check related invoices. Please try again later."})
-			return
-		}
-
-		if invoiceCount > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "This order cannot be deleted because it has associated invoices"})
-			return
-		}
-
-		if err := databases.DB.WithContext(ctx).Where("order_id = ?", orderId).Delete(&models.OrderItem{}).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete related order items. Please try again later."})
+		var availabilityChanges []helpers.AvailabilityChange
+		var notifications []helpers.Notification
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			order, err := helpers.LockOrder(tx, orderId)
+			if err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The requested order could not be found")
+			}
+
+			// Orders that have been confirmed keep their status history; they are cancelled or voided instead
+			if !helpers.IsOrderEditable(order.OrderStatus) {
+				return helpers.NewRequestError(http.StatusConflict, "Only orders that have not been confirmed can be deleted. Cancel or void the order instead.")
+			}
+
+			var invoiceCount int64
+			if err := tx.Model(&models.Invoice{}).Where("order_id = ?", orderId).Count(&invoiceCount).Error; err != nil {
+				return err
+			}
+
+			if invoiceCount > 0 {
+				return helpers.NewRequestError(http.StatusBadRequest, "This order cannot be deleted because it has associated invoices")
+			}
+
+			var orderItemIds []string
+			if err := tx.Model(&models.OrderItem{}).Where("order_id = ?", orderId).Pluck("order_item_id", &orderItemIds).Error; err != nil {
+				return err
+			}
+
+			if availabilityChanges, err = helpers.ReleaseOrderItemStock(tx, orderItemIds, c.GetString("uid")); err != nil {
+				return err
+			}
+
+			if err := tx.Where("order_item_id IN (?)", tx.Model(&models.OrderItem{}).Select("order_item_id").Where("order_id = ?", orderId)).
+				Delete(&models.OrderItemModifier{}).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Where("order_id = ?", orderId).Delete(&models.OrderItem{}).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Where("order_id = ?", orderId).Delete(&models.OrderBundle{}).Error; err != nil {
+				return err
+			}
+
+			// An unconfirmed order's history holds no more than its creation
+			if err := tx.Where("order_id = ?", orderId).Delete(&models.OrderStatusHistory{}).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Where("ticket_id IN (?)", tx.Model(&models.KitchenTicket{}).Select("ticket_id").Where("order_id = ?", orderId)).
+				Delete(&models.KitchenTicketItem{}).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Where("order_id = ?", orderId).Delete(&models.KitchenTicket{}).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Where("order_id = ?", orderId).Delete(&order).Error; err != nil {
+				return err
+			}
+
+			notifications, err = helpers.OfferFreedTables(tx, []string{order.TableID})
+			return err
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to delete order. Please try again later.")
 			return
 		}
 
-		if err := databases.DB.WithContext(ctx).Where("order_id = ?", orderId).Delete(&order).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete order. Please try again later."})
-			return
-		}
+		helpers.PublishAvailabilityChanges(availabilityChanges)
+		helpers.SendNotifications(notifications)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Order and associated items deleted successfully"})
 	}
@@ -271,41 +390,239 @@ func AddItemToOrder() gin.HandlerFunc {
 
 		orderId := c.Param("order_id")
 
+		var request orderItemRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item information. Please check your input."})
+			return
+		}
+
+		orderItem := request.OrderItem
+		orderItem.OrderID = orderId
+		orderItem.BundleLineID = ""
+		if orderItem.Quantity < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The quantity must be at least 1"})
+			return
+		}
+		if orderItem.Seat < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The seat number cannot be negative"})
+			return
+		}
+
+		var bundleLine *models.OrderBundle
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			order, err := helpers.LockOrder(tx, orderId)
+			if err != nil || order.UserID != userId {
+				return helpers.NewRequestError(http.StatusNotFound, "You don't have an order with this ID")
+			}
+
+			if !helpers.IsOrderEditable(order.OrderStatus) {
+				return helpers.NewRequestError(http.StatusBadRequest, "Cannot modify order once it has been processed")
+			}
+
+			if request.BundleID != "" {
+				if bundleLine, err = addBundleLine(tx, &order, &request); err != nil {
+					return err
+				}
+				return helpers.RecalculateOrderTotal(tx, orderId)
+			}
+
+			var foodExists int64
+			if err := tx.Model(&models.Food{}).Where("food_id = ?", orderItem.FoodID).Count(&foodExists).Error; err != nil {
+				return err
+			}
+
+			if foodExists == 0 {
+				return helpers.NewRequestError(http.StatusBadRequest, "The selected food item does not exist")
+			}
+
+			if err := helpers.SnapshotOrderItem(tx, &orderItem, order.Currency); err != nil {
+				if errors.Is(err, helpers.ErrCurrencyMismatch) {
+					return helpers.NewRequestError(http.StatusBadRequest, "The selected food item is priced in a different currency than your order")
+				}
+				return err
+			}
+
+			if err := tx.Create(&orderItem).Error; err != nil {
+				return err
+			}
+
+			return helpers.RecalculateOrderTotal(tx, orderId)
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to add item to your order. Please try again later.")
+			return
+		}
+
+		if bundleLine != nil {
+			c.JSON(http.StatusCreated, bundleLine)
+			return
+		}
+
+		c.JSON(http.StatusCreated, orderItem)
+	}
+}
+
+// TransitionOrderStatus moves an order through its lifecycle, rejecting illegal status changes
+func TransitionOrderStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId := c.GetString("uid")
+		userType := c.GetString("user_type")
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		orderId := c.Param("order_id")
+
+		var request struct {
+			Status string `json:"status" binding:"required"`
+			Reason string `json:"reason"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A target status is required"})
+			return
+		}
+
+		if !helpers.IsValidOrderStatus(request.Status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown order status"})
+			return
+		}
+
 		var order models.Order
-		if err := databases.DB.WithContext(ctx).Where("order_id = ? AND user_id = ?", orderId, userId).First(&order).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "You don't have an order with this ID"})
+		var kitchenEvents []models.KitchenEvent
+		var availabilityChanges []helpers.AvailabilityChange
+		var notifications []helpers.Notification
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if order, err = helpers.LockOrder(tx, orderId); err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The requested order could not be found")
+			}
+
+			// Customers may only cancel their own orders; every other transition is staff only
+			if userType != "ADMIN" {
+				if order.UserID != userId || request.Status != models.OrderStatusCancelled {
+					return helpers.NewRequestError(http.StatusForbidden, "You don't have permission to change the status of this order")
+				}
+			}
+
+			// An invoiced order is only served again when its invoice is deleted
+			if order.OrderStatus == models.OrderStatusInvoiced && request.Status == models.OrderStatusServed {
+				return helpers.NewRequestError(http.StatusConflict, "Delete the order's invoice to bill it again")
+			}
+
+			if err := helpers.TransitionOrder(tx, &order, request.Status, userId, request.Reason); err != nil {
+				return err
+			}
+
+			// Confirmed orders are sent to the kitchen; cancelled ones are withdrawn from it
+			if kitchenEvents, err = helpers.ApplyKitchenSideEffects(tx, &order); err != nil {
+				return err
+			}
+
+			// Confirmed orders take their ingredients from stock; cancelled or voided ones give them back
+			if availabilityChanges, err = helpers.ApplyInventorySideEffects(tx, &order, userId); err != nil {
+				return err
+			}
+
+			// A finished order frees its table for the next party on the waitlist
+			notifications, err = helpers.OfferFinishedOrderTable(tx, &order)
+			return err
+		})
+		if errors.Is(err, helpers.ErrInvalidOrderTransition) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":        err.Error(),
+				"order_status": order.OrderStatus,
+				"allowed":      helpers.NextOrderStatuses(order.OrderStatus),
+			})
+			return
+		}
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to update order status. Please try again later.")
 			return
 		}
 
-		if order.OrderStatus != "pending" && order.OrderStatus != "draft" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot modify order once it has been processed"})
+		helpers.PublishKitchenEvents(kitchenEvents)
+		helpers.PublishAvailabilityChanges(availabilityChanges)
+		helpers.SendNotifications(notifications)
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// GetOrderStatusHistory lists every status change of an order (customers can only access their own)
+func GetOrderStatusHistory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		orderId := c.Param("order_id")
+
+		var order models.Order
+		if err := databases.DB.WithContext(ctx).Where("order_id = ?", orderId).First(&order).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested order could not be found"})
 			return
 		}
 
-		var orderItem models.OrderItem
-		if err := c.ShouldBindJSON(&orderItem); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item information. Please check your input."})
+		if err := helpers.MatchUserTypeToUid(c, order.UserID); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view this order"})
 			return
 		}
 
-		orderItem.OrderID = orderId
+		var history []models.OrderStatusHistory
+		if err := databases.DB.WithContext(ctx).Where("order_id = ?", orderId).Order("created_at ASC, id ASC").Find(&history).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve order history. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, history)
+	}
+}
 
-		var foodExists int64
-		if err := databases.DB.WithContext(ctx).Model(&models.Food{}).Where("food_id = ?", orderItem.FoodID).Count(&foodExists).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify food item. Please try again later."})
+// TransferOrder moves an active order and its items to another table, for guests who change
+// tables mid-meal (admin only)
+func TransferOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to move orders"})
 			return
 		}
 
-		if foodExists == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "The selected food item does not exist"})
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			TableID string `json:"table_id" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The table to move the order to is required"})
 			return
 		}
 
-		if err := databases.DB.WithContext(ctx).Create(&orderItem).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to add item to your order. Please try again later."})
+		var order models.Order
+		var kitchenEvents []models.KitchenEvent
+		var notifications []helpers.Notification
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if order, err = helpers.LockOrder(tx, c.Param("order_id")); err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The requested order could not be found")
+			}
+
+			previousTable := order.TableID
+			if kitchenEvents, err = helpers.TransferOrder(tx, &order, request.TableID); err != nil {
+				return err
+			}
+
+			// The table the guests left is free for the waitlist
+			notifications, err = helpers.OfferFreedTables(tx, []string{previousTable})
+			return err
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to move the order. Please try again later.")
 			return
 		}
 
-		c.JSON(http.StatusCreated, orderItem)
+		helpers.PublishKitchenEvents(kitchenEvents)
+		helpers.SendNotifications(notifications)
+
+		c.JSON(http.StatusOK, order)
 	}
 }
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
@@ -9,8 +10,51 @@ import (
 	"github.com/RestaurantApp/helpers"
 	"github.com/RestaurantApp/models"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// orderItemRequest is the payload for adding to an order: a single food, or a bundle when
+// bundle_id is set, with the foods chosen for its slots
+type orderItemRequest struct {
+	models.OrderItem
+	BundleID         string                    `json:"bundle_id"`
+	BundleSelections []helpers.BundleSelection `json:"bundle_selections"`
+}
+
+// orderItemUpdateRequest is the payload for changing an order item; fields left out are kept
+type orderItemUpdateRequest struct {
+	OrderItemID string                     `json:"order_item_id"`
+	Quantity    *int                       `json:"quantity"`
+	Seat        *int                       `json:"seat"`
+	Modifiers   []models.OrderItemModifier `json:"modifiers"`
+}
+
+// addBundleLine orders a bundle inside an order item transaction, translating a currency mismatch for the client
+func addBundleLine(tx *gorm.DB, order *models.Order, request *orderItemRequest) (*models.OrderBundle, error) {
+	line, err := helpers.AddBundleToOrder(tx, order, request.BundleID, request.Quantity, request.BundleSelections)
+	if errors.Is(err, helpers.ErrCurrencyMismatch) {
+		return nil, helpers.NewRequestError(http.StatusBadRequest, "The bundle is priced in a different currency than the order")
+	}
+	if err != nil || request.Seat == 0 {
+		return line, err
+	}
+
+	// Every component of a bundle is for the seat the bundle was ordered for
+	for i := range line.Items {
+		line.Items[i].Seat = request.Seat
+	}
+	return line, tx.Model(&models.OrderItem{}).Where("bundle_line_id = ?", line.BundleLineID).Update("seat", request.Seat).Error
+}
+
+// syncAcceptedOrderStock keeps stock in line with items changed on an order that has already taken
+// its ingredients from stock
+func syncAcceptedOrderStock(tx *gorm.DB, order *models.Order, orderItemIds []string, actorUid string) ([]helpers.AvailabilityChange, error) {
+	if !helpers.OrderDepletesStock(order.OrderStatus) {
+		return nil, nil
+	}
+	return helpers.SyncOrderItemStock(tx, order, orderItemIds, actorUid)
+}
+
 // GetOrderItems retrieves all order items in the system (admin only)
 func GetOrderItems() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -60,7 +104,7 @@ func GetOrderItem() gin.HandlerFunc {
 		orderItemId := c.Param("order_item_id")
 		var orderItem models.OrderItem
 
-		if err := databases.DB.WithContext(ctx).Where("order_item_id = ?", orderItemId).First(&orderItem).Error; err != nil {
+		if err := databases.DB.WithContext(ctx).Preload("Modifiers").Where("order_item_id = ?", orderItemId).First(&orderItem).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "The requested order item could not be found"})
 			return
 		}
@@ -89,70 +133,123 @@ func CreateOrderItem() gin.HandlerFunc {
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
 		defer cancel()
 
-		var orderItem models.OrderItem
-		if err := c.ShouldBindJSON(&orderItem); err != nil {
+		var request orderItemRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order item data. Please check your input."})
 			return
 		}
 
-		var order models.Order
-		if err := databases.DB.WithContext(ctx).Where("order_id = ?", orderItem.OrderID).First(&order).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "The order referenced does not exist"})
+		orderItem := request.OrderItem
+		orderItem.BundleLineID = ""
+		if orderItem.Quantity < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The quantity must be at least 1"})
+			return
+		}
+		if orderItem.Seat < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The seat number cannot be negative"})
 			return
 		}
 
-		if userType == "USER" {
-			if order.UserID != userId {
-				c.JSON(http.StatusForbidden, gin.H{"error": "You can only add items to your own orders"})
-				return
+		var bundleLine *models.OrderBundle
+		var availabilityChanges []helpers.AvailabilityChange
+		var kitchenEvents []models.KitchenEvent
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Lock the order so concurrent item changes are applied one at a time
+			order, err := helpers.LockOrder(tx, orderItem.OrderID)
+			if err != nil {
+				return helpers.NewRequestError(http.StatusBadRequest, "The order referenced does not exist")
 			}
 
-			if order.OrderStatus != "pending" && order.OrderStatus != "draft" {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "This order cannot be modified in its current state"})
-				return
+			if userType == "USER" {
+				if order.UserID != userId {
+					return helpers.NewRequestError(http.StatusForbidden, "You can only add items to your own orders")
+				}
+
+				if !helpers.IsOrderEditable(order.OrderStatus) {
+					return helpers.NewRequestError(http.StatusBadRequest, "This order cannot be modified in its current state")
+				}
 			}
-		}
 
-		var foodExists int64
-		if err := databases.DB.WithContext(ctx).Model(&models.Food{}).Where("food_id = ?", orderItem.FoodID).Count(&foodExists).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify food item information. Please try again later."})
-			return
-		}
+			// Bundles are added as a bundle line with one order item per component
+			if request.BundleID != "" {
+				if bundleLine, err = addBundleLine(tx, &order, &request); err != nil {
+					return err
+				}
+				if availabilityChanges, err = syncAcceptedOrderStock(tx, &order, bundleLine.ComponentIDs(), userId); err != nil {
+					return err
+				}
+				if kitchenEvents, err = helpers.SyncOrderItemTickets(tx, &order, bundleLine.ComponentIDs(), userId); err != nil {
+					return err
+				}
+				return helpers.RecalculateOrderTotal(tx, order.OrderID)
+			}
 
-		if foodExists == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "The food item referenced does not exist"})
-			return
-		}
+			var foodExists int64
+			if err := tx.Model(&models.Food{}).Where("food_id = ?", orderItem.FoodID).Count(&foodExists).Error; err != nil {
+				return err
+			}
+
+			if foodExists == 0 {
+				return helpers.NewRequestError(http.StatusBadRequest, "The food item referenced does not exist")
+			}
+
+			// Price the item at the current menu price, validating the selected modifiers
+			if err := helpers.SnapshotOrderItem(tx, &orderItem, order.Currency); err != nil {
+				if errors.Is(err, helpers.ErrCurrencyMismatch) {
+					return helpers.NewRequestError(http.StatusBadRequest, "The food item is priced in a different currency than the order")
+				}
+				return err
+			}
 
-		// Check if an order item with the same order_id and food_id already exists
-		var existingOrderItem models.OrderItem
-		result := databases.DB.WithContext(ctx).Where("order_id = ? AND food_id = ?", orderItem.OrderID, orderItem.FoodID).First(&existingOrderItem)
+			// Check if an order item with the same food, variant and modifiers already exists for the same seat
+			var existingOrderItem models.OrderItem
+			result := tx.Where("order_id = ? AND food_id = ? AND variant_id = ? AND modifier_key = ? AND seat = ? AND bundle_line_id = ''",
+				orderItem.OrderID, orderItem.FoodID, orderItem.VariantID, orderItem.ModifierKey, orderItem.Seat).First(&existingOrderItem)
+
+			if result.Error == nil {
+				// If item exists, update the quantity instead of creating a new one, keeping the price captured when it was first added
+				existingOrderItem.Quantity += orderItem.Quantity
+				lineTotal, err := helpers.LineTotal(existingOrderItem.UnitPrice, existingOrderItem.Quantity)
+				if err != nil {
+					return err
+				}
+				existingOrderItem.LineTotal = lineTotal
+				if err := tx.Save(&existingOrderItem).Error; err != nil {
+					return err
+				}
+				if err := tx.Where("order_item_id = ?", existingOrderItem.OrderItemID).Order("id ASC").Find(&existingOrderItem.Modifiers).Error; err != nil {
+					return err
+				}
+				orderItem = existingOrderItem
+			} else {
+				// If item doesn't exist, create it together with its modifiers
+				if err := tx.Create(&orderItem).Error; err != nil {
+					return err
+				}
+			}
 
-		if result.Error == nil {
-			// If item exists, update the quantity instead of creating a new one
-			existingOrderItem.Quantity += orderItem.Quantity
-			if err := databases.DB.WithContext(ctx).Save(&existingOrderItem).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update the existing order item. Please try again later."})
-				return
+			if availabilityChanges, err = syncAcceptedOrderStock(tx, &order, []string{orderItem.OrderItemID}, userId); err != nil {
+				return err
 			}
-			orderItem = existingOrderItem
-		} else {
-			// If item doesn't exist, create a new one
-			if err := databases.DB.WithContext(ctx).Create(&orderItem).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to add item to the order. Please try again later."})
-				return
+
+			// Items added to an order the kitchen already has go out on a new ticket
+			if kitchenEvents, err = helpers.SyncOrderItemTickets(tx, &order, []string{orderItem.OrderItemID}, userId); err != nil {
+				return err
 			}
+
+			return helpers.RecalculateOrderTotal(tx, orderItem.OrderID)
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to add item to the order. Please try again later.")
+			return
 		}
 
-		var totalAmount float64
-		if err := databases.DB.WithContext(ctx).Model(&models.OrderItem{}).
-			Select("COALESCE(SUM(quantity * (SELECT price FROM foods WHERE food_id = order_items.food_id)), 0)").
-			Where("order_id = ?", orderItem.OrderID).
-			Scan(&totalAmount).Error; err != nil {
-		} else {
-			databases.DB.WithContext(ctx).Model(&models.Order{}).
-				Where("order_id = ?", orderItem.OrderID).
-				Update("order_total", totalAmount)
+		helpers.PublishAvailabilityChanges(availabilityChanges)
+		helpers.PublishKitchenEvents(kitchenEvents)
+
+		if bundleLine != nil {
+			c.JSON(http.StatusCreated, bundleLine)
+			return
 		}
 
 		c.JSON(http.StatusCreated, orderItem)
@@ -169,73 +266,104 @@ func UpdateOrderItem() gin.HandlerFunc {
 		defer cancel()
 
 		orderItemId := c.Param("order_item_id")
-		var orderItem models.OrderItem
 
-		if err := databases.DB.WithContext(ctx).Where("order_item_id = ?", orderItemId).First(&orderItem).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "The order item you're trying to update could not be found"})
+		var request orderItemUpdateRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order item data. Please check your input."})
 			return
 		}
 
-		var order models.Order
-		if err := databases.DB.WithContext(ctx).Where("order_id = ?", orderItem.OrderID).First(&order).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "The related order information could not be found"})
+		if request.OrderItemID != orderItemId {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The order item ID in the request does not match the URL"})
 			return
 		}
 
-		if userType == "USER" {
-			if order.UserID != userId {
-				c.JSON(http.StatusForbidden, gin.H{"error": "You can only update items in your own orders"})
-				return
-			}
-
-			if order.OrderStatus != "pending" && order.OrderStatus != "draft" {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "This order cannot be modified in its current state"})
-				return
-			}
-		}
-
-		var updateData models.OrderItem
-		if err := c.ShouldBindJSON(&updateData); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order item data. Please check your input."})
+		if request.Quantity != nil && *request.Quantity < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The quantity must be at least 1"})
 			return
 		}
 
-		if updateData.OrderItemID != orderItemId {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "The order item ID in the request does not match the URL"})
+		if request.Seat != nil && *request.Seat < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The seat number cannot be negative"})
 			return
 		}
 
-		if userType == "USER" {
-			updates := map[string]interface{}{
-				"quantity": updateData.Quantity,
+		var orderItem models.OrderItem
+		var availabilityChanges []helpers.AvailabilityChange
+		var kitchenEvents []models.KitchenEvent
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var current models.OrderItem
+			if err := tx.Select("order_id").Where("order_item_id = ?", orderItemId).First(&current).Error; err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The order item you're trying to update could not be found")
 			}
 
-			if err := databases.DB.WithContext(ctx).Model(&orderItem).Updates(updates).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update the order item. Please try again later."})
-				return
+			// Lock the order before reading the item, so concurrent changes to the order cannot be overwritten
+			order, err := helpers.LockOrder(tx, current.OrderID)
+			if err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The related order information could not be found")
 			}
-		} else {
-			if err := databases.DB.WithContext(ctx).Where("order_item_id = ?", orderItemId).Updates(&updateData).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update the order item. Please try again later."})
-				return
+
+			if err := tx.Where("order_item_id = ? AND order_id = ?", orderItemId, order.OrderID).First(&orderItem).Error; err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The order item you're trying to update could not be found")
+			}
+
+			if orderItem.BundleLineID != "" {
+				return helpers.NewRequestError(http.StatusBadRequest, "This item is part of a bundle; change the bundle instead")
+			}
+
+			if userType == "USER" {
+				if order.UserID != userId {
+					return helpers.NewRequestError(http.StatusForbidden, "You can only update items in your own orders")
+				}
+
+				if !helpers.IsOrderEditable(order.OrderStatus) {
+					return helpers.NewRequestError(http.StatusBadRequest, "This order cannot be modified in its current state")
+				}
+			}
+
+			// Only the quantity, seat and modifiers can change; the food, order and prices are kept
+			if request.Quantity != nil {
+				orderItem.Quantity = *request.Quantity
+			}
+			if request.Seat != nil {
+				orderItem.Seat = *request.Seat
+			}
+
+			// A different modifier selection only changes the modifier deltas; the price captured at order time is kept
+			if request.Modifiers != nil {
+				if err := helpers.ReselectOrderItemModifiers(tx, &orderItem, request.Modifiers); err != nil {
+					return err
+				}
+			} else if err := tx.Where("order_item_id = ?", orderItemId).Order("id ASC").Find(&orderItem.Modifiers).Error; err != nil {
+				return err
+			}
+			lineTotal, err := helpers.LineTotal(orderItem.UnitPrice, orderItem.Quantity)
+			if err != nil {
+				return err
+			}
+			orderItem.LineTotal = lineTotal
+
+			if err := tx.Omit("Modifiers").Save(&orderItem).Error; err != nil {
+				return err
+			}
+
+			if availabilityChanges, err = syncAcceptedOrderStock(tx, &order, []string{orderItem.OrderItemID}, userId); err != nil {
+				return err
 			}
-		}
 
-		if err := databases.DB.WithContext(ctx).Where("order_item_id = ?", orderItemId).First(&orderItem).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order item was updated but could not be retrieved"})
+			if kitchenEvents, err = helpers.SyncOrderItemTickets(tx, &order, []string{orderItem.OrderItemID}, userId); err != nil {
+				return err
+			}
+
+			return helpers.RecalculateOrderTotal(tx, orderItem.OrderID)
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to update the order item. Please try again later.")
 			return
 		}
 
-		var totalAmount float64
-		if err := databases.DB.WithContext(ctx).Model(&models.OrderItem{}).
-			Select("COALESCE(SUM(quantity * (SELECT price FROM foods WHERE food_id = order_items.food_id)), 0)").
-			Where("order_id = ?", orderItem.OrderID).
-			Scan(&totalAmount).Error; err != nil {
-		} else {
-			databases.DB.WithContext(ctx).Model(&models.Order{}).
-				Where("order_id = ?", orderItem.OrderID).
-				Update("order_total", totalAmount)
-		}
+		helpers.PublishAvailabilityChanges(availabilityChanges)
+		helpers.PublishKitchenEvents(kitchenEvents)
 
 		c.JSON(http.StatusOK, orderItem)
 	}
@@ -252,47 +380,64 @@ func DeleteOrderItem() gin.HandlerFunc {
 
 		orderItemId := c.Param("order_item_id")
 
-		var orderItem models.OrderItem
-		if err := databases.DB.WithContext(ctx).Where("order_item_id = ?", orderItemId).First(&orderItem).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "The order item you're trying to remove could not be found"})
-			return
-		}
+		var availabilityChanges []helpers.AvailabilityChange
+		var kitchenEvents []models.KitchenEvent
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var orderItem models.OrderItem
+			if err := tx.Where("order_item_id = ?", orderItemId).First(&orderItem).Error; err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The order item you're trying to remove could not be found")
+			}
 
-		var order models.Order
-		if err := databases.DB.WithContext(ctx).Where("order_id = ?", orderItem.OrderID).First(&order).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "The related order information could not be found"})
-			return
-		}
+			if orderItem.BundleLineID != "" {
+				return helpers.NewRequestError(http.StatusBadRequest, "This item is part of a bundle; remove the bundle instead")
+			}
 
-		if userType == "USER" {
-			if order.UserID != userId {
-				c.JSON(http.StatusForbidden, gin.H{"error": "You can only remove items from your own orders"})
-				return
+			order, err := helpers.LockOrder(tx, orderItem.OrderID)
+			if err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The related order information could not be found")
 			}
 
-			if order.OrderStatus != "pending" && order.OrderStatus != "draft" {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "This order cannot be modified in its current state"})
-				return
+			if userType == "USER" {
+				if order.UserID != userId {
+					return helpers.NewRequestError(http.StatusForbidden, "You can only remove items from your own orders")
+				}
+
+				if !helpers.IsOrderEditable(order.OrderStatus) {
+					return helpers.NewRequestError(http.StatusBadRequest, "This order cannot be modified in its current state")
+				}
+			}
+
+			// Ingredients already taken for the item go back into stock
+			if availabilityChanges, err = helpers.ReleaseOrderItemStock(tx, []string{orderItemId}, userId); err != nil {
+				return err
 			}
-		}
 
-		orderId := orderItem.OrderID
+			if err := tx.Where("order_item_id = ?", orderItemId).Delete(&models.OrderItemModifier{}).Error; err != nil {
+				return err
+			}
 
-		if err := databases.DB.WithContext(ctx).Where("order_item_id = ?", orderItemId).Delete(&orderItem).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to remove the item from the order. Please try again later."})
+			result := tx.Where("order_item_id = ?", orderItemId).Delete(&models.OrderItem{})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return helpers.NewRequestError(http.StatusNotFound, "The order item you're trying to remove could not be found")
+			}
+
+			// The kitchen stops on the item if it has not started it yet
+			if kitchenEvents, err = helpers.SyncOrderItemTickets(tx, &order, []string{orderItemId}, userId); err != nil {
+				return err
+			}
+
+			return helpers.RecalculateOrderTotal(tx, order.OrderID)
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to remove the item from the order. Please try again later.")
 			return
 		}
 
-		var totalAmount float64
-		if err := databases.DB.WithContext(ctx).Model(&models.OrderItem{}).
-			Select("COALESCE(SUM(quantity * (SELECT price FROM foods WHERE food_id = order_items.food_id)), 0)").
-			Where("order_id = ?", orderId).
-			Scan(&totalAmount).Error; err != nil {
-		} else {
-			databases.DB.WithContext(ctx).Model(&models.Order{}).
-				Where("order_id = ?", orderId).
-				Update("order_total", totalAmount)
-		}
+		helpers.PublishAvailabilityChanges(availabilityChanges)
+		helpers.PublishKitchenEvents(kitchenEvents)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Item has been successfully removed from the order"})
 	}
@@ -331,7 +476,7 @@ func GetOrderItemsByOrder() gin.HandlerFunc {
 			return
 		}
 
-		if err := databases.DB.WithContext(ctx).Where("order_id = ?", orderId).
+		if err := databases.DB.WithContext(ctx).Preload("Modifiers").Where("order_id = ?", orderId).
 			Offset(offset).
 			Limit(pagination.Limit).
 			Find(&orderItems).Error; err != nil {
@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetInvoicePayments lists the payments taken against an invoice, voided ones included, with
+// its balance (customers can only view their own)
+func GetInvoicePayments() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		db := databases.DB.WithContext(ctx)
+
+		var invoice models.Invoice
+		if err := db.Where("invoice_id = ?", c.Param("invoice_id")).First(&invoice).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested invoice could not be found"})
+			return
+		}
+
+		var order models.Order
+		if err := db.Where("order_id = ?", invoice.OrderID).First(&order).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The related order information could not be found"})
+			return
+		}
+
+		if err := helpers.MatchUserTypeToUid(c, order.UserID); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view this invoice"})
+			return
+		}
+
+		var payments []models.Payment
+		if err := db.Where("invoice_id = ?", invoice.InvoiceID).Order("paid_at ASC, id ASC").Find(&payments).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve payments. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"invoice_id":     invoice.InvoiceID,
+			"currency":       invoice.Currency,
+			"total_amount":   invoice.TotalAmount,
+			"amount_paid":    invoice.AmountPaid,
+			"balance_due":    invoice.BalanceDue,
+			"payment_status": invoice.PaymentStatus,
+			"payments":       payments,
+		})
+	}
+}
+
+// AddInvoicePayment records a payment towards an invoice. Several payments, by different methods,
+// can settle one invoice; it becomes paid once the balance reaches zero (admin only)
+func AddInvoicePayment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to take payments"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			Amount    models.Money `json:"amount"`
+			Tendered  models.Money `json:"tendered"`
+			Method    string       `json:"method" binding:"required"`
+			Reference string       `json:"reference"`
+			PaidAt    *time.Time   `json:"paid_at"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment data provided. A payment method is required."})
+			return
+		}
+
+		payment := models.Payment{
+			Amount:     request.Amount,
+			Tendered:   request.Tendered,
+			Method:     request.Method,
+			Reference:  request.Reference,
+			ReceivedBy: c.GetString("uid"),
+		}
+		if request.PaidAt != nil {
+			payment.PaidAt = *request.PaidAt
+		}
+
+		var invoice models.Invoice
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if invoice, err = helpers.LockInvoice(tx, c.Param("invoice_id")); err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The requested invoice could not be found")
+			}
+
+			return helpers.RecordPayment(tx, &invoice, &payment)
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to record the payment. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"payment": payment,
+			"invoice": invoice,
+		})
+	}
+}
+
+// AuthorizeInvoicePayment takes a card-present or online payment towards an invoice through the
+// payment provider, capturing it straight away unless capture is false (admin only)
+func AuthorizeInvoicePayment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to take payments"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			Amount    models.Money `json:"amount"`
+			Method    string       `json:"method"`
+			Channel   string       `json:"channel" binding:"required"`
+			Token     string       `json:"token" binding:"required"`
+			Reference string       `json:"reference"`
+			Capture   *bool        `json:"capture"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment data provided. A channel and payment token are required."})
+			return
+		}
+
+		payment := models.Payment{
+			Amount:     request.Amount,
+			Method:     request.Method,
+			Channel:    request.Channel,
+			Reference:  request.Reference,
+			ReceivedBy: c.GetString("uid"),
+		}
+		capture := request.Capture == nil || *request.Capture
+
+		invoice, err := helpers.AuthorizeProviderPayment(ctx, databases.DB.WithContext(ctx), c.Param("invoice_id"), &payment, request.Token, capture)
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to take the payment. Please try again later.")
+			return
+		}
+
+		// A declined attempt is kept on the ledger, but the invoice is still owed
+		status := http.StatusCreated
+		if payment.Status == models.PaymentStatusDeclined {
+			status = http.StatusPaymentRequired
+		}
+
+		c.JSON(status, gin.H{
+			"payment": payment,
+			"invoice": invoice,
+		})
+	}
+}
+
+// CaptureInvoicePayment collects a payment the provider has authorized (admin only)
+func CaptureInvoicePayment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to take payments"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		payment := models.Payment{PaymentID: c.Param("payment_id")}
+		invoice, err := helpers.CaptureProviderPayment(ctx, databases.DB.WithContext(ctx), c.Param("invoice_id"), &payment)
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to capture the payment. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"payment": payment,
+			"invoice": invoice,
+		})
+	}
+}
+
+// PaymentWebhook receives asynchronous payment results from the payment provider. The body must be
+// signed with the shared webhook secret in the X-Payment-Signature header. An event that has
+// already been processed is acknowledged without being applied again.
+func PaymentWebhook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to read the webhook body"})
+			return
+		}
+
+		valid, err := helpers.VerifyPaymentWebhook(body, c.GetHeader(helpers.PaymentSignatureHeader))
+		if errors.Is(err, helpers.ErrWebhookNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payment webhooks are not configured"})
+			return
+		}
+		if err != nil || !valid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook signature"})
+			return
+		}
+
+		var event helpers.PaymentEvent
+		if err := json.Unmarshal(body, &event); err != nil || event.EventID == "" || event.Type == "" || (event.Reference == "" && event.PaymentID == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment event. An event id, type and a reference or payment id are required."})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var payment models.Payment
+		err = databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			payment, err = helpers.ApplyPaymentEvent(tx, event)
+			return err
+		})
+		if errors.Is(err, helpers.ErrDuplicatePaymentEvent) {
+			c.JSON(http.StatusOK, gin.H{
+				"received":   true,
+				"duplicate":  true,
+				"payment_id": payment.PaymentID,
+				"status":     payment.Status,
+			})
+			return
+		}
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to process the payment event. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"received":   true,
+			"payment_id": payment.PaymentID,
+			"status":     payment.Status,
+		})
+	}
+}
+
+// VoidInvoicePayment voids a payment taken in error, putting its amount back on the invoice's
+// balance (admin only)
+func VoidInvoicePayment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to void payments"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			Reason string `json:"reason" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A reason for voiding the payment is required"})
+			return
+		}
+
+		payment := models.Payment{PaymentID: c.Param("payment_id")}
+		invoice, err := helpers.VoidPayment(ctx, databases.DB.WithContext(ctx), c.Param("invoice_id"), &payment, c.GetString("uid"), request.Reason)
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to void the payment. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"payment": payment,
+			"invoice": invoice,
+		})
+	}
+}
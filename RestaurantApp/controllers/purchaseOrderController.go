@@ -0,0 +1,494 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// purchaseOrderLines orders the lines of a purchase order as they were entered
+func purchaseOrderLines(db *gorm.DB) *gorm.DB {
+	return db.Order("id ASC")
+}
+
+// GetPurchaseOrders lists purchase orders, newest first, optionally filtered by status or supplier (admin only)
+func GetPurchaseOrders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view purchase orders"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		pagination := helpers.GetPaginationParams(c)
+		offset := helpers.GetOffset(pagination.Page, pagination.Limit)
+
+		query := databases.DB.WithContext(ctx).Model(&models.PurchaseOrder{})
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if supplierId := c.Query("supplier_id"); supplierId != "" {
+			query = query.Where("supplier_id = ?", supplierId)
+		}
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to count purchase orders"})
+			return
+		}
+
+		var orders []models.PurchaseOrder
+		if err := query.Preload("Lines", purchaseOrderLines).Order("id DESC").Offset(offset).Limit(pagination.Limit).Find(&orders).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve purchase orders. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":       orders,
+			"pagination": helpers.CreatePaginationResponse(pagination.Page, pagination.Limit, total),
+		})
+	}
+}
+
+// GetPurchaseOrder retrieves a purchase order with its lines (admin only)
+func GetPurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view purchase orders"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var order models.PurchaseOrder
+		if err := databases.DB.WithContext(ctx).Preload("Lines", purchaseOrderLines).
+			Where("purchase_order_id = ?", c.Param("purchase_order_id")).First(&order).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested purchase order could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// CreatePurchaseOrder drafts a purchase order for quantities of a supplier's stock items (admin only)
+func CreatePurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to create purchase orders"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var order models.PurchaseOrder
+		if err := c.ShouldBindJSON(&order); err != nil || order.SupplierID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purchase order data provided. A supplier_id and lines are required."})
+			return
+		}
+
+		order.ID = 0
+		order.PurchaseOrderID = ""
+		order.Status = models.PurchaseOrderStatusDraft
+		order.SentAt = nil
+		order.CreatedBy = c.GetString("uid")
+		if order.Currency == "" {
+			order.Currency = models.DefaultCurrency()
+		}
+		for i := range order.Lines {
+			order.Lines[i].ID = 0
+			order.Lines[i].LineID = ""
+		}
+
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var supplierExists int64
+			if err := tx.Model(&models.Supplier{}).Where("supplier_id = ?", order.SupplierID).Count(&supplierExists).Error; err != nil {
+				return err
+			}
+
+			if supplierExists == 0 {
+				return helpers.NewRequestError(http.StatusBadRequest, "The supplier referenced does not exist")
+			}
+
+			if err := helpers.PricePurchaseOrderLines(tx, &order); err != nil {
+				return err
+			}
+
+			return tx.Create(&order).Error
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to create purchase order. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusCreated, order)
+	}
+}
+
+// UpdatePurchaseOrder changes a draft purchase order, replacing its lines when they are given (admin only)
+func UpdatePurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update purchase orders"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var updateData struct {
+			Notes        *string                    `json:"notes"`
+			ExpectedDate *time.Time                 `json:"expected_date"`
+			Lines        []models.PurchaseOrderLine `json:"lines"`
+		}
+		if err := c.ShouldBindJSON(&updateData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purchase order data provided. Please check your input."})
+			return
+		}
+
+		var order models.PurchaseOrder
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if order, err = helpers.LockPurchaseOrder(tx, c.Param("purchase_order_id")); err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The purchase order you're trying to update could not be found")
+			}
+
+			if order.Status != models.PurchaseOrderStatusDraft {
+				return helpers.NewRequestError(http.StatusBadRequest, "Only draft purchase orders can be changed")
+			}
+
+			if updateData.Notes != nil {
+				order.Notes = *updateData.Notes
+			}
+			if updateData.ExpectedDate != nil {
+				order.ExpectedDate = updateData.ExpectedDate
+			}
+
+			if updateData.Lines != nil {
+				order.Lines = updateData.Lines
+				for i := range order.Lines {
+					order.Lines[i].ID = 0
+					order.Lines[i].LineID = ""
+					order.Lines[i].PurchaseOrderID = order.PurchaseOrderID
+				}
+				if err := helpers.PricePurchaseOrderLines(tx, &order); err != nil {
+					return err
+				}
+
+				if err := tx.Where("purchase_order_id = ?", order.PurchaseOrderID).Delete(&models.PurchaseOrderLine{}).Error; err != nil {
+					return err
+				}
+				if err := tx.Create(&order.Lines).Error; err != nil {
+					return err
+				}
+			} else if err := purchaseOrderLines(tx).Where("purchase_order_id = ?", order.PurchaseOrderID).Find(&order.Lines).Error; err != nil {
+				return err
+			}
+
+			return tx.Model(&order).Select("notes", "expected_date", "total").Updates(&order).Error
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to update purchase order. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// DeletePurchaseOrder removes a draft purchase order (admin only). Sent orders are cancelled instead.
+func DeletePurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete purchase orders"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			order, err := helpers.LockPurchaseOrder(tx, c.Param("purchase_order_id"))
+			if err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The purchase order you're trying to delete could not be found")
+			}
+
+			if order.Status != models.PurchaseOrderStatusDraft {
+				return helpers.NewRequestError(http.StatusBadRequest, "Only draft purchase orders can be deleted; cancel a sent order instead")
+			}
+
+			if err := tx.Where("purchase_order_id = ?", order.PurchaseOrderID).Delete(&models.PurchaseOrderLine{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&order).Error
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to delete purchase order. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Purchase order has been successfully deleted"})
+	}
+}
+
+// writePurchaseOrderDocument renders a purchase order as a PDF or CSV file download
+func writePurchaseOrderDocument(c *gin.Context, tx *gorm.DB, order *models.PurchaseOrder) error {
+	format := c.DefaultQuery("format", helpers.DocumentFormatPDF)
+	if format != helpers.DocumentFormatPDF && format != helpers.DocumentFormatCSV {
+		return helpers.NewRequestError(http.StatusBadRequest, "The document format must be pdf or csv")
+	}
+
+	var supplier models.Supplier
+	if err := tx.Where("supplier_id = ?", order.SupplierID).First(&supplier).Error; err != nil {
+		return err
+	}
+
+	if err := purchaseOrderLines(tx).Where("purchase_order_id = ?", order.PurchaseOrderID).Find(&order.Lines).Error; err != nil {
+		return err
+	}
+
+	stockItemIds := make([]string, 0, len(order.Lines))
+	for _, line := range order.Lines {
+		stockItemIds = append(stockItemIds, line.StockItemID)
+	}
+
+	var stockItems []models.StockItem
+	if err := tx.Where("stock_item_id IN ?", stockItemIds).Find(&stockItems).Error; err != nil {
+		return err
+	}
+
+	items := make(map[string]models.StockItem, len(stockItems))
+	for _, item := range stockItems {
+		items[item.StockItemID] = item
+	}
+
+	filename := fmt.Sprintf("purchase-order-%s.%s", order.PurchaseOrderID, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == helpers.DocumentFormatCSV {
+		document, err := helpers.PurchaseOrderCSV(order, &supplier, items)
+		if err != nil {
+			return err
+		}
+		c.Data(http.StatusOK, "text/csv", document)
+		return nil
+	}
+
+	c.Data(http.StatusOK, "application/pdf", helpers.PurchaseOrderPDF(order, &supplier, items))
+	return nil
+}
+
+// GetPurchaseOrderDocument downloads a purchase order as a PDF or, with ?format=csv, a CSV file (admin only)
+func GetPurchaseOrderDocument() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view purchase orders"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		db := databases.DB.WithContext(ctx)
+
+		var order models.PurchaseOrder
+		if err := db.Where("purchase_order_id = ?", c.Param("purchase_order_id")).First(&order).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested purchase order could not be found"})
+			return
+		}
+
+		if err := writePurchaseOrderDocument(c, db, &order); err != nil {
+			helpers.RespondWithError(c, err, "Unable to render the purchase order. Please try again later.")
+		}
+	}
+}
+
+// SendPurchaseOrder marks a draft purchase order as sent and returns the document to pass on to
+// the supplier, as a PDF or, with ?format=csv, a CSV file (admin only)
+func SendPurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to send purchase orders"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var order models.PurchaseOrder
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if order, err = helpers.LockPurchaseOrder(tx, c.Param("purchase_order_id")); err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The purchase order you're trying to send could not be found")
+			}
+
+			if order.Status != models.PurchaseOrderStatusDraft {
+				return helpers.NewRequestError(http.StatusBadRequest, "This purchase order has already been sent")
+			}
+
+			now := time.Now()
+			order.Status = models.PurchaseOrderStatusSent
+			order.SentAt = &now
+			return tx.Model(&order).Select("status", "sent_at").Updates(&order).Error
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to send purchase order. Please try again later.")
+			return
+		}
+
+		if err := writePurchaseOrderDocument(c, databases.DB.WithContext(ctx), &order); err != nil {
+			helpers.RespondWithError(c, err, "The purchase order was sent but its document could not be rendered.")
+		}
+	}
+}
+
+// CancelPurchaseOrder cancels a purchase order that nothing has been received against (admin only)
+func CancelPurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to cancel purchase orders"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var order models.PurchaseOrder
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if order, err = helpers.LockPurchaseOrder(tx, c.Param("purchase_order_id")); err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The purchase order you're trying to cancel could not be found")
+			}
+
+			if order.Status != models.PurchaseOrderStatusDraft && order.Status != models.PurchaseOrderStatusSent {
+				return helpers.NewRequestError(http.StatusBadRequest, "Only purchase orders with nothing received can be cancelled")
+			}
+
+			order.Status = models.PurchaseOrderStatusCancelled
+			return tx.Model(&order).Update("status", order.Status).Error
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to cancel purchase order. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// GetGoodsReceipts lists the deliveries received against a purchase order, including reversed ones (admin only)
+func GetGoodsReceipts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view goods receipts"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var receipts []models.GoodsReceipt
+		if err := databases.DB.WithContext(ctx).Preload("Lines", purchaseOrderLines).
+			Where("purchase_order_id = ?", c.Param("purchase_order_id")).Order("id ASC").Find(&receipts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve goods receipts. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, receipts)
+	}
+}
+
+// ReceiveGoods records a full or partial delivery against a sent purchase order, adding the goods
+// to stock at the actual unit cost paid (admin only)
+func ReceiveGoods() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to receive goods"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var receipt models.GoodsReceipt
+		if err := c.ShouldBindJSON(&receipt); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goods receipt data provided. Please check your input."})
+			return
+		}
+
+		var availabilityChanges []helpers.AvailabilityChange
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			order, err := helpers.LockPurchaseOrder(tx, c.Param("purchase_order_id"))
+			if err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The requested purchase order could not be found")
+			}
+
+			if err := purchaseOrderLines(tx).Where("purchase_order_id = ?", order.PurchaseOrderID).Find(&order.Lines).Error; err != nil {
+				return err
+			}
+
+			availabilityChanges, err = helpers.ReceiveGoods(tx, &order, &receipt, c.GetString("uid"))
+			return err
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to receive goods. Please try again later.")
+			return
+		}
+
+		helpers.PublishAvailabilityChanges(availabilityChanges)
+
+		c.JSON(http.StatusCreated, receipt)
+	}
+}
+
+// ReverseGoodsReceipt undoes a goods receipt entered in error, taking its stock back out (admin only)
+func ReverseGoodsReceipt() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to reverse goods receipts"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			Reason string `json:"reason" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required to reverse a goods receipt"})
+			return
+		}
+
+		var receipt models.GoodsReceipt
+		var availabilityChanges []helpers.AvailabilityChange
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("receipt_id = ?", c.Param("receipt_id")).First(&receipt).Error; err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The goods receipt you're trying to reverse could not be found")
+			}
+
+			var err error
+			availabilityChanges, err = helpers.ReverseGoodsReceipt(tx, &receipt, request.Reason, c.GetString("uid"))
+			return err
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to reverse the goods receipt. Please try again later.")
+			return
+		}
+
+		helpers.PublishAvailabilityChanges(availabilityChanges)
+
+		c.JSON(http.StatusOK, receipt)
+	}
+}
@@ -0,0 +1,403 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+)
+
+// SalesReportLine is the quantity and revenue of one food, or one variant of it, over a report period
+type SalesReportLine struct {
+	FoodID      string       `json:"food_id"`
+	FoodName    string       `json:"food_name"`
+	VariantID   string       `json:"variant_id"`
+	VariantName string       `json:"variant_name"`
+	SKU         string       `json:"sku"`
+	Currency    string       `json:"currency"`
+	Quantity    int64        `json:"quantity"`
+	Revenue     models.Money `json:"revenue"`
+}
+
+// SalesReportTotal sums a sales report per currency. Revenue is taken from the order items' line
+// totals, which include tax when menu prices are tax-inclusive and exclude it otherwise. Refunds are
+// the credit notes issued over the period on the same basis: the tax part of a credit note is left
+// out for invoices taxed on top of their prices, and reported as RefundedTax. NetRevenue is the
+// revenue less those refunds.
+type SalesReportTotal struct {
+	Currency    string       `json:"currency"`
+	Quantity    int64        `json:"quantity"`
+	Revenue     models.Money `json:"revenue"`
+	Refunds     models.Money `json:"refunds"`
+	RefundedTax models.Money `json:"refunded_tax"`
+	NetRevenue  models.Money `json:"net_revenue"`
+}
+
+// GetSalesReport lists units sold and revenue per food and variant over a date range, with totals
+// net of the refunds issued over it (admin only)
+func GetSalesReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view sales reports"})
+			return
+		}
+
+		period, err := helpers.GetReportPeriod(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		// Names are taken from the order item snapshots, so renamed foods still report under one line
+		var lines []SalesReportLine
+		if err := databases.DB.WithContext(ctx).Model(&models.OrderItem{}).
+			Select(`order_items.food_id, MAX(order_items.food_name) AS food_name,
+				order_items.variant_id, MAX(order_items.variant_name) AS variant_name, MAX(order_items.sku) AS sku,
+				orders.currency, SUM(order_items.quantity) AS quantity, SUM(order_items.line_total) AS revenue`).
+			Joins("JOIN orders ON orders.order_id = order_items.order_id").
+			Where("orders.order_status IN ? AND orders.order_date >= ? AND orders.order_date < ?", helpers.SalesOrderStatuses, period.From, period.To).
+			Group("order_items.food_id, order_items.variant_id, orders.currency").
+			Order("revenue DESC, food_name ASC, variant_name ASC").
+			Scan(&lines).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to build the sales report. Please try again later."})
+			return
+		}
+
+		var refunds []struct {
+			Currency string
+			Amount   models.Money
+			Tax      models.Money
+		}
+		if err := databases.DB.WithContext(ctx).Model(&models.CreditNote{}).
+			Select(`credit_notes.currency,
+				SUM(CASE WHEN invoices.prices_include_tax THEN credit_notes.amount
+					ELSE credit_notes.amount - COALESCE(credit_notes.tax_amount, 0) END) AS amount,
+				SUM(COALESCE(credit_notes.tax_amount, 0)) AS tax`).
+			Joins("JOIN invoices ON invoices.invoice_id = credit_notes.invoice_id").
+			Where("credit_notes.issued_at >= ? AND credit_notes.issued_at < ?", period.From, period.To).
+			Group("credit_notes.currency").
+			Scan(&refunds).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to build the sales report. Please try again later."})
+			return
+		}
+
+		var totals []SalesReportTotal
+		totalsByCurrency := make(map[string]int)
+		total := func(currency string) *SalesReportTotal {
+			index, ok := totalsByCurrency[currency]
+			if !ok {
+				index = len(totals)
+				totalsByCurrency[currency] = index
+				totals = append(totals, SalesReportTotal{Currency: currency})
+			}
+			return &totals[index]
+		}
+		for _, line := range lines {
+			lineTotal := total(line.Currency)
+			lineTotal.Quantity += line.Quantity
+			lineTotal.Revenue = lineTotal.Revenue.Add(line.Revenue)
+		}
+		for _, refund := range refunds {
+			refundTotal := total(refund.Currency)
+			refundTotal.Refunds = refundTotal.Refunds.Add(refund.Amount)
+			refundTotal.RefundedTax = refundTotal.RefundedTax.Add(refund.Tax)
+		}
+		for i := range totals {
+			totals[i].NetRevenue = totals[i].Revenue.Sub(totals[i].Refunds)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"period": period.Response(),
+			"data":   lines,
+			"totals": totals,
+		})
+	}
+}
+
+// LowStockLine is an ingredient whose on-hand stock has fallen below its reorder point
+type LowStockLine struct {
+	IngredientID string          `json:"ingredient_id"`
+	Name         string          `json:"name"`
+	Unit         string          `json:"unit"`
+	OnHand       models.Quantity `json:"on_hand"`
+	ReorderPoint models.Quantity `json:"reorder_point"`
+	Shortfall    models.Quantity `json:"shortfall"`
+}
+
+// GetLowStockReport lists the ingredients under their reorder point, largest shortfall first (admin only)
+func GetLowStockReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view stock reports"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var lines []LowStockLine
+		if err := databases.DB.WithContext(ctx).Model(&models.Ingredient{}).
+			Select("ingredient_id, name, unit, on_hand, reorder_point, reorder_point - on_hand AS shortfall").
+			Where("on_hand < reorder_point").
+			Order("shortfall DESC, name ASC").
+			Scan(&lines).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to build the low stock report. Please try again later."})
+			return
+		}
+
+		if lines == nil {
+			lines = []LowStockLine{}
+		}
+		c.JSON(http.StatusOK, lines)
+	}
+}
+
+// WasteReportLine sums the waste for one reason, item or day in one currency
+type WasteReportLine struct {
+	Key      string          `json:"key"`
+	Name     string          `json:"name,omitempty"`
+	Kind     string          `json:"kind,omitempty"`
+	Currency string          `json:"currency"`
+	Entries  int64           `json:"entries"`
+	Quantity models.Quantity `json:"quantity"`
+	Cost     models.Money    `json:"cost"`
+}
+
+// WasteReportTotal compares the waste in one currency to the sales over the same period
+type WasteReportTotal struct {
+	Currency       string       `json:"currency"`
+	Cost           models.Money `json:"cost"`
+	Sales          models.Money `json:"sales"`
+	PercentOfSales float64      `json:"percent_of_sales"`
+}
+
+// wasteBreakdown sums waste entries into lines, keeping lines in the order their keys first appear
+type wasteBreakdown struct {
+	lines []WasteReportLine
+	index map[[2]string]int
+}
+
+func (breakdown *wasteBreakdown) add(key, name, kind string, entry *models.WasteEntry) {
+	if breakdown.index == nil {
+		breakdown.index = make(map[[2]string]int)
+	}
+
+	id := [2]string{key, entry.Currency}
+	i, ok := breakdown.index[id]
+	if !ok {
+		i = len(breakdown.lines)
+		breakdown.index[id] = i
+		breakdown.lines = append(breakdown.lines, WasteReportLine{Key: key, Name: name, Kind: kind, Currency: entry.Currency})
+	}
+
+	line := &breakdown.lines[i]
+	line.Entries++
+	line.Cost = line.Cost.Add(entry.Cost)
+	if kind != "" {
+		// Quantities are only comparable within one item
+		line.Quantity = line.Quantity.Add(entry.Quantity)
+	}
+}
+
+// GetWasteReport breaks the cost of waste down by reason, by food or stock item and by day over a date
+// range, alongside sales for the same period so food cost can be reconciled against revenue (admin only)
+func GetWasteReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view waste reports"})
+			return
+		}
+
+		period, err := helpers.GetReportPeriod(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var entries []models.WasteEntry
+		if err := databases.DB.WithContext(ctx).
+			Where("wasted_at >= ? AND wasted_at < ?", period.From, period.To).
+			Order("wasted_at ASC, id ASC").
+			Find(&entries).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to build the waste report. Please try again later."})
+			return
+		}
+
+		var sales []struct {
+			Currency string
+			Revenue  models.Money
+		}
+		if err := databases.DB.WithContext(ctx).Model(&models.OrderItem{}).
+			Select("orders.currency, SUM(order_items.line_total) AS revenue").
+			Joins("JOIN orders ON orders.order_id = order_items.order_id").
+			Where("orders.order_status IN ? AND orders.order_date >= ? AND orders.order_date < ?", helpers.SalesOrderStatuses, period.From, period.To).
+			Group("orders.currency").
+			Scan(&sales).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to build the waste report. Please try again later."})
+			return
+		}
+
+		var byReason, byItem, byDay wasteBreakdown
+		for i := range entries {
+			entry := &entries[i]
+			byReason.add(entry.Reason, "", "", entry)
+			if entry.FoodID != "" {
+				byItem.add(entry.FoodID, entry.Name, "food", entry)
+			} else {
+				byItem.add(entry.StockItemID, entry.Name, "stock_item", entry)
+			}
+			byDay.add(entry.WastedAt.In(helpers.RestaurantLocation()).Format("2006-01-02"), "", "", entry)
+		}
+
+		var totals []WasteReportTotal
+		totalsByCurrency := make(map[string]int)
+		total := func(currency string) *WasteReportTotal {
+			index, ok := totalsByCurrency[currency]
+			if !ok {
+				index = len(totals)
+				totalsByCurrency[currency] = index
+				totals = append(totals, WasteReportTotal{Currency: currency})
+			}
+			return &totals[index]
+		}
+		for _, line := range byReason.lines {
+			t := total(line.Currency)
+			t.Cost = t.Cost.Add(line.Cost)
+		}
+		for _, row := range sales {
+			total(row.Currency).Sales = row.Revenue
+		}
+		for i := range totals {
+			totals[i].PercentOfSales = helpers.PercentOf(totals[i].Cost, totals[i].Sales)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"period":    period.Response(),
+			"by_reason": nonNilWasteLines(byReason.lines),
+			"by_item":   nonNilWasteLines(byItem.lines),
+			"by_day":    nonNilWasteLines(byDay.lines),
+			"totals":    totals,
+		})
+	}
+}
+
+func nonNilWasteLines(lines []WasteReportLine) []WasteReportLine {
+	if lines == nil {
+		return []WasteReportLine{}
+	}
+	return lines
+}
+
+// FoodCostReportLine is the theoretical ingredient cost of one food, or one variant of it, sold over a report period
+type FoodCostReportLine struct {
+	FoodID          string       `json:"food_id"`
+	FoodName        string       `json:"food_name"`
+	VariantID       string       `json:"variant_id"`
+	VariantName     string       `json:"variant_name"`
+	Quantity        int64        `json:"quantity"`
+	Revenue         models.Money `json:"revenue"`
+	PlateCost       models.Money `json:"plate_cost"`
+	TheoreticalCost models.Money `json:"theoretical_cost"`
+	FoodCostPercent float64      `json:"food_cost_percent"`
+}
+
+// GetFoodCostReport compares the theoretical food cost of what was sold over a date range, from
+// current recipes and ingredient costs, with an actual cost figure passed as ?actual_cost=, e.g.
+// purchases adjusted for the change in stock. Only sales in the default currency are costed (admin only)
+func GetFoodCostReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view food cost reports"})
+			return
+		}
+
+		period, err := helpers.GetReportPeriod(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var actualCost *models.Money
+		if value := c.Query("actual_cost"); value != "" {
+			amount, err := models.ParseMoney(value)
+			if err != nil || amount.IsNegative() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The actual cost must be an amount that is not negative"})
+				return
+			}
+			actualCost = &amount
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		db := databases.DB.WithContext(ctx)
+		currency := models.DefaultCurrency()
+
+		var lines []FoodCostReportLine
+		if err := db.Model(&models.OrderItem{}).
+			Select(`order_items.food_id, MAX(order_items.food_name) AS food_name,
+				order_items.variant_id, MAX(order_items.variant_name) AS variant_name,
+				SUM(order_items.quantity) AS quantity, SUM(order_items.line_total) AS revenue`).
+			Joins("JOIN orders ON orders.order_id = order_items.order_id").
+			Where("orders.order_status IN ? AND orders.order_date >= ? AND orders.order_date < ? AND orders.currency = ?",
+				helpers.SalesOrderStatuses, period.From, period.To, currency).
+			Group("order_items.food_id, order_items.variant_id").
+			Order("food_name ASC, variant_name ASC").
+			Scan(&lines).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to build the food cost report. Please try again later."})
+			return
+		}
+
+		var revenue, theoreticalCost models.Money
+		for i := range lines {
+			line := &lines[i]
+			if line.PlateCost, err = helpers.FoodUnitCost(db, line.FoodID, line.VariantID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to build the food cost report. Please try again later."})
+				return
+			}
+			theoretical, err := line.PlateCost.Mul(line.Quantity)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to build the food cost report. Please try again later."})
+				return
+			}
+			line.TheoreticalCost = theoretical.Round(currency)
+			line.FoodCostPercent = helpers.PercentOf(line.TheoreticalCost, line.Revenue)
+
+			revenue = revenue.Add(line.Revenue)
+			theoreticalCost = theoreticalCost.Add(line.TheoreticalCost)
+		}
+
+		summary := gin.H{
+			"currency":                      currency,
+			"revenue":                       revenue,
+			"theoretical_cost":              theoreticalCost,
+			"theoretical_food_cost_percent": helpers.PercentOf(theoreticalCost, revenue),
+		}
+		if actualCost != nil {
+			variance := actualCost.Sub(theoreticalCost)
+			summary["actual_cost"] = *actualCost
+			summary["actual_food_cost_percent"] = helpers.PercentOf(*actualCost, revenue)
+			summary["variance"] = variance
+			summary["variance_percent"] = helpers.PercentOf(variance, theoreticalCost)
+		}
+
+		if lines == nil {
+			lines = []FoodCostReportLine{}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"period":  period.Response(),
+			"data":    lines,
+			"summary": summary,
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// reservationDateLayout is the format of the date query parameter of reservation searches
+const reservationDateLayout = "2006-01-02"
+
+// GetReservationAvailability lists the open time slots on a date for a party size, with the
+// tables free in each. The optional duration_minutes defaults to the standard reservation length.
+func GetReservationAvailability() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		day, err := time.ParseInLocation(reservationDateLayout, c.Query("date"), helpers.RestaurantLocation())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A date in the format YYYY-MM-DD is required"})
+			return
+		}
+
+		partySize, err := strconv.Atoi(c.Query("party_size"))
+		if err != nil || partySize <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A party_size of at least one is required"})
+			return
+		}
+
+		duration := helpers.DefaultReservationDuration()
+		if value := c.Query("duration_minutes"); value != "" {
+			if duration, err = strconv.Atoi(value); err != nil || duration <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The duration must be a positive number of minutes"})
+				return
+			}
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		slots, err := helpers.FindReservationSlots(databases.DB.WithContext(ctx), day, partySize, duration)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to search for available times. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"date":             day.Format(reservationDateLayout),
+			"party_size":       partySize,
+			"duration_minutes": duration,
+			"slots":            slots,
+		})
+	}
+}
+
+// GetReservations lists reservations in start time order, optionally for one date, status or table (admin only)
+func GetReservations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view reservations"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		pagination := helpers.GetPaginationParams(c)
+		offset := helpers.GetOffset(pagination.Page, pagination.Limit)
+
+		query := databases.DB.WithContext(ctx).Model(&models.Reservation{})
+		if value := c.Query("date"); value != "" {
+			day, err := time.ParseInLocation(reservationDateLayout, value, helpers.RestaurantLocation())
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The date must be in the format YYYY-MM-DD"})
+				return
+			}
+			query = query.Where("start_time >= ? AND start_time < ?", day, day.AddDate(0, 0, 1))
+		}
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if tableId := c.Query("table_id"); tableId != "" {
+			query = query.Where("table_id = ?", tableId)
+		}
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to count reservations"})
+			return
+		}
+
+		var reservations []models.Reservation
+		if err := query.Order("start_time ASC, id ASC").Offset(offset).Limit(pagination.Limit).Find(&reservations).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve reservations. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":       reservations,
+			"pagination": helpers.CreatePaginationResponse(pagination.Page, pagination.Limit, total),
+		})
+	}
+}
+
+// GetReservation retrieves a reservation (admin or the user who made it)
+func GetReservation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var reservation models.Reservation
+		if err := databases.DB.WithContext(ctx).Where("reservation_id = ?", c.Param("reservation_id")).First(&reservation).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested reservation could not be found"})
+			return
+		}
+
+		if err := helpers.MatchUserTypeToUid(c, reservation.CreatedBy); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view this reservation"})
+			return
+		}
+
+		c.JSON(http.StatusOK, reservation)
+	}
+}
+
+// CreateReservation books a table for a party. Without a table_id the smallest suitable table that
+// is free for the whole slot is assigned.
+func CreateReservation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var reservation models.Reservation
+		if err := c.ShouldBindJSON(&reservation); err != nil || reservation.GuestName == "" || reservation.StartTime.IsZero() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reservation data provided. A guest name, party size and start time are required."})
+			return
+		}
+
+		reservation.ID = 0
+		reservation.ReservationID = ""
+		reservation.Status = models.ReservationStatusBooked
+		reservation.OrderID = ""
+		reservation.SeatedAt, reservation.CancelledAt, reservation.CancellationReason = nil, nil, ""
+		reservation.CreatedBy = c.GetString("uid")
+
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := helpers.AssignReservationTable(tx, &reservation); err != nil {
+				return err
+			}
+			return tx.Create(&reservation).Error
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to create reservation. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusCreated, reservation)
+	}
+}
+
+// UpdateReservation changes the guest details, party size, time or table of a booked reservation,
+// checking the new slot for conflicts (admin or the user who made it)
+func UpdateReservation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			GuestName       *string    `json:"guest_name"`
+			GuestPhone      *string    `json:"guest_phone"`
+			PartySize       *int       `json:"party_size"`
+			StartTime       *time.Time `json:"start_time"`
+			DurationMinutes *int       `json:"duration_minutes"`
+			TableID         *string    `json:"table_id"`
+			Notes           *string    `json:"notes"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reservation data provided. Please check your input."})
+			return
+		}
+
+		var reservation models.Reservation
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if reservation, err = helpers.LockReservation(tx, c.Param("reservation_id")); err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The reservation you're trying to update could not be found")
+			}
+			if err := helpers.MatchUserTypeToUid(c, reservation.CreatedBy); err != nil {
+				return helpers.NewRequestError(http.StatusForbidden, "You don't have permission to update this reservation")
+			}
+			if reservation.Status != models.ReservationStatusBooked {
+				return helpers.NewRequestError(http.StatusBadRequest, "Only a booked reservation can be changed")
+			}
+
+			if request.GuestName != nil {
+				reservation.GuestName = *request.GuestName
+			}
+			if request.GuestPhone != nil {
+				reservation.GuestPhone = *request.GuestPhone
+			}
+			if request.Notes != nil {
+				reservation.Notes = *request.Notes
+			}
+
+			rebook := false
+			if request.PartySize != nil {
+				reservation.PartySize, rebook = *request.PartySize, true
+			}
+			if request.StartTime != nil {
+				reservation.StartTime, rebook = *request.StartTime, true
+			}
+			if request.DurationMinutes != nil {
+				reservation.DurationMinutes, rebook = *request.DurationMinutes, true
+			}
+			if request.TableID != nil {
+				reservation.TableID, rebook = *request.TableID, true
+			}
+			if rebook {
+				if err := helpers.AssignReservationTable(tx, &reservation); err != nil {
+					return err
+				}
+			}
+
+			return tx.Model(&reservation).
+				Select("guest_name", "guest_phone", "party_size", "start_time", "duration_minutes", "end_time", "table_id", "notes").
+				Updates(&reservation).Error
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to update reservation. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, reservation)
+	}
+}
+
+// UpdateReservationStatus seats a reservation, marks it as a no-show or cancels it. Staff can make
+// any of these changes; the user who made the reservation can only cancel it.
+func UpdateReservationStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			Status  string `json:"status" binding:"required"`
+			Reason  string `json:"reason"`
+			OrderID string `json:"order_id"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A status is required"})
+			return
+		}
+
+		isAdmin := helpers.CheckUserType(c, "ADMIN") == nil
+		if !isAdmin && request.Status != models.ReservationStatusCancelled {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to change this reservation's status"})
+			return
+		}
+
+		var reservation models.Reservation
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if reservation, err = helpers.LockReservation(tx, c.Param("reservation_id")); err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The reservation could not be found")
+			}
+			if err := helpers.MatchUserTypeToUid(c, reservation.CreatedBy); err != nil {
+				return helpers.NewRequestError(http.StatusForbidden, "You don't have permission to change this reservation's status")
+			}
+
+			return helpers.TransitionReservation(tx, &reservation, request.Status, request.Reason, request.OrderID)
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to update reservation status. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, reservation)
+	}
+}
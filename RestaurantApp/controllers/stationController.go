@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetStations retrieves all kitchen stations (admin only)
+func GetStations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view kitchen stations"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var stations []models.Station
+		if err := databases.DB.WithContext(ctx).Order("name ASC").Find(&stations).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve kitchen stations. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, stations)
+	}
+}
+
+// CreateStation adds a new kitchen station such as grill, fryer, bar or pastry (admin only)
+func CreateStation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to create kitchen stations"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var station models.Station
+		if err := c.ShouldBindJSON(&station); err != nil || station.Name == "" || station.Kind == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid station data provided. A name and kind are required."})
+			return
+		}
+
+		if err := databases.DB.WithContext(ctx).Create(&station).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create kitchen station. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusCreated, station)
+	}
+}
+
+// UpdateStation modifies an existing kitchen station (admin only)
+func UpdateStation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update kitchen stations"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		stationId := c.Param("station_id")
+		var station models.Station
+
+		if err := databases.DB.WithContext(ctx).Where("station_id = ?", stationId).First(&station).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The kitchen station you're trying to update could not be found"})
+			return
+		}
+
+		var updateData models.Station
+		if err := c.ShouldBindJSON(&updateData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid station data provided. Please check your input."})
+			return
+		}
+		updateData.StationID = ""
+
+		if err := databases.DB.WithContext(ctx).Model(&station).Updates(&updateData).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update kitchen station. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, station)
+	}
+}
+
+// DeleteStation removes a kitchen station and its routes (admin only)
+func DeleteStation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete kitchen stations"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		stationId := c.Param("station_id")
+
+		var openTickets int64
+		if err := databases.DB.WithContext(ctx).Model(&models.KitchenTicket{}).
+			Where("station_id = ? AND status IN ?", stationId, []string{models.TicketStatusQueued, models.TicketStatusInProgress}).
+			Count(&openTickets).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to check the station's open tickets. Please try again later."})
+			return
+		}
+
+		if openTickets > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This station cannot be deleted while it has open tickets"})
+			return
+		}
+
+		var result *gorm.DB
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("station_id = ?", stationId).Delete(&models.StationRoute{}).Error; err != nil {
+				return err
+			}
+			result = tx.Where("station_id = ?", stationId).Delete(&models.Station{})
+			return result.Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete kitchen station. Please try again later."})
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The kitchen station you're trying to delete could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Kitchen station has been successfully deleted"})
+	}
+}
+
+// GetStationRoutes lists the foods and menu categories routed to a station (admin only)
+func GetStationRoutes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view station routing"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var routes []models.StationRoute
+		if err := databases.DB.WithContext(ctx).Where("station_id = ?", c.Param("station_id")).Find(&routes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve station routing. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, routes)
+	}
+}
+
+// CreateStationRoute routes a food or a menu category to a station, replacing any existing route for it (admin only)
+func CreateStationRoute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to change station routing"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var route models.StationRoute
+		if err := c.ShouldBindJSON(&route); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid route data provided. Please check your input."})
+			return
+		}
+
+		if (route.FoodID == "") == (route.Category == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A route must name either a food_id or a category"})
+			return
+		}
+
+		route.StationID = c.Param("station_id")
+
+		var stationExists int64
+		if err := databases.DB.WithContext(ctx).Model(&models.Station{}).Where("station_id = ?", route.StationID).Count(&stationExists).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify station information. Please try again later."})
+			return
+		}
+
+		if stationExists == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested kitchen station could not be found"})
+			return
+		}
+
+		if route.FoodID != "" {
+			var foodExists int64
+			if err := databases.DB.WithContext(ctx).Model(&models.Food{}).Where("food_id = ?", route.FoodID).Count(&foodExists).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify food item information. Please try again later."})
+				return
+			}
+
+			if foodExists == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The food item referenced does not exist"})
+				return
+			}
+		}
+
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// A food or category is prepared at one station only
+			if err := tx.Where("food_id = ? AND category = ?", route.FoodID, route.Category).Delete(&models.StationRoute{}).Error; err != nil {
+				return err
+			}
+			return tx.Create(&route).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to save station route. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusCreated, route)
+	}
+}
+
+// DeleteStationRoute removes a route from a station (admin only)
+func DeleteStationRoute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to change station routing"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result := databases.DB.WithContext(ctx).
+			Where("station_id = ? AND route_id = ?", c.Param("station_id"), c.Param("route_id")).
+			Delete(&models.StationRoute{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete station route. Please try again later."})
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The station route you're trying to delete could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Station route has been successfully deleted"})
+	}
+}
+
+// GetStationQueue retrieves the open tickets a station still has to work on, oldest first (admin only)
+func GetStationQueue() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view station queues"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		// Unrouted items are queued under the empty station, reachable as /stations/unassigned/queue
+		stationId := c.Param("station_id")
+		if stationId == "unassigned" {
+			stationId = ""
+		}
+
+		var tickets []models.KitchenTicket
+		if err := databases.DB.WithContext(ctx).
+			Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+			Where("station_id = ? AND status IN ?", stationId, []string{models.TicketStatusQueued, models.TicketStatusInProgress}).
+			Order("created_at ASC").
+			Find(&tickets).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve the station queue. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, tickets)
+	}
+}
@@ -0,0 +1,355 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// stockCountLines orders the lines of a count sheet by ingredient name
+func stockCountLines(db *gorm.DB) *gorm.DB {
+	return db.Order("name ASC")
+}
+
+// GetStockCounts lists stock counts, newest first, optionally filtered by status (admin only)
+func GetStockCounts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view stock counts"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		pagination := helpers.GetPaginationParams(c)
+		offset := helpers.GetOffset(pagination.Page, pagination.Limit)
+
+		query := databases.DB.WithContext(ctx).Model(&models.StockCount{})
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to count stock counts"})
+			return
+		}
+
+		var counts []models.StockCount
+		if err := query.Order("id DESC").Offset(offset).Limit(pagination.Limit).Find(&counts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve stock counts. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":       counts,
+			"pagination": helpers.CreatePaginationResponse(pagination.Page, pagination.Limit, total),
+		})
+	}
+}
+
+// GetStockCount retrieves a stock count with its count sheet (admin only)
+func GetStockCount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view stock counts"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var count models.StockCount
+		if err := databases.DB.WithContext(ctx).Preload("Lines", stockCountLines).
+			Where("stock_count_id = ?", c.Param("stock_count_id")).First(&count).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested stock count could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, count)
+	}
+}
+
+// OpenStockCount starts a stock take for the given ingredients, or for all of them (admin only)
+func OpenStockCount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to open stock counts"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			Note          string   `json:"note"`
+			IngredientIDs []string `json:"ingredient_ids"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stock count data provided"})
+			return
+		}
+
+		count := models.StockCount{
+			Note:     request.Note,
+			OpenedBy: c.GetString("uid"),
+		}
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return helpers.OpenStockCount(tx, &count, request.IngredientIDs)
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to open stock count. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusCreated, count)
+	}
+}
+
+// RecordStockCountEntries saves the quantities counted on one device. Several devices can count
+// the same session at once; each device's entries replace its own earlier ones (admin only)
+func RecordStockCountEntries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to enter stock counts"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			DeviceID string                   `json:"device_id"`
+			Entries  []models.StockCountEntry `json:"entries"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid count data provided. A device_id and entries are required."})
+			return
+		}
+
+		stockCountId := c.Param("stock_count_id")
+
+		var entries []models.StockCountEntry
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			count, err := helpers.LockStockCount(tx, stockCountId)
+			if err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The stock count could not be found")
+			}
+
+			if err := helpers.RecordStockCountEntries(tx, &count, request.DeviceID, c.GetString("uid"), request.Entries); err != nil {
+				return err
+			}
+
+			return tx.Where("stock_count_id = ? AND device_id = ?", stockCountId, request.DeviceID).
+				Order("id ASC").Find(&entries).Error
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to record counted quantities. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"device_id": request.DeviceID,
+			"entries":   entries,
+		})
+	}
+}
+
+// GetStockCountEntries lists the quantities counted in a stock count, optionally for one device (admin only)
+func GetStockCountEntries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view stock counts"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		query := databases.DB.WithContext(ctx).Where("stock_count_id = ?", c.Param("stock_count_id"))
+		if deviceId := c.Query("device_id"); deviceId != "" {
+			query = query.Where("device_id = ?", deviceId)
+		}
+		if ingredientId := c.Query("ingredient_id"); ingredientId != "" {
+			query = query.Where("ingredient_id = ?", ingredientId)
+		}
+
+		var entries []models.StockCountEntry
+		if err := query.Order("id ASC").Find(&entries).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve counted quantities. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, entries)
+	}
+}
+
+// DeleteStockCountEntry removes a counted quantity entered in error while the count is open (admin only)
+func DeleteStockCountEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to enter stock counts"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			count, err := helpers.LockStockCount(tx, c.Param("stock_count_id"))
+			if err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The stock count could not be found")
+			}
+			if count.Status != models.StockCountStatusOpen {
+				return helpers.NewRequestError(http.StatusBadRequest, "This stock count is no longer open and cannot be changed")
+			}
+
+			result := tx.Where("entry_id = ? AND stock_count_id = ?", c.Param("entry_id"), count.StockCountID).Delete(&models.StockCountEntry{})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return helpers.NewRequestError(http.StatusNotFound, "The count entry you're trying to delete could not be found")
+			}
+			return nil
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to delete count entry. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Count entry has been successfully deleted"})
+	}
+}
+
+// CloseStockCount sets every counted ingredient to its counted quantity and locks the count (admin only)
+func CloseStockCount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to close stock counts"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var count models.StockCount
+		var availabilityChanges []helpers.AvailabilityChange
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			count, err = helpers.LockStockCount(tx, c.Param("stock_count_id"))
+			if err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The stock count you're trying to close could not be found")
+			}
+
+			availabilityChanges, err = helpers.CloseStockCount(tx, &count, c.GetString("uid"))
+			return err
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to close stock count. Please try again later.")
+			return
+		}
+
+		helpers.PublishAvailabilityChanges(availabilityChanges)
+
+		c.JSON(http.StatusOK, count)
+	}
+}
+
+// CancelStockCount abandons an open stock count without changing any stock (admin only)
+func CancelStockCount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to cancel stock counts"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var count models.StockCount
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			count, err = helpers.LockStockCount(tx, c.Param("stock_count_id"))
+			if err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The stock count you're trying to cancel could not be found")
+			}
+			if count.Status != models.StockCountStatusOpen {
+				return helpers.NewRequestError(http.StatusBadRequest, "Only an open stock count can be cancelled")
+			}
+
+			count.Status = models.StockCountStatusCancelled
+			return tx.Model(&count).Update("status", count.Status).Error
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to cancel stock count. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, count)
+	}
+}
+
+// GetStockCountVariance reports, per ingredient, the difference between the recorded on-hand
+// quantity and the counted stock and what it is worth. An open count is compared with current
+// stock; a closed count shows the figures it was closed with (admin only)
+func GetStockCountVariance() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view stock counts"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		db := databases.DB.WithContext(ctx)
+
+		var count models.StockCount
+		if err := db.Where("stock_count_id = ?", c.Param("stock_count_id")).First(&count).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested stock count could not be found"})
+			return
+		}
+
+		lines, err := helpers.StockCountVariance(db, &count)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to build the variance report. Please try again later."})
+			return
+		}
+
+		var counted, uncounted int
+		var gains, losses models.Money
+		for _, line := range lines {
+			if !line.Counted {
+				uncounted++
+				continue
+			}
+			counted++
+			if line.VarianceCost.IsNegative() {
+				losses = losses.Add(line.VarianceCost)
+			} else {
+				gains = gains.Add(line.VarianceCost)
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"stock_count": count,
+			"data":        lines,
+			"summary": gin.H{
+				"currency":          models.DefaultCurrency(),
+				"counted_lines":     counted,
+				"uncounted_lines":   uncounted,
+				"gain_cost":         gains,
+				"loss_cost":         losses,
+				"net_variance_cost": gains.Add(losses),
+			},
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetSuppliers retrieves all suppliers (admin only)
+func GetSuppliers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view suppliers"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var suppliers []models.Supplier
+		if err := databases.DB.WithContext(ctx).Order("name ASC").Find(&suppliers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve suppliers. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, suppliers)
+	}
+}
+
+// GetSupplier retrieves a specific supplier by ID (admin only)
+func GetSupplier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view suppliers"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var supplier models.Supplier
+		if err := databases.DB.WithContext(ctx).Where("supplier_id = ?", c.Param("supplier_id")).First(&supplier).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested supplier could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, supplier)
+	}
+}
+
+// CreateSupplier adds a new supplier (admin only)
+func CreateSupplier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to create suppliers"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var supplier models.Supplier
+		if err := c.ShouldBindJSON(&supplier); err != nil || supplier.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid supplier data provided. A name is required."})
+			return
+		}
+		supplier.SupplierID = ""
+
+		if err := databases.DB.WithContext(ctx).Create(&supplier).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create supplier. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusCreated, supplier)
+	}
+}
+
+// UpdateSupplier modifies an existing supplier's details (admin only)
+func UpdateSupplier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update suppliers"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var supplier models.Supplier
+		if err := databases.DB.WithContext(ctx).Where("supplier_id = ?", c.Param("supplier_id")).First(&supplier).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The supplier you're trying to update could not be found"})
+			return
+		}
+
+		var updateData models.Supplier
+		if err := c.ShouldBindJSON(&updateData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid supplier data provided. Please check your input."})
+			return
+		}
+		updateData.SupplierID = ""
+
+		if err := databases.DB.WithContext(ctx).Model(&supplier).Updates(&updateData).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update supplier. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, supplier)
+	}
+}
+
+// DeleteSupplier removes a supplier that has no purchase orders, together with its stock items (admin only)
+func DeleteSupplier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete suppliers"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		supplierId := c.Param("supplier_id")
+
+		var purchaseOrderCount int64
+		if err := databases.DB.WithContext(ctx).Model(&models.PurchaseOrder{}).Where("supplier_id = ?", supplierId).Count(&purchaseOrderCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to check the supplier's purchase orders. Please try again later."})
+			return
+		}
+
+		if purchaseOrderCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This supplier cannot be deleted because it has purchase orders"})
+			return
+		}
+
+		var result *gorm.DB
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("supplier_id = ?", supplierId).Delete(&models.StockItem{}).Error; err != nil {
+				return err
+			}
+			result = tx.Where("supplier_id = ?", supplierId).Delete(&models.Supplier{})
+			return result.Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete supplier. Please try again later."})
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The supplier you're trying to delete could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Supplier has been successfully deleted"})
+	}
+}
+
+// GetStockItems lists the stock items a supplier sells (admin only)
+func GetStockItems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view stock items"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var items []models.StockItem
+		if err := databases.DB.WithContext(ctx).Where("supplier_id = ?", c.Param("supplier_id")).Order("name ASC").Find(&items).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve stock items. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, items)
+	}
+}
+
+// CreateStockItem adds an item a supplier sells, linked to the ingredient it stocks (admin only)
+func CreateStockItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage stock items"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var item models.StockItem
+		if err := c.ShouldBindJSON(&item); err != nil || item.Name == "" || item.IngredientID == "" || item.PurchaseUnit == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stock item data provided. A name, ingredient_id and purchase_unit are required."})
+			return
+		}
+
+		if item.PackSize.IsZero() || item.PackSize.IsNegative() || item.UnitCost.IsNegative() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A stock item needs a positive pack size and a unit cost that is not negative"})
+			return
+		}
+
+		item.StockItemID = ""
+		item.SupplierID = c.Param("supplier_id")
+
+		var supplierExists, ingredientExists int64
+		if err := databases.DB.WithContext(ctx).Model(&models.Supplier{}).Where("supplier_id = ?", item.SupplierID).Count(&supplierExists).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify supplier information. Please try again later."})
+			return
+		}
+
+		if supplierExists == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested supplier could not be found"})
+			return
+		}
+
+		if err := databases.DB.WithContext(ctx).Model(&models.Ingredient{}).Where("ingredient_id = ?", item.IngredientID).Count(&ingredientExists).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify ingredient information. Please try again later."})
+			return
+		}
+
+		if ingredientExists == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The ingredient referenced does not exist"})
+			return
+		}
+
+		if item.Currency == "" {
+			item.Currency = models.DefaultCurrency()
+		}
+		item.UnitCost = item.UnitCost.Round(item.Currency)
+
+		if err := databases.DB.WithContext(ctx).Create(&item).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create stock item. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusCreated, item)
+	}
+}
+
+// UpdateStockItem changes a stock item's name, SKU, purchase unit, pack size or cost (admin only)
+func UpdateStockItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage stock items"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var item models.StockItem
+		if err := databases.DB.WithContext(ctx).Where("stock_item_id = ?", c.Param("stock_item_id")).First(&item).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The stock item you're trying to update could not be found"})
+			return
+		}
+
+		var updateData struct {
+			Name         string           `json:"name"`
+			SupplierSKU  *string          `json:"supplier_sku"`
+			PurchaseUnit string           `json:"purchase_unit"`
+			PackSize     *models.Quantity `json:"pack_size"`
+			UnitCost     *models.Money    `json:"unit_cost"`
+		}
+		if err := c.ShouldBindJSON(&updateData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stock item data provided. Please check your input."})
+			return
+		}
+
+		if updateData.Name != "" {
+			item.Name = updateData.Name
+		}
+		if updateData.SupplierSKU != nil {
+			item.SupplierSKU = *updateData.SupplierSKU
+		}
+		if updateData.PurchaseUnit != "" {
+			item.PurchaseUnit = updateData.PurchaseUnit
+		}
+		if updateData.PackSize != nil {
+			if updateData.PackSize.IsZero() || updateData.PackSize.IsNegative() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The pack size must be positive"})
+				return
+			}
+			item.PackSize = *updateData.PackSize
+		}
+		if updateData.UnitCost != nil {
+			if updateData.UnitCost.IsNegative() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The unit cost cannot be negative"})
+				return
+			}
+			item.UnitCost = updateData.UnitCost.Round(item.Currency)
+		}
+
+		if err := databases.DB.WithContext(ctx).Model(&item).
+			Select("name", "supplier_sku", "purchase_unit", "pack_size", "unit_cost").Updates(&item).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update stock item. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, item)
+	}
+}
+
+// DeleteStockItem removes a stock item that has never been ordered (admin only)
+func DeleteStockItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage stock items"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		stockItemId := c.Param("stock_item_id")
+
+		var lineCount int64
+		if err := databases.DB.WithContext(ctx).Model(&models.PurchaseOrderLine{}).Where("stock_item_id = ?", stockItemId).Count(&lineCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to check if this stock item is used in purchase orders. Please try again later."})
+			return
+		}
+
+		if lineCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This stock item cannot be deleted because it is used in purchase orders"})
+			return
+		}
+
+		result := databases.DB.WithContext(ctx).Where("stock_item_id = ?", stockItemId).Delete(&models.StockItem{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete stock item. Please try again later."})
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The stock item you're trying to delete could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Stock item has been successfully deleted"})
+	}
+}
@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/RestaurantApp/databases"
@@ -84,6 +86,12 @@ func CreateTable() gin.HandlerFunc {
 			return
 		}
 
+		if err := helpers.ValidateTable(&table); err != nil {
+			helpers.RespondWithError(c, err, "Invalid table data provided. Please check your input.")
+			return
+		}
+		table.GroupID = ""
+
 		if err := databases.DB.WithContext(ctx).Create(&table).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create table. Please try again later."})
 			return
@@ -123,6 +131,22 @@ func UpdateTable() gin.HandlerFunc {
 			return
 		}
 
+		// Check the covers the table will have once the update is applied
+		merged := updateData
+		if merged.MinCovers == 0 {
+			merged.MinCovers = table.MinCovers
+		}
+		if merged.MaxCovers == 0 {
+			merged.MaxCovers = table.MaxCovers
+		}
+		if err := helpers.ValidateTable(&merged); err != nil {
+			helpers.RespondWithError(c, err, "Invalid table data provided. Please check your input.")
+			return
+		}
+
+		// Group membership only changes through table groups
+		updateData.GroupID = ""
+
 		if err := databases.DB.WithContext(ctx).Where("table_id = ?", tableId).Updates(&updateData).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update table. Please try again later."})
 			return
@@ -161,6 +185,30 @@ func DeleteTable() gin.HandlerFunc {
 			return
 		}
 
+		var groupedCount int64
+		if err := databases.DB.WithContext(ctx).Model(&models.Table{}).Where("table_id = ? AND group_id <> ''", tableId).Count(&groupedCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to check the table's group. Please try again later."})
+			return
+		}
+
+		if groupedCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This table cannot be deleted while it is part of a table group"})
+			return
+		}
+
+		var reservationCount int64
+		if err := databases.DB.WithContext(ctx).Model(&models.Reservation{}).
+			Where("table_id = ? AND status = ? AND end_time > ?", tableId, models.ReservationStatusBooked, time.Now()).
+			Count(&reservationCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to check the table's reservations. Please try again later."})
+			return
+		}
+
+		if reservationCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This table cannot be deleted because it has upcoming reservations"})
+			return
+		}
+
 		result := databases.DB.WithContext(ctx).Where("table_id = ?", tableId).Delete(&models.Table{})
 		if result.Error != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete table. Please try again later."})
@@ -176,15 +224,25 @@ func DeleteTable() gin.HandlerFunc {
 	}
 }
 
-// GetAvailableTables retrieves tables not currently in use in active orders
+// GetAvailableTables retrieves tables not currently in use in active orders. With ?party_size= only
+// tables that suit the party are returned, smallest first.
 func GetAvailableTables() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		partySize := 0
+		if value := c.Query("party_size"); value != "" {
+			var err error
+			if partySize, err = strconv.Atoi(value); err != nil || partySize <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The party size must be a positive number"})
+				return
+			}
+		}
+
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
 		defer cancel()
 
 		var activeTableIds []string
 		if err := databases.DB.WithContext(ctx).Model(&models.Order{}).
-			Where("order_status NOT IN ?", []string{"completed", "cancelled"}).
+			Where("order_status NOT IN ?", models.TerminalOrderStatuses).
 			Distinct().Pluck("table_id", &activeTableIds).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to determine available tables. Please try again later."})
 			return
@@ -194,7 +252,10 @@ func GetAvailableTables() gin.HandlerFunc {
 		query := databases.DB.WithContext(ctx)
 
 		if len(activeTableIds) > 0 {
-			query = query.Where("table_id NOT IN ?", activeTableIds)
+			// A table grouped with an occupied table is occupied too
+			query = query.Where("table_id NOT IN ?", activeTableIds).
+				Where("group_id = '' OR group_id NOT IN (?)",
+					databases.DB.WithContext(ctx).Model(&models.Table{}).Select("group_id").Where("table_id IN ?", activeTableIds))
 		}
 
 		if err := query.Find(&tables).Error; err != nil {
@@ -202,8 +263,21 @@ func GetAvailableTables() gin.HandlerFunc {
 			return
 		}
 
+		if partySize > 0 {
+			tables = helpers.TablesForParty(tables, partySize)
+		}
+
 		// Check if no tables are available
 		if len(tables) == 0 {
+			if partySize > 0 {
+				response := gin.H{"error": fmt.Sprintf("No available tables for a party of %d at the moment.", partySize)}
+				// Quote how long the party would wait if added to the waitlist now
+				if estimate, err := helpers.EstimateWait(databases.DB.WithContext(ctx), partySize, time.Now()); err == nil {
+					response["estimated_wait_minutes"] = estimate
+				}
+				c.JSON(http.StatusNotFound, response)
+				return
+			}
 			c.JSON(http.StatusNotFound, gin.H{"error": "No available tables at the moment. All tables are currently occupied."})
 			return
 		}
@@ -211,3 +285,48 @@ func GetAvailableTables() gin.HandlerFunc {
 		c.JSON(http.StatusOK, tables)
 	}
 }
+
+// GetFloorPlan returns every table with its floor-plan position and live status, optionally for
+// one section (admin only)
+func GetFloorPlan() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view the floor plan"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		db := databases.DB.WithContext(ctx)
+
+		query := db.Order("section ASC, table_name ASC")
+		if section := c.Query("section"); section != "" {
+			query = query.Where("section = ?", section)
+		}
+
+		var tables []models.Table
+		if err := query.Find(&tables).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve tables. Please try again later."})
+			return
+		}
+
+		plan, err := helpers.BuildFloorPlan(db, tables)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to determine table statuses. Please try again later."})
+			return
+		}
+
+		sections := []string{}
+		for _, table := range tables {
+			if len(sections) == 0 || sections[len(sections)-1] != table.Section {
+				sections = append(sections, table.Section)
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"sections": sections,
+			"tables":   plan,
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetTableGroups lists the tables currently pushed together, with their member tables (admin only)
+func GetTableGroups() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view table groups"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		db := databases.DB.WithContext(ctx)
+
+		var groups []models.TableGroup
+		if err := db.Order("id ASC").Find(&groups).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve table groups. Please try again later."})
+			return
+		}
+
+		for i := range groups {
+			if err := db.Where("group_id = ?", groups[i].TableGroupID).Order("table_name ASC").Find(&groups[i].Tables).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve table groups. Please try again later."})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, groups)
+	}
+}
+
+// CreateTableGroup joins several tables into a temporary group that one order can occupy (admin only)
+func CreateTableGroup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to group tables"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			Name     string   `json:"name"`
+			TableIDs []string `json:"table_ids" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid table group data. The tables to group are required."})
+			return
+		}
+
+		group := models.TableGroup{
+			Name:      request.Name,
+			CreatedBy: c.GetString("uid"),
+		}
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return helpers.CreateTableGroup(tx, &group, request.TableIDs)
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to group tables. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusCreated, group)
+	}
+}
+
+// ReleaseTableGroup splits a table group back into separate tables (admin only)
+func ReleaseTableGroup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to release table groups"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var notifications []helpers.Notification
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			tableIds, err := helpers.ReleaseTableGroup(tx, c.Param("table_group_id"))
+			if err != nil {
+				return err
+			}
+
+			// Tables the group's party was not sitting at are free for the waitlist again
+			notifications, err = helpers.OfferFreedTables(tx, tableIds)
+			return err
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The table group you're trying to release could not be found"})
+			return
+		}
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to release table group. Please try again later.")
+			return
+		}
+
+		helpers.SendNotifications(notifications)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Table group has been successfully released"})
+	}
+}
@@ -0,0 +1,304 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxTaxRateBasisPoints caps a tax rate at 100%
+const maxTaxRateBasisPoints = 10000
+
+// GetTaxCategories retrieves all tax categories with their rates and the tax settings (admin only)
+func GetTaxCategories() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view tax categories"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var categories []models.TaxCategory
+		if err := databases.DB.WithContext(ctx).
+			Preload("Rates", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+			Order("name ASC").
+			Find(&categories).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve tax categories. Please try again later."})
+			return
+		}
+
+		pricing := helpers.TaxPricingExclusive
+		includeTax, rounding := helpers.TaxSettings()
+		if includeTax {
+			pricing = helpers.TaxPricingInclusive
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":     categories,
+			"pricing":  pricing,
+			"rounding": rounding,
+		})
+	}
+}
+
+// CreateTaxCategory adds a new tax category such as food or alcohol (admin only)
+func CreateTaxCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to create tax categories"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var category models.TaxCategory
+		if err := c.ShouldBindJSON(&category); err != nil || category.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tax category data provided. A name is required."})
+			return
+		}
+
+		var nameTaken int64
+		if err := databases.DB.WithContext(ctx).Model(&models.TaxCategory{}).Where("name = ?", category.Name).Count(&nameTaken).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify tax category information. Please try again later."})
+			return
+		}
+
+		if nameTaken > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "A tax category with this name already exists"})
+			return
+		}
+
+		// Rates are added through their own endpoint
+		if err := databases.DB.WithContext(ctx).Omit("Rates").Create(&category).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create tax category. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusCreated, category)
+	}
+}
+
+// UpdateTaxCategory renames or describes an existing tax category (admin only)
+func UpdateTaxCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update tax categories"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		categoryId := c.Param("tax_category_id")
+		var category models.TaxCategory
+
+		if err := databases.DB.WithContext(ctx).Where("tax_category_id = ?", categoryId).First(&category).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The tax category you're trying to update could not be found"})
+			return
+		}
+
+		var updateData models.TaxCategory
+		if err := c.ShouldBindJSON(&updateData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tax category data provided. Please check your input."})
+			return
+		}
+		updateData.TaxCategoryID = ""
+
+		if updateData.Name != "" && updateData.Name != category.Name {
+			var nameTaken int64
+			if err := databases.DB.WithContext(ctx).Model(&models.TaxCategory{}).Where("name = ?", updateData.Name).Count(&nameTaken).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify tax category information. Please try again later."})
+				return
+			}
+
+			if nameTaken > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "A tax category with this name already exists"})
+				return
+			}
+		}
+
+		if err := databases.DB.WithContext(ctx).Model(&category).Omit("Rates").Updates(&updateData).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update tax category. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, category)
+	}
+}
+
+// DeleteTaxCategory removes a tax category and its rates. Categories still assigned to foods cannot
+// be deleted (admin only).
+func DeleteTaxCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete tax categories"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		categoryId := c.Param("tax_category_id")
+
+		var foodCount int64
+		if err := databases.DB.WithContext(ctx).Model(&models.Food{}).Where("tax_category_id = ?", categoryId).Count(&foodCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to check the foods in this tax category. Please try again later."})
+			return
+		}
+
+		if foodCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This tax category cannot be deleted while foods are assigned to it"})
+			return
+		}
+
+		var result *gorm.DB
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("tax_category_id = ?", categoryId).Delete(&models.TaxRate{}).Error; err != nil {
+				return err
+			}
+			result = tx.Where("tax_category_id = ?", categoryId).Delete(&models.TaxCategory{})
+			return result.Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete tax category. Please try again later."})
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The tax category you're trying to delete could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Tax category has been successfully deleted"})
+	}
+}
+
+// CreateTaxRate adds a tax rate to a tax category, in basis points of the net price (admin only)
+func CreateTaxRate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to create tax rates"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var rate models.TaxRate
+		if err := c.ShouldBindJSON(&rate); err != nil || rate.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tax rate data provided. A name and rate_basis_points are required."})
+			return
+		}
+
+		if rate.RateBasisPoints < 0 || rate.RateBasisPoints > maxTaxRateBasisPoints {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A tax rate must be between 0 and 10000 basis points"})
+			return
+		}
+
+		rate.TaxCategoryID = c.Param("tax_category_id")
+
+		var categoryExists int64
+		if err := databases.DB.WithContext(ctx).Model(&models.TaxCategory{}).Where("tax_category_id = ?", rate.TaxCategoryID).Count(&categoryExists).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify tax category information. Please try again later."})
+			return
+		}
+
+		if categoryExists == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested tax category could not be found"})
+			return
+		}
+
+		if err := databases.DB.WithContext(ctx).Create(&rate).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to create tax rate. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusCreated, rate)
+	}
+}
+
+// UpdateTaxRate renames a tax rate or changes its rate. Invoices already issued keep the rate they
+// were taxed at (admin only).
+func UpdateTaxRate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update tax rates"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var rate models.TaxRate
+		if err := databases.DB.WithContext(ctx).
+			Where("tax_category_id = ? AND tax_rate_id = ?", c.Param("tax_category_id"), c.Param("tax_rate_id")).
+			First(&rate).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The tax rate you're trying to update could not be found"})
+			return
+		}
+
+		var request struct {
+			Name            string `json:"name"`
+			RateBasisPoints *int64 `json:"rate_basis_points"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tax rate data provided. Please check your input."})
+			return
+		}
+
+		if request.Name != "" {
+			rate.Name = request.Name
+		}
+		if request.RateBasisPoints != nil {
+			if *request.RateBasisPoints < 0 || *request.RateBasisPoints > maxTaxRateBasisPoints {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "A tax rate must be between 0 and 10000 basis points"})
+				return
+			}
+			rate.RateBasisPoints = *request.RateBasisPoints
+		}
+
+		if err := databases.DB.WithContext(ctx).Model(&rate).Select("name", "rate_basis_points").Updates(&rate).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to update tax rate. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, rate)
+	}
+}
+
+// DeleteTaxRate removes a tax rate from its category; invoices already issued keep it (admin only)
+func DeleteTaxRate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete tax rates"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result := databases.DB.WithContext(ctx).
+			Where("tax_category_id = ? AND tax_rate_id = ?", c.Param("tax_category_id"), c.Param("tax_rate_id")).
+			Delete(&models.TaxRate{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete tax rate. Please try again later."})
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The tax rate you're trying to delete could not be found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Tax rate has been successfully deleted"})
+	}
+}
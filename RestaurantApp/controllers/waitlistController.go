@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetWaitlist lists the parties waiting for a table in queue order with their current estimated
+// wait, or the entries in one status with ?status= (admin only)
+func GetWaitlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view the waitlist"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		db := databases.DB.WithContext(ctx)
+
+		query := db.Order("created_at ASC, id ASC")
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		} else {
+			query = query.Where("status IN ?", helpers.ActiveWaitlistStatuses)
+		}
+
+		var entries []models.WaitlistEntry
+		if err := query.Find(&entries).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve the waitlist. Please try again later."})
+			return
+		}
+
+		for i := range entries {
+			entry := &entries[i]
+			if entry.Status != models.WaitlistStatusWaiting {
+				continue
+			}
+			estimate, err := helpers.EstimateWait(db, entry.PartySize, entry.CreatedAt)
+			if err != nil {
+				// A party no table can seat any more keeps its quoted wait
+				estimate = entry.QuotedWaitMinutes
+			}
+			entry.EstimatedWaitMinutes = estimate
+		}
+
+		c.JSON(http.StatusOK, entries)
+	}
+}
+
+// GetWaitEstimate estimates how long a party of ?party_size= would wait if added to the waitlist now (admin only)
+func GetWaitEstimate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view the waitlist"})
+			return
+		}
+
+		partySize, err := strconv.Atoi(c.Query("party_size"))
+		if err != nil || partySize <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A party_size of at least one is required"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		estimate, err := helpers.EstimateWait(databases.DB.WithContext(ctx), partySize, time.Now())
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to estimate the wait. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"party_size":             partySize,
+			"estimated_wait_minutes": estimate,
+		})
+	}
+}
+
+// AddToWaitlist adds a walk-in party to the back of the waitlist and quotes them a wait (admin only)
+func AddToWaitlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage the waitlist"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var entry models.WaitlistEntry
+		if err := c.ShouldBindJSON(&entry); err != nil || entry.PartyName == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid waitlist data provided. A party name and size are required."})
+			return
+		}
+
+		entry.ID = 0
+		entry.WaitlistID = ""
+		entry.AddedBy = c.GetString("uid")
+
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return helpers.AddToWaitlist(tx, &entry)
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to add the party to the waitlist. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusCreated, entry)
+	}
+}
+
+// NotifyWaitlistEntry tells a waiting party that a table is ready for them (admin only)
+func NotifyWaitlistEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage the waitlist"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			TableID string `json:"table_id" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The table offered to the party is required"})
+			return
+		}
+
+		var entry models.WaitlistEntry
+		var notifications []helpers.Notification
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if entry, err = helpers.LockWaitlistEntry(tx, c.Param("waitlist_id")); err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The waitlist entry could not be found")
+			}
+
+			var table models.Table
+			if err := tx.Where("table_id = ?", request.TableID).First(&table).Error; err != nil {
+				return helpers.NewRequestError(http.StatusBadRequest, "The table referenced does not exist")
+			}
+
+			notifications, err = helpers.NotifyWaitlistEntry(tx, &entry, &table)
+			return err
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to notify the party. Please try again later.")
+			return
+		}
+
+		helpers.SendNotifications(notifications)
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// SeatWaitlistEntry records that a waiting party has been seated, and how long they waited (admin only)
+func SeatWaitlistEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage the waitlist"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request struct {
+			TableID string `json:"table_id"`
+			OrderID string `json:"order_id"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid seating data provided. Please check your input."})
+			return
+		}
+
+		var entry models.WaitlistEntry
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if entry, err = helpers.LockWaitlistEntry(tx, c.Param("waitlist_id")); err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The waitlist entry could not be found")
+			}
+
+			return helpers.SeatWaitlistEntry(tx, &entry, request.TableID, request.OrderID)
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to seat the party. Please try again later.")
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// CancelWaitlistEntry takes a party that left off the waitlist (admin only)
+func CancelWaitlistEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to manage the waitlist"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var entry models.WaitlistEntry
+		var notifications []helpers.Notification
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if entry, err = helpers.LockWaitlistEntry(tx, c.Param("waitlist_id")); err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The waitlist entry could not be found")
+			}
+
+			notifications, err = helpers.CancelWaitlistEntry(tx, &entry)
+			return err
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to cancel the waitlist entry. Please try again later.")
+			return
+		}
+
+		helpers.SendNotifications(notifications)
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetWasteEntries lists waste entries, newest first, optionally filtered by reason, food, stock item and date range (admin only)
+func GetWasteEntries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view waste entries"})
+			return
+		}
+
+		period, err := helpers.GetReportPeriod(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		pagination := helpers.GetPaginationParams(c)
+		offset := helpers.GetOffset(pagination.Page, pagination.Limit)
+
+		query := databases.DB.WithContext(ctx).Model(&models.WasteEntry{}).
+			Where("wasted_at >= ? AND wasted_at < ?", period.From, period.To)
+		if reason := c.Query("reason"); reason != "" {
+			query = query.Where("reason = ?", reason)
+		}
+		if foodId := c.Query("food_id"); foodId != "" {
+			query = query.Where("food_id = ?", foodId)
+		}
+		if stockItemId := c.Query("stock_item_id"); stockItemId != "" {
+			query = query.Where("stock_item_id = ?", stockItemId)
+		}
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to count waste entries"})
+			return
+		}
+
+		var entries []models.WasteEntry
+		if err := query.Order("wasted_at DESC, id DESC").Offset(offset).Limit(pagination.Limit).Find(&entries).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to retrieve waste entries. Please try again later."})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"period":     period.Response(),
+			"data":       entries,
+			"pagination": helpers.CreatePaginationResponse(pagination.Page, pagination.Limit, total),
+		})
+	}
+}
+
+// CreateWasteEntry records wasted food or stock with a reason code, valued at its current cost (admin only)
+func CreateWasteEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to record waste"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var entry models.WasteEntry
+		if err := c.ShouldBindJSON(&entry); err != nil || entry.Reason == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid waste data provided. A reason and quantity are required."})
+			return
+		}
+
+		now := time.Now()
+		if entry.WastedAt.IsZero() {
+			entry.WastedAt = now
+		}
+		if entry.WastedAt.After(now) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Waste cannot be recorded in the future"})
+			return
+		}
+
+		entry.ID = 0
+		entry.WasteID = ""
+		entry.RecordedBy = c.GetString("uid")
+
+		var availabilityChanges []helpers.AvailabilityChange
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			availabilityChanges, err = helpers.RecordWaste(tx, &entry)
+			return err
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to record waste. Please try again later.")
+			return
+		}
+
+		helpers.PublishAvailabilityChanges(availabilityChanges)
+
+		c.JSON(http.StatusCreated, entry)
+	}
+}
+
+// DeleteWasteEntry removes a waste entry recorded in error and puts its stock back (admin only)
+func DeleteWasteEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete waste entries"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var availabilityChanges []helpers.AvailabilityChange
+		err := databases.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var entry models.WasteEntry
+			if err := tx.Where("waste_id = ?", c.Param("waste_id")).First(&entry).Error; err != nil {
+				return helpers.NewRequestError(http.StatusNotFound, "The waste entry you're trying to delete could not be found")
+			}
+
+			var err error
+			availabilityChanges, err = helpers.DeleteWaste(tx, &entry, c.GetString("uid"))
+			return err
+		})
+		if err != nil {
+			helpers.RespondWithError(c, err, "Unable to delete waste entry. Please try again later.")
+			return
+		}
+
+		helpers.PublishAvailabilityChanges(availabilityChanges)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Waste entry has been successfully deleted"})
+	}
+}
@@ -0,0 +1,163 @@
+package databases
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+)
+
+// BackfillOrderItemSnapshots fills the food name, unit price and line total of order items
+// created before those values were captured at order time, using the current food records
+func BackfillOrderItemSnapshots(db *gorm.DB) error {
+	return db.Exec(`
+		UPDATE order_items
+		SET food_name = foods.name,
+			unit_price = foods.price,
+			line_total = order_items.quantity * foods.price
+		FROM foods
+		WHERE foods.food_id = order_items.food_id
+			AND order_items.unit_price IS NULL`).Error
+}
+
+// moneyColumns lists every column holding a Money amount
+var moneyColumns = []struct {
+	Table  string
+	Column string
+}{
+	{"foods", "price"},
+	{"orders", "order_total"},
+	{"order_items", "unit_price"},
+	{"order_items", "line_total"},
+	{"invoices", "total_amount"},
+}
+
+// ConvertMoneyColumns converts money columns created as floating point or unscaled decimals
+// into numeric(19,4). Values are cast through numeric before rounding so no digits are lost.
+func ConvertMoneyColumns(db *gorm.DB) error {
+	for _, money := range moneyColumns {
+		if !db.Migrator().HasTable(money.Table) || !db.Migrator().HasColumn(money.Table, money.Column) {
+			continue
+		}
+
+		var converted int64
+		if err := db.Raw(`
+			SELECT COUNT(*) FROM information_schema.columns
+			WHERE table_schema = CURRENT_SCHEMA()
+				AND table_name = ? AND column_name = ?
+				AND data_type = 'numeric' AND numeric_precision = 19 AND numeric_scale = 4`,
+			money.Table, money.Column).Scan(&converted).Error; err != nil {
+			return err
+		}
+		if converted > 0 {
+			continue
+		}
+
+		if err := db.Exec(fmt.Sprintf(
+			`ALTER TABLE %q ALTER COLUMN %q TYPE numeric(19,4) USING ROUND(%q::numeric, 4)`,
+			money.Table, money.Column, money.Column)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackfillCurrencies assigns the default currency to rows created before amounts carried one
+func BackfillCurrencies(db *gorm.DB, currency string) error {
+	for _, table := range []string{"foods", "orders", "invoices"} {
+		if err := db.Exec(fmt.Sprintf(`UPDATE %q SET currency = ? WHERE currency IS NULL OR currency = ''`, table), currency).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackfillInvoicePayments records invoices marked paid before payments were tracked as a single
+// payment of their total, then fills in the amount paid and balance of invoices that predate them.
+// The free-text status and method of those invoices are normalised first: statuses are lower
+// cased, with any status that is not one of the invoice statuses recorded as pending, and methods
+// such as "Bank Transfer" become "bank_transfer", with any method that is not one of the payment
+// methods recorded as "other".
+func BackfillInvoicePayments(db *gorm.DB) error {
+	if err := db.Exec(`
+		UPDATE invoices
+		SET payment_status = normalised.status,
+			payment_method = CASE
+				WHEN normalised.method IN ? THEN normalised.method
+				WHEN normalised.method = '' AND normalised.status <> ? THEN ''
+				ELSE ?
+			END
+		FROM (
+			SELECT invoice_id,
+				LOWER(TRIM(COALESCE(payment_status, ''))) AS status,
+				REGEXP_REPLACE(LOWER(TRIM(COALESCE(payment_method, ''))), '[\s-]+', '_', 'g') AS method
+			FROM invoices
+			WHERE balance_due IS NULL
+		) AS normalised
+		WHERE normalised.invoice_id = invoices.invoice_id`,
+		models.PaymentMethods, models.InvoiceStatusPaid, models.PaymentMethodOther).Error; err != nil {
+		return err
+	}
+
+	// Statuses such as "partially paid" or "settled" are not backed by any recorded payment, so
+	// those invoices start out unpaid
+	result := db.Exec(`
+		UPDATE invoices
+		SET payment_status = ?
+		WHERE balance_due IS NULL AND payment_status NOT IN ?`,
+		models.InvoiceStatusPending, models.InvoiceStatuses)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Recorded %d invoices with an unrecognised payment status as %s", result.RowsAffected, models.InvoiceStatusPending)
+	}
+
+	if err := db.Exec(`
+		INSERT INTO payments (payment_id, invoice_id, amount, tendered, "change", currency, method, reference,
+			status, paid_at, received_by, voided_by, void_reason, created_at, updated_at)
+		SELECT 'legacy-' || invoices.invoice_id, invoices.invoice_id, invoices.total_amount, invoices.total_amount, 0,
+			invoices.currency, invoices.payment_method, 'legacy', 'completed',
+			invoices.updated_at, '', '', '', NOW(), NOW()
+		FROM invoices
+		WHERE invoices.payment_status = 'paid'
+			AND invoices.balance_due IS NULL
+			AND NOT EXISTS (SELECT 1 FROM payments WHERE payments.invoice_id = invoices.invoice_id)`).Error; err != nil {
+		return err
+	}
+
+	return db.Exec(`
+		UPDATE invoices
+		SET amount_paid = paid.amount,
+			balance_due = invoices.total_amount - paid.amount
+		FROM (
+			SELECT invoices.invoice_id, COALESCE(SUM(payments.amount), 0) AS amount
+			FROM invoices
+			LEFT JOIN payments ON payments.invoice_id = invoices.invoice_id AND payments.status = 'completed'
+			WHERE invoices.balance_due IS NULL
+			GROUP BY invoices.invoice_id
+		) AS paid
+		WHERE paid.invoice_id = invoices.invoice_id`).Error
+}
+
+// BackfillInvoiceSubtotals gives invoices issued before tax was charged a subtotal equal to their
+// total and no tax
+func BackfillInvoiceSubtotals(db *gorm.DB) error {
+	return db.Exec(`
+		UPDATE invoices
+		SET subtotal = total_amount,
+			tax_amount = 0
+		WHERE subtotal IS NULL`).Error
+}
+
+// BackfillKitchenEventPositions gives kitchen events recorded before the feed was ordered by commit
+// their sequence number as their position, so screens can keep resuming from the last id they saw.
+// It only runs while no event has a position yet.
+func BackfillKitchenEventPositions(db *gorm.DB) error {
+	return db.Exec(`
+		UPDATE kitchen_events
+		SET position = sequence
+		WHERE position IS NULL
+			AND NOT EXISTS (SELECT 1 FROM kitchen_events WHERE position IS NOT NULL)`).Error
+}
@@ -0,0 +1,120 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+)
+
+// Availability feed event types
+const (
+	AvailabilityEventSnapshot = "availability_snapshot"
+	AvailabilityEventChanged  = "availability_changed"
+)
+
+// Kinds of items that can be marked unavailable
+const (
+	AvailabilityKindFood     = "food"
+	AvailabilityKindModifier = "modifier"
+)
+
+// availabilitySequence numbers availability events so clients can tell them apart; the feed
+// is not replayed, clients receive a fresh snapshot when they reconnect
+var availabilitySequence atomic.Uint64
+
+// AvailabilityChange describes a food or modifier being 86'd or made available again
+type AvailabilityChange struct {
+	Kind              string     `json:"kind"`
+	ID                string     `json:"id"`
+	Name              string     `json:"name"`
+	Available         bool       `json:"available"`
+	UnavailableUntil  *time.Time `json:"unavailable_until"`
+	UnavailableReason string     `json:"unavailable_reason"`
+	StockedOut        bool       `json:"stocked_out"`
+}
+
+// FoodAvailabilityChange describes the current availability of a food
+func FoodAvailabilityChange(food *models.Food) AvailabilityChange {
+	return AvailabilityChange{
+		Kind:              AvailabilityKindFood,
+		ID:                food.FoodID,
+		Name:              food.Name,
+		Available:         food.AvailableAt(time.Now()),
+		UnavailableUntil:  food.UnavailableUntil,
+		UnavailableReason: food.UnavailableReason,
+		StockedOut:        food.StockedOut,
+	}
+}
+
+// ModifierAvailabilityChange describes the current availability of a modifier
+func ModifierAvailabilityChange(modifier *models.Modifier) AvailabilityChange {
+	return AvailabilityChange{
+		Kind:              AvailabilityKindModifier,
+		ID:                modifier.ModifierID,
+		Name:              modifier.Name,
+		Available:         modifier.AvailableAt(time.Now()),
+		UnavailableUntil:  modifier.UnavailableUntil,
+		UnavailableReason: modifier.UnavailableReason,
+	}
+}
+
+// EndOfService returns when the current service ends: the next midnight in the restaurant's timezone
+func EndOfService(now time.Time) time.Time {
+	local := now.In(RestaurantLocation())
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location()).AddDate(0, 0, 1)
+}
+
+// UnavailableError rejects ordering an item that has been 86'd
+func UnavailableError(name string, until *time.Time) *RequestError {
+	if until == nil {
+		return NewRequestError(http.StatusBadRequest, fmt.Sprintf("%q is currently unavailable", name))
+	}
+	return NewRequestError(http.StatusBadRequest, fmt.Sprintf("%q is unavailable until %s", name, until.In(RestaurantLocation()).Format("Jan 2 15:04")))
+}
+
+// FoodUnavailableError rejects ordering a food that has been 86'd or has run out of ingredients
+func FoodUnavailableError(food *models.Food) *RequestError {
+	if food.StockedOut {
+		return NewRequestError(http.StatusBadRequest, fmt.Sprintf("%q is out of stock", food.Name))
+	}
+	return UnavailableError(food.Name, food.UnavailableUntil)
+}
+
+// NewAvailabilityEvent wraps data as an availability feed event
+func NewAvailabilityEvent(eventType string, data interface{}) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Sequence: availabilitySequence.Add(1), Type: eventType, Data: payload}, nil
+}
+
+// PublishAvailabilityChange broadcasts an availability change to connected clients. It must only be
+// called once the change has been committed.
+func PublishAvailabilityChange(change AvailabilityChange) {
+	event, err := NewAvailabilityEvent(AvailabilityEventChanged, change)
+	if err != nil {
+		return
+	}
+	AvailabilityBroker.Publish(event)
+}
+
+// PublishAvailabilityChanges broadcasts committed availability changes to connected clients
+func PublishAvailabilityChanges(changes []AvailabilityChange) {
+	for _, change := range changes {
+		PublishAvailabilityChange(change)
+	}
+}
+
+// FilterAvailableFoods limits a foods query to foods that can, or cannot, be ordered right now
+func FilterAvailableFoods(query *gorm.DB, available bool) *gorm.DB {
+	if available {
+		return query.Where("NOT foods.stocked_out AND (foods.unavailable_until IS NULL OR foods.unavailable_until <= ?)", time.Now())
+	}
+	return query.Where("foods.stocked_out OR foods.unavailable_until > ?", time.Now())
+}
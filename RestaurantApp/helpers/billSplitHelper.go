@@ -0,0 +1,307 @@
+package helpers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+)
+
+// Bill split strategies
+const (
+	BillSplitEven  = "even"
+	BillSplitItems = "items"
+	BillSplitSeats = "seats"
+)
+
+// maxBillSplitWays caps how many invoices one order can be split into
+const maxBillSplitWays = 50
+
+// BillSplitRequest describes how to split an order's bill: into Ways equal parts, into one part per
+// group of order item ids, or into one part per seat
+type BillSplitRequest struct {
+	Strategy       string     `json:"strategy" binding:"required"`
+	Ways           int        `json:"ways"`
+	Groups         [][]string `json:"groups"`
+	PaymentMethod  string     `json:"payment_method"`
+	PaymentDueDate time.Time  `json:"payment_due_date"`
+}
+
+// SplitInvoice is one of the invoices a bill was split into, with the order items it covers
+type SplitInvoice struct {
+	Invoice      models.Invoice `json:"invoice"`
+	OrderItemIDs []string       `json:"order_item_ids"`
+}
+
+// billShare is one part of a split bill before it is priced
+type billShare struct {
+	label string
+	items []shareItem
+}
+
+// shareItem is an order item covered by a bill share, shared equally between ways shares
+type shareItem struct {
+	item models.OrderItem
+	ways int64
+}
+
+// portion adds up the share's part of an amount taken from each of its order items
+func (share billShare) portion(amount func(models.OrderItem) models.Money) int64 {
+	total := models.Money(0)
+	for _, part := range share.items {
+		total = total.Add(amount(part.item).MulRatio(1, part.ways, models.RoundHalfEven))
+	}
+	return int64(total)
+}
+
+// orderItemIds lists the ids of the order items the share covers
+func (share billShare) orderItemIds() []string {
+	ids := make([]string, 0, len(share.items))
+	for _, part := range share.items {
+		ids = append(ids, part.item.OrderItemID)
+	}
+	return ids
+}
+
+// SplitOrderBill invoices a served order as several invoices. The order is taxed as a whole and
+// its subtotal, or total when prices include tax, and the tax of each rate are then allocated
+// across the parts in whole minor units, in proportion to what each part covers, so the invoices
+// always add up to exactly what a single invoice would have been; any rounding remainder goes to
+// the parts with the largest fractions, the last cent included.
+func SplitOrderBill(tx *gorm.DB, order *models.Order, request BillSplitRequest, actorUid string) ([]SplitInvoice, error) {
+	if !CanTransitionOrder(order.OrderStatus, models.OrderStatusInvoiced) {
+		return nil, NewRequestError(http.StatusConflict, "Only orders that have been served can be invoiced")
+	}
+
+	var invoiceCount int64
+	if err := tx.Model(&models.Invoice{}).Where("order_id = ?", order.OrderID).Count(&invoiceCount).Error; err != nil {
+		return nil, err
+	}
+	if invoiceCount > 0 {
+		return nil, NewRequestError(http.StatusConflict, "This order has already been invoiced")
+	}
+
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", order.OrderID).Order("id ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	var shares []billShare
+	var err error
+	switch request.Strategy {
+	case BillSplitEven:
+		shares, err = evenShares(items, request.Ways)
+	case BillSplitItems:
+		shares, err = itemShares(items, request.Groups)
+	case BillSplitSeats:
+		shares, err = seatShares(items)
+	default:
+		return nil, NewRequestError(http.StatusBadRequest, "The split strategy must be even, items or seats")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown, err := ComputeOrderTax(tx, order.Currency, items)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := priceBillShares(breakdown, shares, order.Currency)
+
+	dueDate := request.PaymentDueDate
+	if dueDate.IsZero() {
+		dueDate = time.Now().AddDate(0, 0, 7)
+	}
+
+	splits := make([]SplitInvoice, 0, len(shares))
+	for i, share := range shares {
+		invoice := models.Invoice{
+			OrderID:          order.OrderID,
+			PaymentStatus:    models.InvoiceStatusPending,
+			PaymentMethod:    request.PaymentMethod,
+			PaymentDueDate:   dueDate,
+			Subtotal:         parts[i].subtotal,
+			TaxAmount:        parts[i].tax,
+			TotalAmount:      parts[i].total,
+			BalanceDue:       parts[i].total,
+			Currency:         order.Currency,
+			SplitLabel:       share.label,
+			PricesIncludeTax: breakdown.PricesIncludeTax,
+			TaxRounding:      breakdown.Rounding,
+			TaxLines:         parts[i].taxLines,
+		}
+		if err := tx.Create(&invoice).Error; err != nil {
+			return nil, err
+		}
+		splits = append(splits, SplitInvoice{Invoice: invoice, OrderItemIDs: share.orderItemIds()})
+	}
+
+	reason := fmt.Sprintf("bill split %s into %d invoices", request.Strategy, len(splits))
+	if err := TransitionOrder(tx, order, models.OrderStatusInvoiced, actorUid, reason); err != nil {
+		return nil, err
+	}
+	return splits, nil
+}
+
+// billPart is the priced part of a split bill for one share
+type billPart struct {
+	subtotal models.Money
+	tax      models.Money
+	total    models.Money
+	taxLines []models.InvoiceTaxLine
+}
+
+// priceBillShares allocates an order's subtotal, or total when prices include tax, and the tax of
+// each rate across the shares of a split bill, so the parts add up exactly to the breakdown
+func priceBillShares(breakdown TaxBreakdown, shares []billShare, currency string) []billPart {
+	weights := make([]int64, len(shares))
+	for i, share := range shares {
+		weights[i] = share.portion(func(item models.OrderItem) models.Money { return item.LineTotal })
+	}
+	var amounts []models.Money
+	if breakdown.PricesIncludeTax {
+		amounts = breakdown.Total.Allocate(weights, currency)
+	} else {
+		amounts = breakdown.Subtotal.Allocate(weights, currency)
+	}
+
+	// Each share's part of every rate is allocated by what the share's items contribute to it
+	parts := make([]billPart, len(shares))
+	for _, line := range breakdown.Lines {
+		rateId := line.TaxRateID
+		taxableWeights := make([]int64, len(shares))
+		taxWeights := make([]int64, len(shares))
+		for i, share := range shares {
+			taxableWeights[i] = share.portion(func(item models.OrderItem) models.Money { return breakdown.items[item.OrderItemID][rateId].taxable })
+			taxWeights[i] = share.portion(func(item models.OrderItem) models.Money { return breakdown.items[item.OrderItemID][rateId].tax })
+		}
+		taxableParts := line.TaxableAmount.Allocate(taxableWeights, currency)
+		taxParts := line.TaxAmount.Allocate(taxWeights, currency)
+		for i := range shares {
+			if taxableParts[i].IsZero() && taxParts[i].IsZero() {
+				continue
+			}
+			part := line
+			part.TaxableAmount, part.TaxAmount = taxableParts[i], taxParts[i]
+			parts[i].taxLines = append(parts[i].taxLines, part)
+			parts[i].tax = parts[i].tax.Add(part.TaxAmount)
+		}
+	}
+
+	for i := range parts {
+		parts[i].subtotal, parts[i].total = amounts[i], amounts[i].Add(parts[i].tax)
+		if breakdown.PricesIncludeTax {
+			parts[i].subtotal, parts[i].total = amounts[i].Sub(parts[i].tax), amounts[i]
+		}
+	}
+	return parts
+}
+
+// evenShares splits a bill into equal parts that each cover the whole order
+func evenShares(items []models.OrderItem, ways int) ([]billShare, error) {
+	if ways < 2 || ways > maxBillSplitWays {
+		return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("An even split must be between 2 and %d ways", maxBillSplitWays))
+	}
+
+	parts := make([]shareItem, 0, len(items))
+	for _, item := range items {
+		parts = append(parts, shareItem{item: item, ways: int64(ways)})
+	}
+	shares := make([]billShare, ways)
+	for i := range shares {
+		shares[i] = billShare{label: fmt.Sprintf("%d of %d", i+1, ways), items: parts}
+	}
+	return shares, nil
+}
+
+// itemShares splits a bill into one part per group of order items. Every item of the order must be
+// in exactly one group, and the components of a bundle must stay together.
+func itemShares(items []models.OrderItem, groups [][]string) ([]billShare, error) {
+	if len(groups) < 2 || len(groups) > maxBillSplitWays {
+		return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("A split by items needs between 2 and %d groups of order items", maxBillSplitWays))
+	}
+
+	byId := make(map[string]models.OrderItem, len(items))
+	for _, item := range items {
+		byId[item.OrderItemID] = item
+	}
+
+	assigned := make(map[string]int)
+	bundleGroups := make(map[string]int)
+	shares := make([]billShare, 0, len(groups))
+	for i, group := range groups {
+		if len(group) == 0 {
+			return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Group %d has no order items", i+1))
+		}
+
+		share := billShare{label: fmt.Sprintf("%d of %d", i+1, len(groups))}
+		for _, id := range group {
+			item, ok := byId[id]
+			if !ok {
+				return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Order item %q is not part of this order", id))
+			}
+			if _, ok := assigned[id]; ok {
+				return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Order item %q is in more than one group", id))
+			}
+			assigned[id] = i
+
+			if item.BundleLineID != "" {
+				if other, ok := bundleGroups[item.BundleLineID]; ok && other != i {
+					return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("The items of bundle %q must be in the same group", item.BundleLineID))
+				}
+				bundleGroups[item.BundleLineID] = i
+			}
+			share.items = append(share.items, shareItem{item: item, ways: 1})
+		}
+		shares = append(shares, share)
+	}
+
+	for _, item := range items {
+		if _, ok := assigned[item.OrderItemID]; !ok {
+			return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Order item %q (%s) is not in any group", item.OrderItemID, item.FoodName))
+		}
+	}
+	return shares, nil
+}
+
+// seatShares splits a bill into one part per seat. Items not assigned to a seat are shared
+// equally between all the seats.
+func seatShares(items []models.OrderItem) ([]billShare, error) {
+	bySeat := make(map[int]*billShare)
+	var seats []int
+	var shared []models.OrderItem
+	for _, item := range items {
+		if item.Seat == 0 {
+			shared = append(shared, item)
+			continue
+		}
+		share, ok := bySeat[item.Seat]
+		if !ok {
+			share = &billShare{label: fmt.Sprintf("Seat %d", item.Seat)}
+			bySeat[item.Seat] = share
+			seats = append(seats, item.Seat)
+		}
+		share.items = append(share.items, shareItem{item: item, ways: 1})
+	}
+	if len(seats) < 2 {
+		return nil, NewRequestError(http.StatusBadRequest, "A split by seat needs items assigned to at least two seats")
+	}
+	if len(seats) > maxBillSplitWays {
+		return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("A bill cannot be split more than %d ways", maxBillSplitWays))
+	}
+	sort.Ints(seats)
+
+	shares := make([]billShare, 0, len(seats))
+	for _, seat := range seats {
+		share := bySeat[seat]
+		for _, item := range shared {
+			share.items = append(share.items, shareItem{item: item, ways: int64(len(seats))})
+		}
+		shares = append(shares, *share)
+	}
+	return shares, nil
+}
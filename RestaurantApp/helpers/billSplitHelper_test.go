@@ -0,0 +1,141 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/RestaurantApp/models"
+)
+
+// testSplitItems is a burger and a beer for seat 1, a salad for seat 2, a beer for seat 3 and a
+// shared water, priced at the given line totals
+func testSplitItems(burger, salad, beer, water models.Money) []models.OrderItem {
+	return []models.OrderItem{
+		testOrderItem("a", "burger", burger, 1),
+		testOrderItem("b", "salad", salad, 2),
+		testOrderItem("c", "beer", beer, 1),
+		testOrderItem("d", "beer", beer, 3),
+		testOrderItem("e", "water", water, 0),
+	}
+}
+
+func TestPriceBillShares(t *testing.T) {
+	tests := []struct {
+		name       string
+		currency   string
+		items      []models.OrderItem
+		includeTax bool
+		rounding   string
+		split      func(items []models.OrderItem) ([]billShare, error)
+		wantBase   []models.Money
+	}{
+		{
+			name: "untaxed three ways", currency: "USD", items: []models.OrderItem{testOrderItem("e", "water", 100000, 0)},
+			rounding: models.TaxRoundingLine,
+			split:    func(items []models.OrderItem) ([]billShare, error) { return evenShares(items, 3) },
+			wantBase: []models.Money{33400, 33300, 33300},
+		},
+		{
+			name: "even three ways exclusive per line", currency: "USD", items: testSplitItems(100100, 33300, 77700, 10100),
+			rounding: models.TaxRoundingLine,
+			split:    func(items []models.OrderItem) ([]billShare, error) { return evenShares(items, 3) },
+		},
+		{
+			name: "even three ways in yen inclusive per line", currency: "JPY", items: testSplitItems(10010000, 3330000, 7770000, 1000000),
+			includeTax: true, rounding: models.TaxRoundingLine,
+			split: func(items []models.OrderItem) ([]billShare, error) { return evenShares(items, 3) },
+		},
+		{
+			name: "even three ways in dinars exclusive per invoice", currency: "KWD", items: testSplitItems(10010, 3330, 77770, 1010),
+			rounding: models.TaxRoundingInvoice,
+			split:    func(items []models.OrderItem) ([]billShare, error) { return evenShares(items, 3) },
+		},
+		{
+			name: "even seven ways of a few cents", currency: "USD", items: []models.OrderItem{testOrderItem("a", "burger", 500, 0)},
+			rounding: models.TaxRoundingLine,
+			split:    func(items []models.OrderItem) ([]billShare, error) { return evenShares(items, 7) },
+		},
+		{
+			name: "by items inclusive per invoice", currency: "USD", items: testSplitItems(100100, 33300, 77700, 10100),
+			includeTax: true, rounding: models.TaxRoundingInvoice,
+			split: func(items []models.OrderItem) ([]billShare, error) {
+				return itemShares(items, [][]string{{"a", "e"}, {"b", "c"}, {"d"}})
+			},
+		},
+		{
+			name: "by items in dinars inclusive per line", currency: "KWD", items: testSplitItems(10010, 3330, 77770, 1010),
+			includeTax: true, rounding: models.TaxRoundingLine,
+			split: func(items []models.OrderItem) ([]billShare, error) {
+				return itemShares(items, [][]string{{"a"}, {"b", "e"}, {"c", "d"}})
+			},
+		},
+		{
+			name: "by seat in yen exclusive per line", currency: "JPY", items: testSplitItems(10010000, 3330000, 7770000, 1000000),
+			rounding: models.TaxRoundingLine,
+			split:    seatShares,
+		},
+		{
+			name: "by seat in dinars inclusive per invoice", currency: "KWD", items: testSplitItems(10010, 3330, 77770, 1010),
+			includeTax: true, rounding: models.TaxRoundingInvoice,
+			split: seatShares,
+		},
+	}
+
+	for _, tt := range tests {
+		shares, err := tt.split(tt.items)
+		if err != nil {
+			t.Fatalf("%s: splitting returned error %v", tt.name, err)
+		}
+		breakdown := taxOrderItems(tt.currency, tt.items, testTaxCategories, testTaxRates, tt.includeTax, tt.rounding)
+		parts := priceBillShares(breakdown, shares, tt.currency)
+		if len(parts) != len(shares) {
+			t.Fatalf("%s: %d parts for %d shares", tt.name, len(parts), len(shares))
+		}
+
+		var subtotal, tax, total models.Money
+		lineTax := make(map[string]models.Money)
+		lineTaxable := make(map[string]models.Money)
+		for i, part := range parts {
+			subtotal, tax, total = subtotal.Add(part.subtotal), tax.Add(part.tax), total.Add(part.total)
+
+			if part.subtotal.Add(part.tax) != part.total {
+				t.Errorf("%s: part %d subtotal %d plus tax %d is not its total %d", tt.name, i, part.subtotal, part.tax, part.total)
+			}
+			for _, amount := range []models.Money{part.subtotal, part.tax, part.total} {
+				if amount != amount.Round(tt.currency) {
+					t.Errorf("%s: part %d has %d, which is not in whole minor units of %s", tt.name, i, amount, tt.currency)
+				}
+			}
+
+			partTax := models.Money(0)
+			for _, line := range part.taxLines {
+				partTax = partTax.Add(line.TaxAmount)
+				lineTax[line.TaxRateID] = lineTax[line.TaxRateID].Add(line.TaxAmount)
+				lineTaxable[line.TaxRateID] = lineTaxable[line.TaxRateID].Add(line.TaxableAmount)
+			}
+			if partTax != part.tax {
+				t.Errorf("%s: part %d tax lines add up to %d, want its tax %d", tt.name, i, partTax, part.tax)
+			}
+
+			if tt.wantBase != nil {
+				base := part.subtotal
+				if tt.includeTax {
+					base = part.total
+				}
+				if base != tt.wantBase[i] {
+					t.Errorf("%s: part %d = %d, want %d", tt.name, i, base, tt.wantBase[i])
+				}
+			}
+		}
+
+		if subtotal != breakdown.Subtotal || tax != breakdown.TaxAmount || total != breakdown.Total {
+			t.Errorf("%s: parts add up to subtotal %d, tax %d, total %d, want %d, %d, %d", tt.name,
+				subtotal, tax, total, breakdown.Subtotal, breakdown.TaxAmount, breakdown.Total)
+		}
+		for _, line := range breakdown.Lines {
+			if lineTax[line.TaxRateID] != line.TaxAmount || lineTaxable[line.TaxRateID] != line.TaxableAmount {
+				t.Errorf("%s: %s parts add up to tax %d on %d, want %d on %d", tt.name, line.TaxRateID,
+					lineTax[line.TaxRateID], lineTaxable[line.TaxRateID], line.TaxAmount, line.TaxableAmount)
+			}
+		}
+	}
+}
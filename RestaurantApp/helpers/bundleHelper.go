@@ -0,0 +1,212 @@
+package helpers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/RestaurantApp/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BundleSelection is the food chosen for one slot of an ordered bundle
+type BundleSelection struct {
+	SlotID    string                     `json:"slot_id"`
+	FoodID    string                     `json:"food_id"`
+	VariantID string                     `json:"variant_id"`
+	Modifiers []models.OrderItemModifier `json:"modifiers"`
+}
+
+// AddBundleToOrder orders a bundle on a locked order. Every slot of the bundle becomes an order
+// item for the chosen food, so the kitchen and stock keep seeing individual foods. The bundle
+// price is allocated across the components in proportion to their menu prices; slot upcharges
+// and modifier deltas are added to the component they belong to.
+func AddBundleToOrder(tx *gorm.DB, order *models.Order, bundleId string, quantity int, selections []BundleSelection) (*models.OrderBundle, error) {
+	if quantity < 1 {
+		return nil, NewRequestError(http.StatusBadRequest, "The bundle quantity must be at least 1")
+	}
+
+	var bundle models.Bundle
+	if err := tx.Preload("Slots", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Preload("Slots.Options").
+		Where("bundle_id = ?", bundleId).
+		First(&bundle).Error; err != nil {
+		return nil, NewRequestError(http.StatusBadRequest, "The bundle referenced does not exist")
+	}
+
+	if order.Currency != "" && bundle.Currency != order.Currency {
+		return nil, ErrCurrencyMismatch
+	}
+
+	selectionsBySlot := make(map[string]BundleSelection)
+	for _, selection := range selections {
+		if _, ok := selectionsBySlot[selection.SlotID]; ok {
+			return nil, NewRequestError(http.StatusBadRequest, "Each bundle slot can only be filled once")
+		}
+		selectionsBySlot[selection.SlotID] = selection
+	}
+
+	components := make([]models.OrderItem, 0, len(bundle.Slots))
+	upcharges := make([]models.Money, 0, len(bundle.Slots))
+	weights := make([]int64, 0, len(bundle.Slots))
+	for _, slot := range bundle.Slots {
+		selection, ok := selectionsBySlot[slot.SlotID]
+		delete(selectionsBySlot, slot.SlotID)
+
+		// A slot with a single fixed option does not need to be chosen
+		if !ok && len(slot.Options) == 1 {
+			selection = BundleSelection{SlotID: slot.SlotID, FoodID: slot.Options[0].FoodID, VariantID: slot.Options[0].VariantID}
+			ok = true
+		}
+		if !ok {
+			return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Please choose an option for %q", slot.Name))
+		}
+
+		option, found := findSlotOption(slot, selection)
+		if !found {
+			return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("The selected food is not an option for %q", slot.Name))
+		}
+
+		component := models.OrderItem{
+			OrderItemID: uuid.New().String(),
+			OrderID:     order.OrderID,
+			FoodID:      selection.FoodID,
+			VariantID:   selection.VariantID,
+			Quantity:    quantity,
+			Modifiers:   selection.Modifiers,
+		}
+		if err := SnapshotOrderItem(tx, &component, order.Currency); err != nil {
+			return nil, err
+		}
+
+		modifierDeltas := models.Money(0)
+		for _, modifier := range component.Modifiers {
+			modifierDeltas = modifierDeltas.Add(modifier.PriceDelta)
+		}
+
+		components = append(components, component)
+		upcharges = append(upcharges, option.PriceDelta.Add(modifierDeltas))
+		weights = append(weights, int64(component.UnitPrice.Sub(modifierDeltas)))
+	}
+
+	if len(selectionsBySlot) > 0 {
+		return nil, NewRequestError(http.StatusBadRequest, "A selection refers to a slot that is not part of this bundle")
+	}
+
+	if len(components) == 0 {
+		return nil, NewRequestError(http.StatusBadRequest, "This bundle has no components and cannot be ordered")
+	}
+
+	line := models.OrderBundle{
+		OrderID:    order.OrderID,
+		BundleID:   bundle.BundleID,
+		BundleName: bundle.Name,
+		Quantity:   quantity,
+	}
+	if err := tx.Create(&line).Error; err != nil {
+		return nil, err
+	}
+
+	shares := bundle.Price.Allocate(weights, bundle.Currency)
+	unitPrice := models.Money(0)
+	for i := range components {
+		components[i].BundleLineID = line.BundleLineID
+		components[i].UnitPrice = shares[i].Add(upcharges[i])
+		lineTotal, err := LineTotal(components[i].UnitPrice, quantity)
+		if err != nil {
+			return nil, err
+		}
+		components[i].LineTotal = lineTotal
+		unitPrice = unitPrice.Add(components[i].UnitPrice)
+
+		if err := tx.Create(&components[i]).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	lineTotal, err := LineTotal(unitPrice, quantity)
+	if err != nil {
+		return nil, err
+	}
+	line.UnitPrice = unitPrice
+	line.LineTotal = lineTotal
+	line.Items = components
+	if err := tx.Model(&line).Updates(map[string]interface{}{
+		"unit_price": line.UnitPrice,
+		"line_total": line.LineTotal,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return &line, nil
+}
+
+// UpdateBundleQuantity changes how many of an ordered bundle are on the order, keeping the
+// captured component prices
+func UpdateBundleQuantity(tx *gorm.DB, line *models.OrderBundle, quantity int) error {
+	if quantity < 1 {
+		return NewRequestError(http.StatusBadRequest, "The bundle quantity must be at least 1")
+	}
+
+	if err := tx.Where("bundle_line_id = ?", line.BundleLineID).Order("id ASC").Find(&line.Items).Error; err != nil {
+		return err
+	}
+
+	for i := range line.Items {
+		line.Items[i].Quantity = quantity
+		lineTotal, err := LineTotal(line.Items[i].UnitPrice, quantity)
+		if err != nil {
+			return err
+		}
+		line.Items[i].LineTotal = lineTotal
+		if err := tx.Omit("Modifiers").Save(&line.Items[i]).Error; err != nil {
+			return err
+		}
+	}
+
+	lineTotal, err := LineTotal(line.UnitPrice, quantity)
+	if err != nil {
+		return err
+	}
+	line.Quantity = quantity
+	line.LineTotal = lineTotal
+	return tx.Save(line).Error
+}
+
+// RemoveBundleFromOrder deletes an ordered bundle together with its component items, returning any
+// ingredients the components had used to stock. The deleted components are left in line.Items.
+func RemoveBundleFromOrder(tx *gorm.DB, line *models.OrderBundle, actorUid string) ([]AvailabilityChange, error) {
+	if err := tx.Where("bundle_line_id = ?", line.BundleLineID).Order("id ASC").Find(&line.Items).Error; err != nil {
+		return nil, err
+	}
+	componentIds := line.ComponentIDs()
+
+	changes, err := ReleaseOrderItemStock(tx, componentIds, actorUid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("order_item_id IN ?", componentIds).Delete(&models.OrderItemModifier{}).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("bundle_line_id = ?", line.BundleLineID).Delete(&models.OrderItem{}).Error; err != nil {
+		return nil, err
+	}
+
+	return changes, tx.Delete(line).Error
+}
+
+// findSlotOption matches a selection against a slot's options. An option without a variant
+// accepts any variant of its food.
+func findSlotOption(slot models.BundleSlot, selection BundleSelection) (models.BundleSlotOption, bool) {
+	for _, option := range slot.Options {
+		if option.FoodID != selection.FoodID {
+			continue
+		}
+		if option.VariantID == "" || option.VariantID == selection.VariantID {
+			return option, true
+		}
+	}
+	return models.BundleSlotOption{}, false
+}
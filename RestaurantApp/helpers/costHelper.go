@@ -0,0 +1,95 @@
+package helpers
+
+import (
+	"math"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+)
+
+// IngredientUnitCost converts the price paid for one purchase unit into the cost of one unit of
+// the ingredient, e.g. a 25 kg sack at 30.00 costs 1.20 per kg
+func IngredientUnitCost(purchaseUnitCost models.Money, packSize models.Quantity) models.Money {
+	if packSize <= 0 {
+		return 0
+	}
+	return purchaseUnitCost.MulRatio(int64(models.QuantityFromInt(1)), int64(packSize), models.RoundHalfUp)
+}
+
+// FoodUnitCost returns the ingredient cost of one portion of a food, or of one of its variants,
+// from its recipe and the current ingredient costs
+func FoodUnitCost(tx *gorm.DB, foodId, variantId string) (models.Money, error) {
+	var lines []struct {
+		Quantity models.Quantity
+		UnitCost models.Money
+	}
+	if err := tx.Model(&models.Recipe{}).
+		Select("recipes.quantity, ingredients.unit_cost").
+		Joins("JOIN ingredients ON ingredients.ingredient_id = recipes.ingredient_id").
+		Where("recipes.food_id = ? AND (recipes.variant_id = '' OR recipes.variant_id = ?)", foodId, variantId).
+		Scan(&lines).Error; err != nil {
+		return 0, err
+	}
+
+	var cost models.Money
+	for _, line := range lines {
+		cost = cost.Add(line.Quantity.Cost(line.UnitCost))
+	}
+	return cost, nil
+}
+
+// FoodCosting is the theoretical plate cost of a food, or of one of its variants, against its price
+type FoodCosting struct {
+	VariantID       string       `json:"variant_id,omitempty"`
+	VariantName     string       `json:"variant_name,omitempty"`
+	Price           models.Money `json:"price"`
+	PlateCost       models.Money `json:"plate_cost"`
+	Margin          models.Money `json:"margin"`
+	MarginPercent   float64      `json:"margin_percent"`
+	FoodCostPercent float64      `json:"food_cost_percent"`
+}
+
+// NewFoodCosting works out the margin of selling a plate costing plateCost at price
+func NewFoodCosting(price, plateCost models.Money) FoodCosting {
+	margin := price.Sub(plateCost)
+	return FoodCosting{
+		Price:           price,
+		PlateCost:       plateCost,
+		Margin:          margin,
+		MarginPercent:   PercentOf(margin, price),
+		FoodCostPercent: PercentOf(plateCost, price),
+	}
+}
+
+// CostFood returns the costing of a food at its price, or of each of its variants when it is sold in
+// variants. The food's Variants must be loaded.
+func CostFood(tx *gorm.DB, food *models.Food) ([]FoodCosting, error) {
+	if len(food.Variants) == 0 {
+		plateCost, err := FoodUnitCost(tx, food.FoodID, "")
+		if err != nil {
+			return nil, err
+		}
+		return []FoodCosting{NewFoodCosting(food.Price, plateCost)}, nil
+	}
+
+	costings := make([]FoodCosting, 0, len(food.Variants))
+	for _, variant := range food.Variants {
+		plateCost, err := FoodUnitCost(tx, food.FoodID, variant.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		costing := NewFoodCosting(variant.Price, plateCost)
+		costing.VariantID = variant.VariantID
+		costing.VariantName = variant.Name
+		costings = append(costings, costing)
+	}
+	return costings, nil
+}
+
+// PercentOf returns part as a percentage of whole, rounded to two decimals, or 0 when whole is not positive
+func PercentOf(part, whole models.Money) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return math.Round(float64(part)*10000/float64(whole)) / 100
+}
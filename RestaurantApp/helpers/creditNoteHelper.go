@@ -0,0 +1,222 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// creditNoteSequence names the document sequence credit note numbers are taken from
+const creditNoteSequence = "credit_note"
+
+// creditNoteNumberFormat formats a credit note's sequence number as its document number
+const creditNoteNumberFormat = "CN-%06d"
+
+// RefundLine asks for an amount to be given back against one payment; without an amount, whatever
+// has not been refunded yet is given back
+type RefundLine struct {
+	PaymentID string       `json:"payment_id" binding:"required"`
+	Amount    models.Money `json:"amount"`
+}
+
+// NextDocumentNumber takes the next number of a document sequence, starting at 1. The sequence row
+// stays locked until the transaction ends, so numbers are gapless: one rolled back is reused.
+func NextDocumentNumber(tx *gorm.DB, name string) (int64, error) {
+	if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).
+		Create(&models.DocumentSequence{Name: name}).Error; err != nil {
+		return 0, err
+	}
+
+	var sequence models.DocumentSequence
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", name).First(&sequence).Error; err != nil {
+		return 0, err
+	}
+
+	sequence.LastNumber++
+	if err := tx.Model(&sequence).Update("last_number", sequence.LastNumber).Error; err != nil {
+		return 0, err
+	}
+	return sequence.LastNumber, nil
+}
+
+// refundPlan is a validated refund line with the payment it is made against
+type refundPlan struct {
+	payment models.Payment
+	amount  models.Money
+}
+
+// IssueRefund documents money given back against payments on an invoice in a credit note with the
+// next credit note number. Each payment can be refunded in full or in part, up to what has not been
+// refunded yet. Every line is checked before anything is saved; refunds through the payment
+// provider are saved as pending and made by SettleProviderRefunds once the credit note has been
+// committed, while other methods are handed back by staff. The invoice's total is reduced by the
+// credit note, so a paid invoice stays paid.
+func IssueRefund(tx *gorm.DB, invoice *models.Invoice, lines []RefundLine, reason, actorUid string) (models.CreditNote, error) {
+	if len(lines) == 0 {
+		return models.CreditNote{}, NewRequestError(http.StatusBadRequest, "At least one payment to refund is required")
+	}
+
+	plans := make([]refundPlan, 0, len(lines))
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		if seen[line.PaymentID] {
+			return models.CreditNote{}, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Payment %q is listed more than once", line.PaymentID))
+		}
+		seen[line.PaymentID] = true
+
+		payment, err := LockPayment(tx, invoice.InvoiceID, line.PaymentID)
+		if err != nil {
+			return models.CreditNote{}, NewRequestError(http.StatusNotFound, fmt.Sprintf("Payment %q could not be found on this invoice", line.PaymentID))
+		}
+		if payment.Status != models.PaymentStatusCompleted {
+			return models.CreditNote{}, NewRequestError(http.StatusConflict, fmt.Sprintf("A %s payment cannot be refunded", payment.Status))
+		}
+
+		refundable := payment.Amount.Sub(payment.RefundedAmount)
+		amount := line.Amount
+		if amount.IsZero() {
+			amount = refundable
+		}
+		amount = amount.Round(payment.Currency)
+		if amount.IsNegative() || amount.IsZero() {
+			return models.CreditNote{}, NewRequestError(http.StatusBadRequest, "The refund amount must be positive")
+		}
+		if refundable.Sub(amount).IsNegative() {
+			return models.CreditNote{}, NewRequestError(http.StatusBadRequest,
+				fmt.Sprintf("Payment %q only has %s left to refund", payment.PaymentID, refundable))
+		}
+		plans = append(plans, refundPlan{payment: payment, amount: amount})
+	}
+
+	return recordCreditNote(tx, invoice, plans, reason, actorUid, models.RefundStatusPending)
+}
+
+// recordCreditNote saves a credit note for validated refunds, with the next credit note number, and
+// marks the amounts refunded on their payments. providerStatus is the status given to refunds made
+// through the payment provider.
+func recordCreditNote(tx *gorm.DB, invoice *models.Invoice, plans []refundPlan, reason, actorUid, providerStatus string) (models.CreditNote, error) {
+	note := models.CreditNote{
+		InvoiceID: invoice.InvoiceID,
+		OrderID:   invoice.OrderID,
+		Currency:  invoice.Currency,
+		Reason:    reason,
+		IssuedBy:  actorUid,
+		IssuedAt:  time.Now(),
+	}
+
+	for _, plan := range plans {
+		payment := plan.payment
+		payment.RefundedAmount = payment.RefundedAmount.Add(plan.amount)
+		if payment.RefundedAmount == payment.Amount {
+			payment.Status = models.PaymentStatusRefunded
+		}
+		if err := tx.Model(&payment).Select("refunded_amount", "status").Updates(&payment).Error; err != nil {
+			return models.CreditNote{}, err
+		}
+
+		status := models.RefundStatusCompleted
+		if payment.Provider != "" {
+			status = providerStatus
+		}
+		note.Amount = note.Amount.Add(plan.amount)
+		note.Refunds = append(note.Refunds, models.Refund{
+			InvoiceID:         invoice.InvoiceID,
+			PaymentID:         payment.PaymentID,
+			Amount:            plan.amount,
+			Currency:          payment.Currency,
+			Method:            payment.Method,
+			Provider:          payment.Provider,
+			ProviderReference: payment.ProviderReference,
+			Status:            status,
+		})
+	}
+
+	// The tax given back is in proportion to the invoice's tax, so reports can net refunds before or after tax
+	if !invoice.TotalAmount.IsZero() {
+		note.TaxAmount = note.Amount.MulRatio(int64(invoice.TaxAmount), int64(invoice.TotalAmount), models.LookupCurrency(invoice.Currency).Mode).Round(invoice.Currency)
+	}
+
+	number, err := NextDocumentNumber(tx, creditNoteSequence)
+	if err != nil {
+		return models.CreditNote{}, err
+	}
+	note.Number = fmt.Sprintf(creditNoteNumberFormat, number)
+	if err := tx.Create(&note).Error; err != nil {
+		return models.CreditNote{}, err
+	}
+
+	return note, ApplyInvoicePayments(tx, invoice)
+}
+
+// SettleProviderRefunds makes the pending provider refunds of a committed credit note through the
+// payment provider. Each refund's id is its idempotency key, so settling again after a failure never
+// refunds twice. Only refunds the provider reports as made are completed; those it could not be
+// reached for or did not make stay pending and an error is returned.
+func SettleProviderRefunds(ctx context.Context, db *gorm.DB, note *models.CreditNote) error {
+	var failed []string
+	provider := CurrentPaymentProvider()
+	for i := range note.Refunds {
+		refund := &note.Refunds[i]
+		if refund.Status != models.RefundStatusPending {
+			continue
+		}
+
+		result, err := provider.Refund(ctx, refund.ProviderReference, refund.Amount, refund.Currency, refund.RefundID)
+		switch {
+		case err != nil:
+			refund.ProviderMessage = "Provider unreachable: " + err.Error()
+			failed = append(failed, refund.PaymentID)
+		case result.Status == models.PaymentStatusRefunded:
+			refund.Status = models.RefundStatusCompleted
+			refund.ProviderMessage = result.Message
+		case result.Status == models.PaymentStatusPending:
+			// The provider confirms the refund later through the payment webhook
+			refund.ProviderMessage = result.Message
+		default:
+			refund.ProviderMessage = "Refund not made: " + result.Message
+			failed = append(failed, refund.PaymentID)
+		}
+
+		settle, cancel := settleDB(ctx, db)
+		err = settle.Model(refund).Select("status", "provider_message").Updates(refund).Error
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(failed) > 0 {
+		return NewRequestError(http.StatusBadGateway, fmt.Sprintf("Credit note %s was issued, but the payment provider did not refund payments %s; retry the refunds later",
+			note.Number, strings.Join(failed, ", ")))
+	}
+	return nil
+}
+
+// CreditNotePDF renders a credit note as a printable PDF for the guest
+func CreditNotePDF(note *models.CreditNote, invoice *models.Invoice) []byte {
+	doc := NewPDFDocument()
+	doc.Text(18, "Credit Note")
+	doc.Text(10, "Number: "+note.Number)
+	doc.Text(10, "Date: "+note.IssuedAt.In(RestaurantLocation()).Format("2006-01-02"))
+	doc.Text(10, "Original invoice: "+invoice.InvoiceID)
+	doc.Text(10, "Invoice date: "+invoice.CreatedAt.In(RestaurantLocation()).Format("2006-01-02"))
+	doc.Text(10, "Reason: "+note.Reason)
+
+	columns := []float64{0, 120, 260, 445}
+	doc.Gap(10)
+	doc.Row(10, []string{"Date", "Method", "Payment", "Refunded"}, columns)
+	for _, refund := range note.Refunds {
+		doc.Row(10, []string{refund.CreatedAt.In(RestaurantLocation()).Format("2006-01-02"), refund.Method, refund.PaymentID, refund.Amount.String()}, columns)
+	}
+	doc.Gap(4)
+	doc.Row(11, []string{"Total credited (" + note.Currency + ")", note.Amount.String()}, []float64{260, 445})
+	doc.Row(10, []string{"Of which tax", note.TaxAmount.String()}, []float64{260, 445})
+	doc.Row(10, []string{"Invoice total", invoice.TotalAmount.String()}, []float64{260, 445})
+	return doc.Bytes()
+}
@@ -0,0 +1,74 @@
+package helpers
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Event is a message delivered to live subscribers of a Broker
+type Event struct {
+	Sequence uint64          `json:"sequence"`
+	Type     string          `json:"type"`
+	Key      string          `json:"key,omitempty"` // lets subscribers filter, e.g. by kitchen station
+	Data     json.RawMessage `json:"data"`
+}
+
+// Broker fans published events out to every connected subscriber. Subscribers that fall
+// too far behind are disconnected rather than blocking publishers; they are expected to
+// reconnect and replay what they missed.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// subscriberBuffer is how many undelivered events a subscriber may have before it is dropped
+const subscriberBuffer = 64
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[chan Event]struct{})}
+}
+
+// Subscribe registers a new subscriber. The returned function must be called to unsubscribe.
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish delivers events to every subscriber without blocking
+func (b *Broker) Publish(events ...Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		for _, event := range events {
+			select {
+			case ch <- event:
+			default:
+				// The subscriber is not keeping up; close it so it reconnects and replays
+				delete(b.subscribers, ch)
+				close(ch)
+			}
+			if _, ok := b.subscribers[ch]; !ok {
+				break
+			}
+		}
+	}
+}
+
+// KitchenBroker streams kitchen ticket changes to connected kitchen screens
+var KitchenBroker = NewBroker()
+
+// AvailabilityBroker streams food and modifier availability changes to connected clients
+var AvailabilityBroker = NewBroker()
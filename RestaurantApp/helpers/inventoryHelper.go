@@ -0,0 +1,247 @@
+package helpers
+
+import (
+	"sort"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+)
+
+// stockDepletingStatuses are the order statuses in which an order's ingredients have been used
+var stockDepletingStatuses = map[string]bool{
+	models.OrderStatusAccepted:  true,
+	models.OrderStatusPreparing: true,
+	models.OrderStatusReady:     true,
+	models.OrderStatusServed:    true,
+	models.OrderStatusInvoiced:  true,
+	models.OrderStatusCompleted: true,
+}
+
+// OrderDepletesStock reports whether an order in the given status has taken its ingredients from stock
+func OrderDepletesStock(status string) bool {
+	return stockDepletingStatuses[status]
+}
+
+// AdjustStock records a signed stock movement for an ingredient and applies it to the on-hand quantity
+func AdjustStock(tx *gorm.DB, movement *models.StockMovement) error {
+	if movement.Quantity.IsZero() {
+		return nil
+	}
+
+	result := tx.Model(&models.Ingredient{}).
+		Where("ingredient_id = ?", movement.IngredientID).
+		Update("on_hand", gorm.Expr("on_hand + ?", movement.Quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return tx.Create(movement).Error
+}
+
+// recipeUsage returns how much of each ingredient an order item uses according to its food's current
+// recipe. An item without a positive quantity uses nothing, so it can never put stock back.
+func recipeUsage(tx *gorm.DB, orderItem *models.OrderItem) (map[string]models.Quantity, error) {
+	usage := make(map[string]models.Quantity)
+	if orderItem.Quantity <= 0 {
+		return usage, nil
+	}
+
+	var lines []models.Recipe
+	if err := tx.Where("food_id = ? AND (variant_id = '' OR variant_id = ?)", orderItem.FoodID, orderItem.VariantID).
+		Find(&lines).Error; err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		usage[line.IngredientID] = usage[line.IngredientID].Add(line.Quantity.Mul(int64(orderItem.Quantity)))
+	}
+	return usage, nil
+}
+
+// orderItemStockMovements returns the movements that make the net stock movements recorded for an
+// order item match its use of ingredients: the recipe quantities while depleted, nothing otherwise.
+// Restoring returns exactly what was taken, even if the recipe has changed since.
+func orderItemStockMovements(tx *gorm.DB, orderItem *models.OrderItem, depleted bool, actorUid string) ([]models.StockMovement, error) {
+	var recorded []struct {
+		IngredientID string
+		Quantity     models.Quantity
+	}
+	if err := tx.Model(&models.StockMovement{}).
+		Select("ingredient_id, SUM(quantity) AS quantity").
+		Where("source_type = ? AND source_id = ?", models.StockSourceOrderItem, orderItem.OrderItemID).
+		Group("ingredient_id").
+		Scan(&recorded).Error; err != nil {
+		return nil, err
+	}
+
+	target := make(map[string]models.Quantity)
+	if depleted {
+		usage, err := recipeUsage(tx, orderItem)
+		if err != nil {
+			return nil, err
+		}
+		for ingredientId, quantity := range usage {
+			target[ingredientId] = quantity.Neg()
+		}
+	}
+
+	current := make(map[string]models.Quantity)
+	for _, row := range recorded {
+		current[row.IngredientID] = row.Quantity
+		if _, ok := target[row.IngredientID]; !ok {
+			target[row.IngredientID] = 0
+		}
+	}
+
+	reason := "order item restored"
+	if depleted {
+		reason = "order item used"
+	}
+
+	var movements []models.StockMovement
+	for ingredientId, quantity := range target {
+		movement := models.StockMovement{
+			IngredientID: ingredientId,
+			Quantity:     quantity.Sub(current[ingredientId]),
+			SourceType:   models.StockSourceOrderItem,
+			SourceID:     orderItem.OrderItemID,
+			Reason:       reason,
+			ActorUID:     actorUid,
+		}
+		if !movement.Quantity.IsZero() {
+			movements = append(movements, movement)
+		}
+	}
+	return movements, nil
+}
+
+// applyStockMovements applies movements in ingredient id order, so concurrent transactions lock the
+// ingredient rows they share in the same order and cannot deadlock. It returns the ingredients that moved.
+func applyStockMovements(tx *gorm.DB, movements []models.StockMovement) ([]string, error) {
+	sort.SliceStable(movements, func(i, j int) bool { return movements[i].IngredientID < movements[j].IngredientID })
+
+	moved := make([]string, 0, len(movements))
+	for i := range movements {
+		if err := AdjustStock(tx, &movements[i]); err != nil {
+			return nil, err
+		}
+		moved = append(moved, movements[i].IngredientID)
+	}
+	return moved, nil
+}
+
+// ApplyInventorySideEffects updates stock after an order status change: confirmed orders take
+// their ingredients from stock, and cancelled or voided orders give back what they took.
+// The returned availability changes must be published once the transaction commits.
+func ApplyInventorySideEffects(tx *gorm.DB, order *models.Order, actorUid string) ([]AvailabilityChange, error) {
+	switch order.OrderStatus {
+	case models.OrderStatusAccepted, models.OrderStatusCancelled, models.OrderStatusVoided:
+		var orderItemIds []string
+		if err := tx.Model(&models.OrderItem{}).Where("order_id = ?", order.OrderID).Pluck("order_item_id", &orderItemIds).Error; err != nil {
+			return nil, err
+		}
+		return SyncOrderItemStock(tx, order, orderItemIds, actorUid)
+	}
+	return nil, nil
+}
+
+// SyncOrderItemStock brings the stock used by the given items of an order in line with the order's
+// status, so items added to or changed on an accepted order are reflected in inventory
+func SyncOrderItemStock(tx *gorm.DB, order *models.Order, orderItemIds []string, actorUid string) ([]AvailabilityChange, error) {
+	if len(orderItemIds) == 0 {
+		return nil, nil
+	}
+
+	var orderItems []models.OrderItem
+	if err := tx.Where("order_id = ? AND order_item_id IN ?", order.OrderID, orderItemIds).Find(&orderItems).Error; err != nil {
+		return nil, err
+	}
+
+	depleted := OrderDepletesStock(order.OrderStatus)
+	var movements []models.StockMovement
+	for i := range orderItems {
+		itemMovements, err := orderItemStockMovements(tx, &orderItems[i], depleted, actorUid)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, itemMovements...)
+	}
+
+	moved, err := applyStockMovements(tx, movements)
+	if err != nil {
+		return nil, err
+	}
+	return RefreshStockAvailability(tx, moved)
+}
+
+// ReleaseOrderItemStock gives back the ingredients used by order items that are about to be deleted
+func ReleaseOrderItemStock(tx *gorm.DB, orderItemIds []string, actorUid string) ([]AvailabilityChange, error) {
+	var movements []models.StockMovement
+	for _, orderItemId := range orderItemIds {
+		itemMovements, err := orderItemStockMovements(tx, &models.OrderItem{OrderItemID: orderItemId}, false, actorUid)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, itemMovements...)
+	}
+
+	moved, err := applyStockMovements(tx, movements)
+	if err != nil {
+		return nil, err
+	}
+	return RefreshStockAvailability(tx, moved)
+}
+
+// RefreshStockAvailability marks foods using the given ingredients as stocked out when any ingredient
+// no longer covers a single portion, and available again once it does. It returns the foods that changed.
+func RefreshStockAvailability(tx *gorm.DB, ingredientIds []string) ([]AvailabilityChange, error) {
+	if len(ingredientIds) == 0 {
+		return nil, nil
+	}
+	return refreshStockedOut(tx, tx.Model(&models.Recipe{}).Select("food_id").Where("ingredient_id IN ?", ingredientIds))
+}
+
+// RefreshFoodStockAvailability re-evaluates whether a food is stocked out, e.g. after its recipe changed
+func RefreshFoodStockAvailability(tx *gorm.DB, foodId string) ([]AvailabilityChange, error) {
+	return refreshStockedOut(tx, []string{foodId})
+}
+
+// refreshStockedOut updates the stocked out flag of the given foods. Only recipe lines shared by every
+// variant are considered, so a food stays available while any of its variants could still be made.
+func refreshStockedOut(tx *gorm.DB, foodIds interface{}) ([]AvailabilityChange, error) {
+	var foods []struct {
+		FoodID     string
+		StockedOut bool
+		Short      bool
+	}
+	if err := tx.Model(&models.Food{}).
+		Select(`foods.food_id, foods.stocked_out,
+			EXISTS (SELECT 1 FROM recipes r JOIN ingredients i ON i.ingredient_id = r.ingredient_id
+				WHERE r.food_id = foods.food_id AND r.variant_id = '' AND i.on_hand < r.quantity) AS short`).
+		Where("foods.food_id IN (?)", foodIds).
+		Scan(&foods).Error; err != nil {
+		return nil, err
+	}
+
+	var changes []AvailabilityChange
+	for _, row := range foods {
+		if row.Short == row.StockedOut {
+			continue
+		}
+
+		var food models.Food
+		if err := tx.Where("food_id = ?", row.FoodID).First(&food).Error; err != nil {
+			return nil, err
+		}
+		food.StockedOut = row.Short
+		if err := tx.Model(&food).Update("stocked_out", food.StockedOut).Error; err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, FoodAvailabilityChange(&food))
+	}
+	return changes, nil
+}
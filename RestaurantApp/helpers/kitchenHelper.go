@@ -0,0 +1,571 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Kitchen feed event types
+const (
+	KitchenEventTicketCreated = "ticket_created"
+	KitchenEventTicketUpdated = "ticket_updated"
+)
+
+// ErrInvalidTicketStatus is returned when a kitchen ticket item cannot move to the requested status
+var ErrInvalidTicketStatus = errors.New("invalid kitchen ticket status change")
+
+// ticketTransitions maps each ticket item status to the statuses kitchen staff may bump it to
+var ticketTransitions = map[string][]string{
+	models.TicketStatusQueued:     {models.TicketStatusInProgress, models.TicketStatusReady},
+	models.TicketStatusInProgress: {models.TicketStatusReady},
+	models.TicketStatusReady:      {},
+	models.TicketStatusCancelled:  {},
+}
+
+// ApplyKitchenSideEffects updates the kitchen feed after an order status change: confirmed
+// orders get tickets, and cancelled or voided orders have their open tickets withdrawn.
+// The returned events must be published with PublishKitchenEvents once the transaction commits.
+func ApplyKitchenSideEffects(tx *gorm.DB, order *models.Order) ([]models.KitchenEvent, error) {
+	switch order.OrderStatus {
+	case models.OrderStatusAccepted:
+		return GenerateKitchenTickets(tx, order)
+	case models.OrderStatusCancelled, models.OrderStatusVoided:
+		return CancelKitchenTickets(tx, order.OrderID)
+	}
+	return nil, nil
+}
+
+// kitchenOrderStatuses are the order statuses in which an order's items have been sent to the kitchen
+var kitchenOrderStatuses = map[string]bool{
+	models.OrderStatusAccepted:  true,
+	models.OrderStatusPreparing: true,
+	models.OrderStatusReady:     true,
+	models.OrderStatusServed:    true,
+}
+
+// kitchenFeedLock is the advisory lock key that serialises assigning kitchen feed positions
+const kitchenFeedLock = 51740023
+
+// GenerateKitchenTickets splits the items of a confirmed order into one kitchen ticket per station
+func GenerateKitchenTickets(tx *gorm.DB, order *models.Order) ([]models.KitchenEvent, error) {
+	var orderItems []models.OrderItem
+	if err := tx.Preload("Modifiers", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("order_id = ?", order.OrderID).Order("id ASC").Find(&orderItems).Error; err != nil {
+		return nil, err
+	}
+
+	return ticketOrderItems(tx, order, orderItems)
+}
+
+// SyncOrderItemTickets keeps the kitchen in line with items added to, changed on or removed from an
+// order that has already been sent to the kitchen. Quantities not yet on a ticket go out on new
+// tickets, and queued ticket items beyond what is still ordered are withdrawn; items the kitchen
+// has started on are left alone, and a ready order that gets new tickets goes back to preparing.
+// The returned events must be published once the transaction commits.
+func SyncOrderItemTickets(tx *gorm.DB, order *models.Order, orderItemIds []string, actorUid string) ([]models.KitchenEvent, error) {
+	if !kitchenOrderStatuses[order.OrderStatus] || len(orderItemIds) == 0 {
+		return nil, nil
+	}
+
+	var orderItems []models.OrderItem
+	if err := tx.Preload("Modifiers", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("order_id = ? AND order_item_id IN ?", order.OrderID, orderItemIds).Order("id ASC").Find(&orderItems).Error; err != nil {
+		return nil, err
+	}
+
+	ordered := make(map[string]int, len(orderItemIds))
+	for _, orderItemId := range orderItemIds {
+		ordered[orderItemId] = 0
+	}
+	for _, orderItem := range orderItems {
+		ordered[orderItem.OrderItemID] = orderItem.Quantity
+	}
+
+	events, err := withdrawTicketItems(tx, order.OrderID, ordered, actorUid)
+	if err != nil {
+		return nil, err
+	}
+	// Withdrawing items may have moved the order on to ready
+	if len(events) > 0 {
+		if err := tx.Model(&models.Order{}).Select("order_status").Where("order_id = ?", order.OrderID).Scan(&order.OrderStatus).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	created, err := ticketOrderItems(tx, order, orderItems)
+	if err != nil {
+		return nil, err
+	}
+
+	// A ready order with new tickets still has food to cook, so it must not be served yet
+	if len(created) > 0 && order.OrderStatus == models.OrderStatusReady {
+		if err := TransitionOrder(tx, order, models.OrderStatusPreparing, actorUid, "items added after the order was ready"); err != nil {
+			return nil, err
+		}
+	}
+	return append(events, created...), nil
+}
+
+// ticketOrderItems sends whatever quantity of the given order items is not yet on a kitchen ticket
+// to the kitchen, as one new ticket per station
+func ticketOrderItems(tx *gorm.DB, order *models.Order, orderItems []models.OrderItem) ([]models.KitchenEvent, error) {
+	if len(orderItems) == 0 {
+		return nil, nil
+	}
+
+	orderItemIds := make([]string, 0, len(orderItems))
+	foodIds := make([]string, 0, len(orderItems))
+	for _, orderItem := range orderItems {
+		orderItemIds = append(orderItemIds, orderItem.OrderItemID)
+		foodIds = append(foodIds, orderItem.FoodID)
+	}
+
+	ticketed, err := ticketedQuantities(tx, orderItemIds)
+	if err != nil {
+		return nil, err
+	}
+
+	stations, err := ResolveStations(tx, foodIds)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group items by station, keeping tickets in the order their stations first appear
+	var tickets []*models.KitchenTicket
+	ticketsByStation := make(map[string]*models.KitchenTicket)
+	for _, orderItem := range orderItems {
+		outstanding := orderItem.Quantity - ticketed[orderItem.OrderItemID]
+		if outstanding <= 0 {
+			continue
+		}
+
+		stationId := stations[orderItem.FoodID]
+		ticket, ok := ticketsByStation[stationId]
+		if !ok {
+			ticket = &models.KitchenTicket{
+				OrderID:   order.OrderID,
+				StationID: stationId,
+				TableID:   order.TableID,
+				Status:    models.TicketStatusQueued,
+			}
+			ticketsByStation[stationId] = ticket
+			tickets = append(tickets, ticket)
+		}
+
+		ticket.Items = append(ticket.Items, models.KitchenTicketItem{
+			OrderItemID: orderItem.OrderItemID,
+			FoodID:      orderItem.FoodID,
+			FoodName:    orderItem.FoodName,
+			VariantName: orderItem.VariantName,
+			Modifiers:   ModifierSummary(orderItem.Modifiers),
+			Quantity:    outstanding,
+			Status:      models.TicketStatusQueued,
+		})
+	}
+
+	var events []models.KitchenEvent
+	for _, ticket := range tickets {
+		if err := tx.Create(ticket).Error; err != nil {
+			return nil, err
+		}
+
+		event, err := RecordKitchenEvent(tx, KitchenEventTicketCreated, ticket)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// withdrawTicketItems cancels or shrinks the newest queued ticket items of order items whose
+// ticketed quantity is more than the quantity still ordered, then updates the tickets affected
+func withdrawTicketItems(tx *gorm.DB, orderId string, ordered map[string]int, actorUid string) ([]models.KitchenEvent, error) {
+	orderItemIds := make([]string, 0, len(ordered))
+	for orderItemId := range ordered {
+		orderItemIds = append(orderItemIds, orderItemId)
+	}
+	sort.Strings(orderItemIds)
+
+	ticketed, err := ticketedQuantities(tx, orderItemIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var ticketIds []string
+	affected := make(map[string]bool)
+	for _, orderItemId := range orderItemIds {
+		excess := ticketed[orderItemId] - ordered[orderItemId]
+		if excess <= 0 {
+			continue
+		}
+
+		var queued []models.KitchenTicketItem
+		if err := tx.Where("order_item_id = ? AND status = ?", orderItemId, models.TicketStatusQueued).
+			Order("id DESC").Find(&queued).Error; err != nil {
+			return nil, err
+		}
+
+		for _, item := range queued {
+			if excess <= 0 {
+				break
+			}
+
+			update := map[string]interface{}{"quantity": item.Quantity - excess}
+			if item.Quantity <= excess {
+				update = map[string]interface{}{"status": models.TicketStatusCancelled}
+			}
+			if err := tx.Model(&item).Updates(update).Error; err != nil {
+				return nil, err
+			}
+			excess -= item.Quantity
+
+			if !affected[item.TicketID] {
+				affected[item.TicketID] = true
+				ticketIds = append(ticketIds, item.TicketID)
+			}
+		}
+	}
+
+	if len(ticketIds) == 0 {
+		return nil, nil
+	}
+
+	var events []models.KitchenEvent
+	for _, ticketId := range ticketIds {
+		var ticket models.KitchenTicket
+		if err := tx.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+			Where("ticket_id = ?", ticketId).First(&ticket).Error; err != nil {
+			return nil, err
+		}
+
+		ticket.Status = summarizeTicketStatus(ticket.Items)
+		if err := tx.Model(&ticket).Update("status", ticket.Status).Error; err != nil {
+			return nil, err
+		}
+
+		event, err := RecordKitchenEvent(tx, KitchenEventTicketUpdated, &ticket)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	// Withdrawing the last unfinished items may leave every ticket of the order ready
+	return events, syncOrderWithKitchen(tx, orderId, actorUid)
+}
+
+// ticketedQuantities returns how much of each order item is on kitchen tickets that have not been cancelled
+func ticketedQuantities(tx *gorm.DB, orderItemIds []string) (map[string]int, error) {
+	var rows []struct {
+		OrderItemID string
+		Quantity    int
+	}
+	if err := tx.Model(&models.KitchenTicketItem{}).
+		Select("order_item_id, COALESCE(SUM(quantity), 0) AS quantity").
+		Where("order_item_id IN ? AND status <> ?", orderItemIds, models.TicketStatusCancelled).
+		Group("order_item_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	ticketed := make(map[string]int, len(rows))
+	for _, row := range rows {
+		ticketed[row.OrderItemID] = row.Quantity
+	}
+	return ticketed, nil
+}
+
+// ResolveStations maps each food to the kitchen station that prepares it. A route for the food
+// itself wins over a route for its menu's category; foods with no route map to the empty station.
+func ResolveStations(tx *gorm.DB, foodIds []string) (map[string]string, error) {
+	stations := make(map[string]string)
+
+	var categories []struct {
+		FoodID   string
+		Category string
+	}
+	if err := tx.Model(&models.Food{}).
+		Select("foods.food_id, menus.category").
+		Joins("JOIN menus ON foods.menu_id = menus.menu_id").
+		Where("foods.food_id IN ?", foodIds).
+		Scan(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	categoryNames := make([]string, 0, len(categories))
+	for _, category := range categories {
+		categoryNames = append(categoryNames, category.Category)
+	}
+
+	var categoryRoutes []models.StationRoute
+	if err := tx.Where("category IN ? AND food_id = ''", categoryNames).Order("id ASC").Find(&categoryRoutes).Error; err != nil {
+		return nil, err
+	}
+
+	stationByCategory := make(map[string]string)
+	for _, route := range categoryRoutes {
+		if _, ok := stationByCategory[route.Category]; !ok {
+			stationByCategory[route.Category] = route.StationID
+		}
+	}
+	for _, category := range categories {
+		if stationId, ok := stationByCategory[category.Category]; ok {
+			stations[category.FoodID] = stationId
+		}
+	}
+
+	var foodRoutes []models.StationRoute
+	if err := tx.Where("food_id IN ?", foodIds).Order("id ASC").Find(&foodRoutes).Error; err != nil {
+		return nil, err
+	}
+	for _, route := range foodRoutes {
+		stations[route.FoodID] = route.StationID
+	}
+
+	return stations, nil
+}
+
+// CancelKitchenTickets withdraws every ticket of an order that is not yet ready
+func CancelKitchenTickets(tx *gorm.DB, orderId string) ([]models.KitchenEvent, error) {
+	var tickets []models.KitchenTicket
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status IN ?", orderId, []string{models.TicketStatusQueued, models.TicketStatusInProgress}).
+		Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+
+	var events []models.KitchenEvent
+	for i := range tickets {
+		ticket := &tickets[i]
+		if err := tx.Model(&models.KitchenTicketItem{}).
+			Where("ticket_id = ? AND status <> ?", ticket.TicketID, models.TicketStatusReady).
+			Update("status", models.TicketStatusCancelled).Error; err != nil {
+			return nil, err
+		}
+
+		ticket.Status = models.TicketStatusCancelled
+		if err := tx.Model(ticket).Update("status", ticket.Status).Error; err != nil {
+			return nil, err
+		}
+
+		if err := tx.Where("ticket_id = ?", ticket.TicketID).Order("id ASC").Find(&ticket.Items).Error; err != nil {
+			return nil, err
+		}
+
+		event, err := RecordKitchenEvent(tx, KitchenEventTicketUpdated, ticket)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// BumpKitchenTicket moves the given items of a ticket (or all of them when itemIds is empty)
+// to a new status, recomputes the ticket status and advances the order to match the kitchen.
+// The order row is locked before the ticket so the lock order matches order status changes.
+func BumpKitchenTicket(tx *gorm.DB, ticketId string, itemIds []string, status, actorUid string) (models.KitchenTicket, []models.KitchenEvent, error) {
+	var ticket models.KitchenTicket
+	if err := tx.Where("ticket_id = ?", ticketId).First(&ticket).Error; err != nil {
+		return ticket, nil, err
+	}
+
+	if _, err := LockOrder(tx, ticket.OrderID); err != nil {
+		return ticket, nil, err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("ticket_id = ?", ticketId).First(&ticket).Error; err != nil {
+		return ticket, nil, err
+	}
+
+	query := tx.Where("ticket_id = ?", ticketId)
+	if len(itemIds) > 0 {
+		query = query.Where("ticket_item_id IN ?", itemIds)
+	}
+
+	var items []models.KitchenTicketItem
+	if err := query.Find(&items).Error; err != nil {
+		return ticket, nil, err
+	}
+	if len(items) == 0 || (len(itemIds) > 0 && len(items) != len(itemIds)) {
+		return ticket, nil, gorm.ErrRecordNotFound
+	}
+
+	for _, item := range items {
+		// Bumping a whole ticket skips items that are already at the requested status
+		if len(itemIds) == 0 && item.Status == status {
+			continue
+		}
+		if !canBumpTicketItem(item.Status, status) {
+			return ticket, nil, ErrInvalidTicketStatus
+		}
+		if err := tx.Model(&item).Update("status", status).Error; err != nil {
+			return ticket, nil, err
+		}
+	}
+
+	if err := tx.Where("ticket_id = ?", ticketId).Order("id ASC").Find(&ticket.Items).Error; err != nil {
+		return ticket, nil, err
+	}
+
+	ticket.Status = summarizeTicketStatus(ticket.Items)
+	if err := tx.Model(&ticket).Update("status", ticket.Status).Error; err != nil {
+		return ticket, nil, err
+	}
+
+	event, err := RecordKitchenEvent(tx, KitchenEventTicketUpdated, &ticket)
+	if err != nil {
+		return ticket, nil, err
+	}
+
+	if err := syncOrderWithKitchen(tx, ticket.OrderID, actorUid); err != nil {
+		return ticket, nil, err
+	}
+
+	return ticket, []models.KitchenEvent{event}, nil
+}
+
+// RecordKitchenEvent persists a snapshot of a ticket to the kitchen feed
+func RecordKitchenEvent(tx *gorm.DB, eventType string, ticket *models.KitchenTicket) (models.KitchenEvent, error) {
+	payload, err := json.Marshal(ticket)
+	if err != nil {
+		return models.KitchenEvent{}, err
+	}
+
+	event := models.KitchenEvent{
+		EventType: eventType,
+		TicketID:  ticket.TicketID,
+		StationID: ticket.StationID,
+		Payload:   string(payload),
+	}
+	err = tx.Create(&event).Error
+	return event, err
+}
+
+// PublishKitchenEvents tells connected kitchen screens that kitchen events have committed. The
+// screens read them back from the feed in commit order, so a live event is only a wake-up.
+func PublishKitchenEvents(events []models.KitchenEvent) {
+	for _, event := range events {
+		KitchenBroker.Publish(KitchenEventToBrokerEvent(event))
+	}
+}
+
+// PositionKitchenEvents gives committed kitchen events that have no feed position yet the next
+// positions, in sequence order. Sequence numbers are taken when an event is recorded, so events
+// can commit out of sequence order; positions are only given to events that have committed, under
+// an advisory lock, so a screen that has seen a position has already been able to see every
+// position before it.
+func PositionKitchenEvents(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", kitchenFeedLock).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+			UPDATE kitchen_events SET position = pending.position
+			FROM (
+				SELECT sequence,
+					(SELECT COALESCE(MAX(position), 0) FROM kitchen_events) + ROW_NUMBER() OVER (ORDER BY sequence) AS position
+				FROM kitchen_events
+				WHERE position IS NULL
+			) AS pending
+			WHERE kitchen_events.sequence = pending.sequence`).Error
+	})
+}
+
+// KitchenEventToBrokerEvent converts a stored kitchen event into a feed event identified by its
+// feed position, which is zero until the event has been positioned
+func KitchenEventToBrokerEvent(event models.KitchenEvent) Event {
+	var position uint64
+	if event.Position != nil {
+		position = *event.Position
+	}
+
+	return Event{
+		Sequence: position,
+		Type:     event.EventType,
+		Key:      event.StationID,
+		Data:     json.RawMessage(event.Payload),
+	}
+}
+
+func canBumpTicketItem(from, to string) bool {
+	for _, next := range ticketTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// summarizeTicketStatus derives a ticket's status from the statuses of its items
+func summarizeTicketStatus(items []models.KitchenTicketItem) string {
+	allDone, started, active := true, false, false
+	for _, item := range items {
+		switch item.Status {
+		case models.TicketStatusCancelled:
+			continue
+		case models.TicketStatusReady:
+			started = true
+		case models.TicketStatusInProgress:
+			started = true
+			allDone = false
+		default:
+			allDone = false
+		}
+		active = true
+	}
+
+	switch {
+	case !active:
+		return models.TicketStatusCancelled
+	case allDone:
+		return models.TicketStatusReady
+	case started:
+		return models.TicketStatusInProgress
+	default:
+		return models.TicketStatusQueued
+	}
+}
+
+// syncOrderWithKitchen moves an order to preparing once any station starts on it, and to ready
+// only once every station has finished its ticket
+func syncOrderWithKitchen(tx *gorm.DB, orderId, actorUid string) error {
+	order, err := LockOrder(tx, orderId)
+	if err != nil {
+		return err
+	}
+
+	var tickets []models.KitchenTicket
+	if err := tx.Where("order_id = ? AND status <> ?", orderId, models.TicketStatusCancelled).Find(&tickets).Error; err != nil {
+		return err
+	}
+
+	allReady, started := len(tickets) > 0, false
+	for _, ticket := range tickets {
+		if ticket.Status != models.TicketStatusReady {
+			allReady = false
+		}
+		if ticket.Status != models.TicketStatusQueued {
+			started = true
+		}
+	}
+
+	if started && order.OrderStatus == models.OrderStatusAccepted {
+		if err := TransitionOrder(tx, &order, models.OrderStatusPreparing, actorUid, "kitchen started preparing"); err != nil {
+			return err
+		}
+	}
+	if allReady && order.OrderStatus == models.OrderStatusPreparing {
+		if err := TransitionOrder(tx, &order, models.OrderStatusReady, actorUid, "all kitchen tickets ready"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package helpers
+
+import (
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LockOrder loads an order with SELECT ... FOR UPDATE, holding the row lock until the transaction ends
+func LockOrder(tx *gorm.DB, orderId string) (models.Order, error) {
+	var order models.Order
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderId).First(&order).Error
+	return order, err
+}
+
+// LockTable loads a table with SELECT ... FOR UPDATE, serialising concurrent attempts to seat it
+func LockTable(tx *gorm.DB, tableId string) (models.Table, error) {
+	var table models.Table
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("table_id = ?", tableId).First(&table).Error
+	return table, err
+}
+
+// LockInvoice loads an invoice with SELECT ... FOR UPDATE
+func LockInvoice(tx *gorm.DB, invoiceId string) (models.Invoice, error) {
+	var invoice models.Invoice
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("invoice_id = ?", invoiceId).First(&invoice).Error
+	return invoice, err
+}
+
+// LockReservation loads a reservation with SELECT ... FOR UPDATE
+func LockReservation(tx *gorm.DB, reservationId string) (models.Reservation, error) {
+	var reservation models.Reservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("reservation_id = ?", reservationId).First(&reservation).Error
+	return reservation, err
+}
+
+// LockWaitlistEntry loads a waitlist entry with SELECT ... FOR UPDATE
+func LockWaitlistEntry(tx *gorm.DB, waitlistId string) (models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("waitlist_id = ?", waitlistId).First(&entry).Error
+	return entry, err
+}
+
+// LockPayment loads a payment of an invoice with SELECT ... FOR UPDATE
+func LockPayment(tx *gorm.DB, invoiceId, paymentId string) (models.Payment, error) {
+	var payment models.Payment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("invoice_id = ? AND payment_id = ?", invoiceId, paymentId).First(&payment).Error
+	return payment, err
+}
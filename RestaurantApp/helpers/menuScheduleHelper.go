@@ -0,0 +1,141 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// scheduleTimeLayout is the format of menu schedule start and end times
+const scheduleTimeLayout = "15:04"
+
+// activeAtLayout is accepted for active_at values without a timezone, read in the restaurant's timezone
+const activeAtLayout = "2006-01-02T15:04"
+
+var (
+	restaurantLocationMu sync.RWMutex
+	restaurantLocation   = time.Local
+)
+
+// ErrInvalidActiveAt is returned when the active_at query parameter cannot be parsed
+var ErrInvalidActiveAt = errors.New("active_at must be \"now\", an RFC 3339 timestamp or YYYY-MM-DDTHH:MM")
+
+// ConfigureTimezone sets the IANA timezone, e.g. "Africa/Addis_Ababa", in which menu schedules
+// and report dates are evaluated. An empty name keeps the server's local timezone.
+func ConfigureTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return err
+	}
+
+	restaurantLocationMu.Lock()
+	defer restaurantLocationMu.Unlock()
+	restaurantLocation = location
+	return nil
+}
+
+// RestaurantLocation returns the restaurant's timezone
+func RestaurantLocation() *time.Location {
+	restaurantLocationMu.RLock()
+	defer restaurantLocationMu.RUnlock()
+	return restaurantLocation
+}
+
+// GetActiveAt reads the optional active_at filter from the query string. It returns nil when the
+// filter is not set.
+func GetActiveAt(c *gin.Context) (*time.Time, error) {
+	value := c.Query("active_at")
+	if value == "" {
+		return nil, nil
+	}
+
+	if strings.EqualFold(value, "now") {
+		now := time.Now()
+		return &now, nil
+	}
+
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return &at, nil
+	}
+
+	at, err := time.ParseInLocation(activeAtLayout, value, RestaurantLocation())
+	if err != nil {
+		return nil, ErrInvalidActiveAt
+	}
+	return &at, nil
+}
+
+// ValidateMenuSchedule checks a schedule's day and times, normalising the times to "HH:MM"
+func ValidateMenuSchedule(schedule *models.MenuSchedule) error {
+	if schedule.DayOfWeek < 0 || schedule.DayOfWeek > 6 {
+		return NewRequestError(http.StatusBadRequest, "day_of_week must be between 0 (Sunday) and 6 (Saturday)")
+	}
+
+	start, err := time.Parse(scheduleTimeLayout, schedule.StartTime)
+	if err != nil {
+		return NewRequestError(http.StatusBadRequest, "start_time must be a time of day in HH:MM format")
+	}
+
+	end, err := time.Parse(scheduleTimeLayout, schedule.EndTime)
+	if err != nil {
+		return NewRequestError(http.StatusBadRequest, "end_time must be a time of day in HH:MM format")
+	}
+
+	if start.Equal(end) {
+		return NewRequestError(http.StatusBadRequest, "A schedule's start and end times must differ")
+	}
+
+	schedule.StartTime = start.Format(scheduleTimeLayout)
+	schedule.EndTime = end.Format(scheduleTimeLayout)
+	return nil
+}
+
+// ActiveMenuIDs returns a subquery selecting the menus being served at the given time: inside
+// their date window and, if they have schedules, inside one of them. A menu is served for the
+// whole of its end date. Windows running past midnight are matched from the previous day's schedule.
+func ActiveMenuIDs(db *gorm.DB, at time.Time) *gorm.DB {
+	local := at.In(RestaurantLocation())
+	day := int(local.Weekday())
+	previousDay := (day + 6) % 7
+	timeOfDay := local.Format(scheduleTimeLayout)
+
+	return db.Session(&gorm.Session{NewDB: true}).Model(&models.Menu{}).Select("menus.menu_id").
+		Where("(menus.start_date IS NULL OR menus.start_date <= ?) AND (menus.end_date IS NULL OR menus.end_date::date >= ?::date)",
+			at, local.Format("2006-01-02")).
+		Where(`(NOT EXISTS (SELECT 1 FROM menu_schedules s WHERE s.menu_id = menus.menu_id)
+			OR EXISTS (SELECT 1 FROM menu_schedules s WHERE s.menu_id = menus.menu_id AND (
+				(s.day_of_week = ? AND s.start_time <= ? AND (s.end_time > ? OR s.end_time <= s.start_time))
+				OR (s.day_of_week = ? AND s.end_time <= s.start_time AND s.end_time > ?))))`,
+			day, timeOfDay, timeOfDay, previousDay, timeOfDay)
+}
+
+// CheckFoodMenuActive rejects ordering a food whose menu is not being served at the given time
+func CheckFoodMenuActive(tx *gorm.DB, food *models.Food, at time.Time) error {
+	var active int64
+	if err := tx.Model(&models.Menu{}).
+		Where("menu_id = ? AND menu_id IN (?)", food.MenuID, ActiveMenuIDs(tx, at)).
+		Count(&active).Error; err != nil {
+		return err
+	}
+
+	if active == 0 {
+		var menu models.Menu
+		name := "its menu"
+		if err := tx.Where("menu_id = ?", food.MenuID).First(&menu).Error; err == nil {
+			name = fmt.Sprintf("the %q menu", menu.Name)
+		}
+		return NewRequestError(http.StatusBadRequest, fmt.Sprintf("%q cannot be ordered right now because %s is not being served", food.Name, name))
+	}
+	return nil
+}
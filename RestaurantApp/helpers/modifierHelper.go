@@ -0,0 +1,148 @@
+package helpers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+)
+
+// ResolveModifiers validates the modifiers selected on an order item against the food's modifier
+// groups and returns them with their names and price deltas captured. Selection errors are
+// returned as a RequestError so they reach the client unchanged.
+func ResolveModifiers(tx *gorm.DB, foodId string, selections []models.OrderItemModifier) ([]models.OrderItemModifier, error) {
+	var groups []models.ModifierGroup
+	if err := tx.Preload("Modifiers").Where("food_id = ?", foodId).Order("id ASC").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	type option struct {
+		group    *models.ModifierGroup
+		modifier models.Modifier
+	}
+	options := make(map[string]option)
+	for i := range groups {
+		for _, modifier := range groups[i].Modifiers {
+			options[modifier.ModifierID] = option{group: &groups[i], modifier: modifier}
+		}
+	}
+
+	resolved := make([]models.OrderItemModifier, 0, len(selections))
+	counts := make(map[string]int)
+	for _, selection := range selections {
+		chosen, ok := options[selection.ModifierID]
+		if !ok {
+			return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Modifier %q is not available for this food item", selection.ModifierID))
+		}
+
+		if !chosen.modifier.AvailableAt(time.Now()) {
+			return nil, UnavailableError(chosen.modifier.Name, chosen.modifier.UnavailableUntil)
+		}
+
+		if counts[chosen.modifier.ModifierID] > 0 {
+			return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Modifier %q was selected more than once", chosen.modifier.Name))
+		}
+		counts[chosen.modifier.ModifierID]++
+		counts[chosen.group.GroupID]++
+
+		resolved = append(resolved, models.OrderItemModifier{
+			GroupID:    chosen.group.GroupID,
+			GroupName:  chosen.group.Name,
+			ModifierID: chosen.modifier.ModifierID,
+			Name:       chosen.modifier.Name,
+			PriceDelta: chosen.modifier.PriceDelta,
+		})
+	}
+
+	for _, group := range groups {
+		selected := counts[group.GroupID]
+		if minimum := group.MinimumSelections(); selected < minimum {
+			return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Please choose at least %d option(s) for %q", minimum, group.Name))
+		}
+		if group.MaxSelections > 0 && selected > group.MaxSelections {
+			return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Please choose at most %d option(s) for %q", group.MaxSelections, group.Name))
+		}
+	}
+
+	// Keep a stable order so identical selections produce the same modifier key
+	sort.Slice(resolved, func(i, j int) bool {
+		if resolved[i].GroupID != resolved[j].GroupID {
+			return resolved[i].GroupID < resolved[j].GroupID
+		}
+		return resolved[i].ModifierID < resolved[j].ModifierID
+	})
+	return resolved, nil
+}
+
+// ReselectOrderItemModifiers changes the modifiers selected on an order item. The base or variant
+// price captured when the item was ordered is kept, as are the price deltas of modifiers that stay
+// selected; only newly chosen modifiers are priced at their current delta.
+func ReselectOrderItemModifiers(tx *gorm.DB, orderItem *models.OrderItem, selections []models.OrderItemModifier) error {
+	var previous []models.OrderItemModifier
+	if err := tx.Where("order_item_id = ?", orderItem.OrderItemID).Find(&previous).Error; err != nil {
+		return err
+	}
+
+	modifiers, err := ResolveModifiers(tx, orderItem.FoodID, selections)
+	if err != nil {
+		return err
+	}
+
+	capturedDeltas := make(map[string]models.Money, len(previous))
+	unitPrice := orderItem.UnitPrice
+	for _, modifier := range previous {
+		capturedDeltas[modifier.ModifierID] = modifier.PriceDelta
+		unitPrice = unitPrice.Sub(modifier.PriceDelta)
+	}
+	for i := range modifiers {
+		if delta, ok := capturedDeltas[modifiers[i].ModifierID]; ok {
+			modifiers[i].PriceDelta = delta
+		}
+		unitPrice = unitPrice.Add(modifiers[i].PriceDelta)
+	}
+
+	orderItem.UnitPrice = unitPrice
+	orderItem.Modifiers = modifiers
+	orderItem.ModifierKey = ModifierKey(modifiers)
+	return ReplaceOrderItemModifiers(tx, orderItem)
+}
+
+// ModifierKey identifies a set of resolved modifiers, so items with the same food and
+// the same selection can be merged into one line
+func ModifierKey(modifiers []models.OrderItemModifier) string {
+	ids := make([]string, 0, len(modifiers))
+	for _, modifier := range modifiers {
+		ids = append(ids, modifier.ModifierID)
+	}
+	return strings.Join(ids, ",")
+}
+
+// ModifierSummary renders the selected modifiers for a kitchen ticket, e.g. "Medium rare, No onions"
+func ModifierSummary(modifiers []models.OrderItemModifier) string {
+	names := make([]string, 0, len(modifiers))
+	for _, modifier := range modifiers {
+		names = append(names, modifier.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// ReplaceOrderItemModifiers swaps the stored modifiers of an order item for its current selection
+func ReplaceOrderItemModifiers(tx *gorm.DB, orderItem *models.OrderItem) error {
+	if err := tx.Where("order_item_id = ?", orderItem.OrderItemID).Delete(&models.OrderItemModifier{}).Error; err != nil {
+		return err
+	}
+
+	if len(orderItem.Modifiers) == 0 {
+		return nil
+	}
+
+	for i := range orderItem.Modifiers {
+		orderItem.Modifiers[i].ID = 0
+		orderItem.Modifiers[i].OrderItemID = orderItem.OrderItemID
+	}
+	return tx.Create(&orderItem.Modifiers).Error
+}
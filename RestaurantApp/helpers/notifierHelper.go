@@ -0,0 +1,102 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// notificationTimeout bounds how long delivering one notification may take
+const notificationTimeout = 10 * time.Second
+
+// Notification is a message for a guest, addressed to a phone number or other contact
+type Notification struct {
+	To      string `json:"to"`
+	Message string `json:"message"`
+}
+
+// Notifier delivers notifications to guests, e.g. by SMS. Implementations must be safe for concurrent use.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// LogNotifier writes notifications to the server log. It is used until another notifier is configured.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, notification Notification) error {
+	log.Printf("notification to %s: %s", notification.To, notification.Message)
+	return nil
+}
+
+// WebhookNotifier posts each notification as JSON to a URL, such as an SMS gateway
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: &http.Client{Timeout: notificationTimeout}}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := n.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("notification webhook returned %s", response.Status)
+	}
+	return nil
+}
+
+var (
+	notifierMu sync.RWMutex
+	notifier   Notifier = LogNotifier{}
+)
+
+// SetNotifier replaces the notifier used to contact guests
+func SetNotifier(n Notifier) {
+	notifierMu.Lock()
+	defer notifierMu.Unlock()
+	notifier = n
+}
+
+// SendNotifications delivers notifications in the background so a slow provider never holds up a
+// request. Failures are logged; staff can still call the guest from the waitlist screen.
+func SendNotifications(notifications []Notification) {
+	if len(notifications) == 0 {
+		return
+	}
+
+	notifierMu.RLock()
+	n := notifier
+	notifierMu.RUnlock()
+
+	go func() {
+		for _, notification := range notifications {
+			ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+			if err := n.Notify(ctx, notification); err != nil {
+				log.Printf("failed to notify %s: %v", notification.To, err)
+			}
+			cancel()
+		}
+	}()
+}
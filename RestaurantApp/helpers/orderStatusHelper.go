@@ -0,0 +1,97 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidOrderTransition is returned when an order status change is not allowed by the lifecycle
+var ErrInvalidOrderTransition = errors.New("invalid order status transition")
+
+// orderTransitions maps each order status to the statuses it may move to next
+var orderTransitions = map[string][]string{
+	models.OrderStatusDraft:     {models.OrderStatusPending, models.OrderStatusCancelled},
+	models.OrderStatusPending:   {models.OrderStatusAccepted, models.OrderStatusCancelled},
+	models.OrderStatusAccepted:  {models.OrderStatusPreparing, models.OrderStatusCancelled},
+	models.OrderStatusPreparing: {models.OrderStatusReady, models.OrderStatusVoided},
+	models.OrderStatusReady:     {models.OrderStatusServed, models.OrderStatusPreparing, models.OrderStatusVoided},
+	models.OrderStatusServed:    {models.OrderStatusInvoiced, models.OrderStatusVoided},
+	models.OrderStatusInvoiced:  {models.OrderStatusCompleted, models.OrderStatusVoided, models.OrderStatusServed},
+	models.OrderStatusCompleted: {},
+	models.OrderStatusCancelled: {},
+	models.OrderStatusVoided:    {},
+}
+
+// IsValidOrderStatus reports whether status is part of the order lifecycle
+func IsValidOrderStatus(status string) bool {
+	_, ok := orderTransitions[status]
+	return ok
+}
+
+// CanTransitionOrder reports whether an order may move from one status to another
+func CanTransitionOrder(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOrderStatuses returns the statuses an order in the given status may move to
+func NextOrderStatuses(status string) []string {
+	return orderTransitions[status]
+}
+
+// IsOrderEditable reports whether items can still be added to, changed or removed from an order
+func IsOrderEditable(status string) bool {
+	return status == models.OrderStatusDraft || status == models.OrderStatusPending
+}
+
+// RecordOrderCreated writes the initial status history entry for a newly created order
+func RecordOrderCreated(tx *gorm.DB, order *models.Order, actorUid string) error {
+	history := models.OrderStatusHistory{
+		OrderID:  order.OrderID,
+		ToStatus: order.OrderStatus,
+		ActorUID: actorUid,
+		Reason:   "order created",
+	}
+	return tx.Create(&history).Error
+}
+
+// TransitionOrder moves an order to a new status and records the change in the status history.
+// It must be called inside a transaction so the status and its history are written together.
+func TransitionOrder(tx *gorm.DB, order *models.Order, to, actorUid, reason string) error {
+	from := order.OrderStatus
+	if !CanTransitionOrder(from, to) {
+		return fmt.Errorf("%w: cannot move order from %q to %q", ErrInvalidOrderTransition, from, to)
+	}
+
+	// Guard against a concurrent transition having changed the status since the order was read
+	result := tx.Model(&models.Order{}).
+		Where("order_id = ? AND order_status = ?", order.OrderID, from).
+		Update("order_status", to)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: order status changed concurrently", ErrInvalidOrderTransition)
+	}
+
+	history := models.OrderStatusHistory{
+		OrderID:    order.OrderID,
+		FromStatus: from,
+		ToStatus:   to,
+		ActorUID:   actorUid,
+		Reason:     reason,
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return err
+	}
+
+	order.OrderStatus = to
+	return nil
+}
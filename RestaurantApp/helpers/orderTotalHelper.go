@@ -0,0 +1,118 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+)
+
+// ErrCurrencyMismatch is returned when a food is priced in a different currency than the order
+var ErrCurrencyMismatch = errors.New("food is priced in a different currency than the order")
+
+// SnapshotOrderItem copies the current name and price of the item's food onto the order item,
+// so later menu price changes do not affect orders that have already been placed. Foods that
+// have been marked unavailable, have run out of ingredients or whose menu is not being served are rejected. A food with
+// variants is priced by the selected variant, and the selected modifiers are validated and
+// their price deltas are included in the unit price.
+func SnapshotOrderItem(tx *gorm.DB, orderItem *models.OrderItem, currency string) error {
+	var food models.Food
+	if err := tx.Preload("Variants").Where("food_id = ?", orderItem.FoodID).First(&food).Error; err != nil {
+		return err
+	}
+
+	if currency != "" && food.Currency != "" && food.Currency != currency {
+		return ErrCurrencyMismatch
+	}
+
+	now := time.Now()
+	if !food.AvailableAt(now) {
+		return FoodUnavailableError(&food)
+	}
+
+	if err := CheckFoodMenuActive(tx, &food, now); err != nil {
+		return err
+	}
+
+	variant, err := resolveVariant(&food, orderItem.VariantID)
+	if err != nil {
+		return err
+	}
+
+	modifiers, err := ResolveModifiers(tx, food.FoodID, orderItem.Modifiers)
+	if err != nil {
+		return err
+	}
+
+	unitPrice := food.Price
+	orderItem.VariantID, orderItem.VariantName, orderItem.SKU = "", "", ""
+	if variant != nil {
+		unitPrice = variant.Price
+		orderItem.VariantID = variant.VariantID
+		orderItem.VariantName = variant.Name
+		orderItem.SKU = variant.SKU
+	}
+	for _, modifier := range modifiers {
+		unitPrice = unitPrice.Add(modifier.PriceDelta)
+	}
+
+	orderItem.FoodName = food.Name
+	orderItem.UnitPrice = unitPrice
+	orderItem.Modifiers = modifiers
+	orderItem.ModifierKey = ModifierKey(modifiers)
+	lineTotal, err := LineTotal(orderItem.UnitPrice, orderItem.Quantity)
+	if err != nil {
+		return err
+	}
+	orderItem.LineTotal = lineTotal
+	return nil
+}
+
+// LineTotal prices a quantity of items at a unit price, rejecting quantities whose total is too large to hold
+func LineTotal(unitPrice models.Money, quantity int) (models.Money, error) {
+	total, err := unitPrice.Mul(int64(quantity))
+	if errors.Is(err, models.ErrMoneyOverflow) {
+		return 0, NewRequestError(http.StatusBadRequest, "The quantity is too large for this item")
+	}
+	return total, err
+}
+
+// resolveVariant finds the selected variant of a food. Foods sold in variants require one to be
+// chosen; foods without variants reject a variant selection.
+func resolveVariant(food *models.Food, variantId string) (*models.FoodVariant, error) {
+	if len(food.Variants) == 0 {
+		if variantId != "" {
+			return nil, NewRequestError(http.StatusBadRequest, "This food item is not sold in variants")
+		}
+		return nil, nil
+	}
+
+	if variantId == "" {
+		return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Please choose a variant of %q", food.Name))
+	}
+
+	for i := range food.Variants {
+		if food.Variants[i].VariantID == variantId {
+			return &food.Variants[i], nil
+		}
+	}
+	return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Variant %q is not available for this food item", variantId))
+}
+
+// RecalculateOrderTotal sums the line totals captured on an order's items and stores the result on the order
+func RecalculateOrderTotal(tx *gorm.DB, orderId string) error {
+	var totalAmount models.Money
+	if err := tx.Model(&models.OrderItem{}).
+		Select("COALESCE(SUM(line_total), 0)").
+		Where("order_id = ?", orderId).
+		Scan(&totalAmount).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Order{}).
+		Where("order_id = ?", orderId).
+		Update("order_total", totalAmount).Error
+}
@@ -0,0 +1,593 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// paymentEventTolerance is how far a payment event's timestamp may be from now for it to be accepted
+const paymentEventTolerance = 5 * time.Minute
+
+// ErrDuplicatePaymentEvent is returned when a payment event has already been applied
+var ErrDuplicatePaymentEvent = errors.New("payment event has already been processed")
+
+// paymentTransitions maps each payment status to the statuses it may move to next
+var paymentTransitions = map[string][]string{
+	models.PaymentStatusPending:    {models.PaymentStatusAuthorized, models.PaymentStatusCompleted, models.PaymentStatusDeclined, models.PaymentStatusVoided},
+	models.PaymentStatusAuthorized: {models.PaymentStatusCompleted, models.PaymentStatusDeclined, models.PaymentStatusVoided},
+	models.PaymentStatusCompleted:  {models.PaymentStatusVoided, models.PaymentStatusRefunded},
+	models.PaymentStatusDeclined:   {},
+	models.PaymentStatusVoided:     {},
+	models.PaymentStatusRefunded:   {},
+}
+
+// paymentEventStatuses maps the payment webhook event types to the payment status each reports
+var paymentEventStatuses = map[string]string{
+	"payment.authorized": models.PaymentStatusAuthorized,
+	"payment.captured":   models.PaymentStatusCompleted,
+	"payment.declined":   models.PaymentStatusDeclined,
+	"payment.voided":     models.PaymentStatusVoided,
+	"payment.refunded":   models.PaymentStatusRefunded,
+}
+
+// CanTransitionPayment reports whether a payment may move from one status to another
+func CanTransitionPayment(from, to string) bool {
+	for _, next := range paymentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidPaymentMethod reports whether method is one of the payment methods
+func IsValidPaymentMethod(method string) bool {
+	for _, known := range models.PaymentMethods {
+		if known == method {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyInvoicePayments recomputes an invoice's amount paid, net of refunds, its credited amount and
+// its balance from its payments and credit notes, and saves them with the payment status and method
+// that follow. An invoice is paid once nothing is left to pay, and refunded once credit notes cover
+// its whole total; one paid with several methods is recorded as mixed.
+func ApplyInvoicePayments(tx *gorm.DB, invoice *models.Invoice) error {
+	var payments []models.Payment
+	if err := tx.Where("invoice_id = ? AND status IN ?", invoice.InvoiceID,
+		[]string{models.PaymentStatusCompleted, models.PaymentStatusRefunded}).
+		Find(&payments).Error; err != nil {
+		return err
+	}
+
+	var credited models.Money
+	if err := tx.Model(&models.CreditNote{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("invoice_id = ?", invoice.InvoiceID).
+		Scan(&credited).Error; err != nil {
+		return err
+	}
+
+	paid := models.Money(0)
+	methods := make(map[string]bool)
+	for _, payment := range payments {
+		paid = paid.Add(payment.Amount.Sub(payment.RefundedAmount))
+		methods[payment.Method] = true
+	}
+
+	invoice.AmountPaid = paid
+	invoice.CreditedAmount = credited
+	invoice.BalanceDue = invoice.TotalAmount.Sub(credited).Sub(paid)
+	owing := !invoice.BalanceDue.IsNegative() && !invoice.BalanceDue.IsZero()
+	switch {
+	case !credited.IsZero() && credited == invoice.TotalAmount:
+		invoice.PaymentStatus = models.InvoiceStatusRefunded
+	case !owing:
+		invoice.PaymentStatus = models.InvoiceStatusPaid
+	case paid.IsZero():
+		invoice.PaymentStatus = models.InvoiceStatusPending
+	default:
+		invoice.PaymentStatus = models.InvoiceStatusPartiallyPaid
+	}
+
+	if len(methods) > 1 {
+		invoice.PaymentMethod = models.InvoicePaymentMethodMixed
+	} else if len(payments) == 1 {
+		invoice.PaymentMethod = payments[0].Method
+	}
+
+	return tx.Model(invoice).
+		Select("amount_paid", "credited_amount", "balance_due", "payment_status", "payment_method").
+		Updates(invoice).Error
+}
+
+// checkPaymentAmount rounds a payment's amount to the invoice currency, defaulting it to what is
+// left to pay, and checks it is positive and does not exceed the balance due less the payments
+// still in flight with the payment provider
+func checkPaymentAmount(tx *gorm.DB, invoice *models.Invoice, payment *models.Payment) error {
+	if invoice.PaymentStatus == models.InvoiceStatusPaid || invoice.PaymentStatus == models.InvoiceStatusRefunded {
+		return NewRequestError(http.StatusConflict, "This invoice has already been paid")
+	}
+
+	var inFlight models.Money
+	if err := tx.Model(&models.Payment{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("invoice_id = ? AND status IN ?", invoice.InvoiceID, models.InFlightPaymentStatuses).
+		Scan(&inFlight).Error; err != nil {
+		return err
+	}
+	available := invoice.BalanceDue.Sub(inFlight)
+
+	if payment.Amount.IsZero() {
+		payment.Amount = available
+	}
+	payment.Amount = payment.Amount.Round(invoice.Currency)
+	if payment.Amount.IsNegative() || payment.Amount.IsZero() {
+		return NewRequestError(http.StatusBadRequest, "The payment amount must be positive")
+	}
+	if available.Sub(payment.Amount).IsNegative() {
+		return NewRequestError(http.StatusBadRequest, fmt.Sprintf("The payment amount exceeds the %s left to pay", available))
+	}
+	return nil
+}
+
+// RecordPayment takes a payment towards an invoice's balance and updates the invoice. Without an
+// amount the whole balance is paid. Only cash can be tendered above the amount, with the rest
+// given back as change.
+func RecordPayment(tx *gorm.DB, invoice *models.Invoice, payment *models.Payment) error {
+	if !IsValidPaymentMethod(payment.Method) {
+		return NewRequestError(http.StatusBadRequest, "Unknown payment method")
+	}
+	if err := checkPaymentAmount(tx, invoice, payment); err != nil {
+		return err
+	}
+
+	if payment.Tendered.IsZero() {
+		payment.Tendered = payment.Amount
+	}
+	payment.Change = payment.Tendered.Sub(payment.Amount)
+	if payment.Change.IsNegative() {
+		return NewRequestError(http.StatusBadRequest, "The amount tendered is less than the payment amount")
+	}
+	if !payment.Change.IsZero() && payment.Method != models.PaymentMethodCash {
+		return NewRequestError(http.StatusBadRequest, "Only cash payments can be tendered above the payment amount")
+	}
+
+	now := time.Now()
+	if payment.PaidAt.IsZero() {
+		payment.PaidAt = now
+	}
+	if payment.PaidAt.After(now) {
+		return NewRequestError(http.StatusBadRequest, "A payment cannot be dated in the future")
+	}
+
+	payment.InvoiceID = invoice.InvoiceID
+	payment.Currency = invoice.Currency
+	payment.Status = models.PaymentStatusCompleted
+	payment.VoidedAt, payment.VoidedBy, payment.VoidReason = nil, "", ""
+	if err := tx.Create(payment).Error; err != nil {
+		return err
+	}
+
+	return ApplyInvoicePayments(tx, invoice)
+}
+
+// providerSettleTimeout bounds how long recording a payment provider's answer may take
+const providerSettleTimeout = 10 * time.Second
+
+// voidIdempotencyKey is the idempotency key a payment is voided, or refunded in full to void it, with
+func voidIdempotencyKey(paymentId string) string {
+	return paymentId + ":void"
+}
+
+// settleDB returns db with a context that outlives the request's deadline, so the answer of a slow
+// payment provider is still recorded
+func settleDB(ctx context.Context, db *gorm.DB) (*gorm.DB, context.CancelFunc) {
+	settleCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), providerSettleTimeout)
+	return db.WithContext(settleCtx), cancel
+}
+
+// AuthorizeProviderPayment takes a card-present or online payment through the payment provider.
+// The attempt is committed as pending before the provider is called, so it is on the ledger and
+// holds its amount against the balance whatever happens next, and the invoice is not locked while
+// the provider answers. The payment then follows the provider's answer: declined payments stay on
+// the ledger for reference, approved ones are authorized and, when capture is set, captured straight
+// away. A payment the provider answers asynchronously, or whose call failed, stays pending until the
+// payment webhook reports its outcome or it is voided.
+func AuthorizeProviderPayment(ctx context.Context, db *gorm.DB, invoiceId string, payment *models.Payment, token string, capture bool) (models.Invoice, error) {
+	if payment.Channel != models.PaymentChannelCardPresent && payment.Channel != models.PaymentChannelOnline {
+		return models.Invoice{}, NewRequestError(http.StatusBadRequest, "The payment channel must be card_present or online")
+	}
+	if payment.Method == "" {
+		payment.Method = models.PaymentMethodCard
+	}
+	if payment.Method != models.PaymentMethodCard && payment.Method != models.PaymentMethodMobile {
+		return models.Invoice{}, NewRequestError(http.StatusBadRequest, "Only card and mobile payments can be taken through the payment provider")
+	}
+
+	provider := CurrentPaymentProvider()
+	var invoice models.Invoice
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if invoice, err = LockInvoice(tx, invoiceId); err != nil {
+			return NewRequestError(http.StatusNotFound, "The requested invoice could not be found")
+		}
+		if err := checkPaymentAmount(tx, &invoice, payment); err != nil {
+			return err
+		}
+
+		payment.InvoiceID = invoice.InvoiceID
+		payment.Currency = invoice.Currency
+		payment.Tendered, payment.Change = payment.Amount, 0
+		payment.Status = models.PaymentStatusPending
+		payment.Provider = provider.Name()
+		payment.PaidAt = time.Now()
+		return tx.Create(payment).Error
+	})
+	if err != nil {
+		return invoice, err
+	}
+
+	result, err := provider.Authorize(ctx, ProviderPaymentRequest{
+		PaymentID:      payment.PaymentID,
+		InvoiceID:      invoice.InvoiceID,
+		Amount:         payment.Amount,
+		Currency:       payment.Currency,
+		Channel:        payment.Channel,
+		Token:          token,
+		IdempotencyKey: payment.PaymentID,
+	})
+	if err != nil {
+		return invoice, recordProviderFailure(ctx, db, payment, err)
+	}
+	if invoice, err = settleProviderResult(ctx, db, payment, result); err != nil {
+		return invoice, err
+	}
+
+	if capture && payment.Status == models.PaymentStatusAuthorized {
+		return CaptureProviderPayment(ctx, db, invoice.InvoiceID, payment)
+	}
+	return invoice, nil
+}
+
+// CaptureProviderPayment collects an authorized provider payment, which then counts towards the
+// invoice. The provider is called outside the invoice lock; capturing twice is harmless, as both
+// calls carry the same idempotency key.
+func CaptureProviderPayment(ctx context.Context, db *gorm.DB, invoiceId string, payment *models.Payment) (models.Invoice, error) {
+	var invoice models.Invoice
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if invoice, err = LockInvoice(tx, invoiceId); err != nil {
+			return NewRequestError(http.StatusNotFound, "The requested invoice could not be found")
+		}
+		if *payment, err = LockPayment(tx, invoice.InvoiceID, payment.PaymentID); err != nil {
+			return NewRequestError(http.StatusNotFound, "The payment could not be found on this invoice")
+		}
+		if payment.Provider == "" || payment.Status != models.PaymentStatusAuthorized {
+			return NewRequestError(http.StatusConflict, "Only an authorized provider payment can be captured")
+		}
+		return nil
+	})
+	if err != nil {
+		return invoice, err
+	}
+
+	result, err := CurrentPaymentProvider().Capture(ctx, payment.ProviderReference, payment.Amount, payment.Currency, payment.PaymentID+":capture")
+	if err != nil {
+		return invoice, recordProviderFailure(ctx, db, payment, err)
+	}
+	return settleProviderResult(ctx, db, payment, result)
+}
+
+// settleProviderResult records a payment provider's answer on the payment and updates the invoice.
+// If a webhook has meanwhile moved the payment past the status reported, the payment is left as it is.
+func settleProviderResult(ctx context.Context, db *gorm.DB, payment *models.Payment, result ProviderResult) (models.Invoice, error) {
+	db, cancel := settleDB(ctx, db)
+	defer cancel()
+
+	var invoice models.Invoice
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if invoice, err = LockInvoice(tx, payment.InvoiceID); err != nil {
+			return err
+		}
+		if *payment, err = LockPayment(tx, invoice.InvoiceID, payment.PaymentID); err != nil {
+			return err
+		}
+
+		if result.Status != payment.Status && !CanTransitionPayment(payment.Status, result.Status) {
+			return nil
+		}
+		if result.Reference != "" {
+			payment.ProviderReference = result.Reference
+		}
+		if err := applyProviderResult(tx, payment, result); err != nil {
+			return err
+		}
+		return ApplyInvoicePayments(tx, &invoice)
+	})
+	return invoice, err
+}
+
+// recordProviderFailure notes on a payment that the payment provider could not be reached. The
+// payment keeps its status, since the provider may still have acted on the call; retrying with the
+// same idempotency key or the payment webhook settles it.
+func recordProviderFailure(ctx context.Context, db *gorm.DB, payment *models.Payment, cause error) error {
+	db, cancel := settleDB(ctx, db)
+	defer cancel()
+
+	payment.ProviderMessage = "Provider unreachable: " + cause.Error()
+	if err := db.Model(payment).Update("provider_message", payment.ProviderMessage).Error; err != nil {
+		return err
+	}
+	return NewRequestError(http.StatusBadGateway, fmt.Sprintf("The payment provider could not be reached: %s. Payment %s stays %s until the provider confirms it.",
+		cause.Error(), payment.PaymentID, payment.Status))
+}
+
+// recordProviderRefusal notes on a payment that the payment provider did not void or refund it, so
+// the payment keeps counting towards the invoice. A pending answer is settled later by the payment
+// webhook; any other answer means the void was refused.
+func recordProviderRefusal(ctx context.Context, db *gorm.DB, payment *models.Payment, result ProviderResult) error {
+	db, cancel := settleDB(ctx, db)
+	defer cancel()
+
+	payment.ProviderMessage = result.Message
+	if err := db.Model(payment).Update("provider_message", payment.ProviderMessage).Error; err != nil {
+		return err
+	}
+	if result.Status == models.PaymentStatusPending {
+		return NewRequestError(http.StatusConflict, fmt.Sprintf("The payment provider is still processing the void of payment %s; it is voided once the provider confirms it.", payment.PaymentID))
+	}
+	return NewRequestError(http.StatusBadGateway, fmt.Sprintf("The payment provider did not void payment %s: %s. The payment stays %s.",
+		payment.PaymentID, result.Message, payment.Status))
+}
+
+// applyProviderResult moves a provider payment to the status the provider reported and saves it
+func applyProviderResult(tx *gorm.DB, payment *models.Payment, result ProviderResult) error {
+	if result.Status != payment.Status && !CanTransitionPayment(payment.Status, result.Status) {
+		return NewRequestError(http.StatusConflict, fmt.Sprintf("A %s payment cannot become %s", payment.Status, result.Status))
+	}
+
+	if result.Status == models.PaymentStatusCompleted && payment.Status != models.PaymentStatusCompleted {
+		payment.PaidAt = time.Now()
+	}
+	payment.Status = result.Status
+	payment.ProviderMessage = result.Message
+	return tx.Model(payment).
+		Select("status", "provider_reference", "provider_message", "paid_at").
+		Updates(payment).Error
+}
+
+// checkVoidable reports why a payment cannot be voided, if it cannot
+func checkVoidable(payment *models.Payment) error {
+	if !CanTransitionPayment(payment.Status, models.PaymentStatusVoided) {
+		return NewRequestError(http.StatusConflict, fmt.Sprintf("A %s payment cannot be voided", payment.Status))
+	}
+	if !payment.RefundedAmount.IsZero() {
+		return NewRequestError(http.StatusConflict, "A payment that has been partly refunded cannot be voided; refund the rest instead")
+	}
+	return nil
+}
+
+// VoidPayment cancels a payment taken in error. It stays on the invoice's ledger but no longer
+// counts towards it, so a paid invoice goes back to owing the voided amount. A provider payment is
+// voided with the provider, or refunded in full if it had already been captured; the provider is
+// called outside the invoice lock, and the void is recorded once it has answered.
+func VoidPayment(ctx context.Context, db *gorm.DB, invoiceId string, payment *models.Payment, userId, reason string) (models.Invoice, error) {
+	var invoice models.Invoice
+	void := func(tx *gorm.DB) error {
+		now := time.Now()
+		payment.Status = models.PaymentStatusVoided
+		payment.VoidedAt = &now
+		payment.VoidedBy = userId
+		payment.VoidReason = reason
+		if err := tx.Model(payment).Select("status", "voided_at", "voided_by", "void_reason").Updates(payment).Error; err != nil {
+			return err
+		}
+		return ApplyInvoicePayments(tx, &invoice)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if invoice, err = LockInvoice(tx, invoiceId); err != nil {
+			return NewRequestError(http.StatusNotFound, "The requested invoice could not be found")
+		}
+		if *payment, err = LockPayment(tx, invoice.InvoiceID, payment.PaymentID); err != nil {
+			return NewRequestError(http.StatusNotFound, "The payment could not be found on this invoice")
+		}
+		if err := checkVoidable(payment); err != nil {
+			return err
+		}
+
+		// Payments taken outside the provider are voided straight away
+		if payment.Provider == "" {
+			return void(tx)
+		}
+		return nil
+	})
+	if err != nil || payment.Provider == "" {
+		return invoice, err
+	}
+
+	provider := CurrentPaymentProvider()
+	var result ProviderResult
+	wanted := models.PaymentStatusVoided
+	if payment.Status == models.PaymentStatusCompleted {
+		wanted = models.PaymentStatusRefunded
+		result, err = provider.Refund(ctx, payment.ProviderReference, payment.Amount, payment.Currency, voidIdempotencyKey(payment.PaymentID))
+	} else {
+		result, err = provider.Void(ctx, payment.ProviderReference, voidIdempotencyKey(payment.PaymentID))
+	}
+	if err != nil {
+		return invoice, recordProviderFailure(ctx, db, payment, err)
+	}
+	if result.Status != wanted {
+		return invoice, recordProviderRefusal(ctx, db, payment, result)
+	}
+
+	settle, cancel := settleDB(ctx, db)
+	defer cancel()
+	err = settle.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if invoice, err = LockInvoice(tx, invoiceId); err != nil {
+			return err
+		}
+		if *payment, err = LockPayment(tx, invoice.InvoiceID, payment.PaymentID); err != nil {
+			return err
+		}
+		// A webhook may have recorded the void already
+		if payment.Status == models.PaymentStatusVoided {
+			return nil
+		}
+		if err := checkVoidable(payment); err != nil {
+			return err
+		}
+		return void(tx)
+	})
+	return invoice, err
+}
+
+// PaymentEvent is an asynchronous payment result posted by the payment provider to the payment
+// webhook. It names the payment by the provider's reference or, for a payment whose authorization
+// answer never arrived, by the payment id it was authorized with. Timestamp is when the event was
+// sent, in Unix seconds; it is part of the signed body, so an old event cannot be replayed. Refund
+// events carry the amount refunded and the idempotency key of the call that made the refund, if any.
+type PaymentEvent struct {
+	EventID        string       `json:"event_id"`
+	Timestamp      int64        `json:"timestamp"`
+	Type           string       `json:"type" binding:"required"`
+	Reference      string       `json:"reference"`
+	PaymentID      string       `json:"payment_id"`
+	Amount         models.Money `json:"amount"`
+	IdempotencyKey string       `json:"idempotency_key"`
+	Message        string       `json:"message"`
+}
+
+// checkPaymentEvent validates an event's type, id and timestamp, which must be within a few minutes
+// of now, and returns the payment status the event reports
+func checkPaymentEvent(event PaymentEvent, now time.Time) (string, error) {
+	status, ok := paymentEventStatuses[event.Type]
+	if !ok {
+		return "", NewRequestError(http.StatusBadRequest, fmt.Sprintf("Unknown payment event type %q", event.Type))
+	}
+	if event.EventID == "" {
+		return "", NewRequestError(http.StatusBadRequest, "The payment event has no event id")
+	}
+	sent := time.Unix(event.Timestamp, 0)
+	if event.Timestamp == 0 || now.Sub(sent) > paymentEventTolerance || sent.Sub(now) > paymentEventTolerance {
+		return "", NewRequestError(http.StatusBadRequest, "The payment event's timestamp is missing or outside the accepted window")
+	}
+	return status, nil
+}
+
+// ApplyPaymentEvent moves the provider payment an event refers to into the status it reports and
+// updates the invoice. Events sent more than a few minutes ago are refused, and each event id is
+// applied only once: a repeat returns ErrDuplicatePaymentEvent. An event the payment already
+// reflects is accepted without changes.
+func ApplyPaymentEvent(tx *gorm.DB, event PaymentEvent) (models.Payment, error) {
+	status, err := checkPaymentEvent(event, time.Now())
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	query := tx.Where("provider = ?", CurrentPaymentProvider().Name())
+	if event.Reference != "" {
+		query = query.Where("provider_reference = ?", event.Reference)
+	} else {
+		query = query.Where("payment_id = ?", event.PaymentID)
+	}
+	var found models.Payment
+	if err := query.First(&found).Error; err != nil {
+		return models.Payment{}, NewRequestError(http.StatusNotFound, "No payment matches the event's reference")
+	}
+
+	// Lock the invoice before the payment, in the same order as the payment endpoints
+	invoice, err := LockInvoice(tx, found.InvoiceID)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	payment, err := LockPayment(tx, invoice.InvoiceID, found.PaymentID)
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	// The unique event id makes a concurrent delivery of the same event wait here, then skip it
+	result := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "event_id"}}, DoNothing: true}).
+		Create(&models.ProcessedPaymentEvent{EventID: event.EventID, Type: event.Type, PaymentID: payment.PaymentID})
+	if result.Error != nil {
+		return models.Payment{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return payment, ErrDuplicatePaymentEvent
+	}
+
+	message := event.Message
+	if message == "" {
+		message = event.Type
+	}
+	if status == models.PaymentStatusRefunded {
+		return applyRefundEvent(tx, &invoice, payment, event, message)
+	}
+	if payment.Status == status {
+		return payment, nil
+	}
+	if payment.ProviderReference == "" {
+		payment.ProviderReference = event.Reference
+	}
+	if err := applyProviderResult(tx, &payment, ProviderResult{Reference: payment.ProviderReference, Status: status, Message: message}); err != nil {
+		return models.Payment{}, err
+	}
+	return payment, ApplyInvoicePayments(tx, &invoice)
+}
+
+// applyRefundEvent records a refund the payment provider reports. A refund made for a credit note,
+// recognised by the idempotency key it was made with, completes that refund, and one made to void
+// the payment is recorded by the void. Any other refund was made at the provider directly and is
+// documented in a credit note of its own, for the amount reported or whatever had not been refunded
+// yet, so the invoice and the sales report account for it.
+func applyRefundEvent(tx *gorm.DB, invoice *models.Invoice, payment models.Payment, event PaymentEvent, message string) (models.Payment, error) {
+	if event.IdempotencyKey != "" {
+		var refund models.Refund
+		err := tx.Where("refund_id = ? AND payment_id = ?", event.IdempotencyKey, payment.PaymentID).First(&refund).Error
+		if err == nil {
+			return payment, tx.Model(&refund).Where("status = ?", models.RefundStatusPending).
+				Updates(map[string]interface{}{"status": models.RefundStatusCompleted, "provider_message": message}).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Payment{}, err
+		}
+		if event.IdempotencyKey == voidIdempotencyKey(payment.PaymentID) {
+			return payment, nil
+		}
+	}
+
+	if payment.Status == models.PaymentStatusRefunded {
+		return payment, nil
+	}
+	if payment.Status != models.PaymentStatusCompleted {
+		return models.Payment{}, NewRequestError(http.StatusConflict, fmt.Sprintf("A %s payment cannot be refunded", payment.Status))
+	}
+
+	remaining := payment.Amount.Sub(payment.RefundedAmount)
+	amount := event.Amount.Round(payment.Currency)
+	if amount.IsZero() {
+		amount = remaining
+	}
+	if amount.IsNegative() || remaining.Sub(amount).IsNegative() {
+		return models.Payment{}, NewRequestError(http.StatusConflict, fmt.Sprintf("The refund reported exceeds the %s left to refund on the payment", remaining))
+	}
+
+	if _, err := recordCreditNote(tx, invoice, []refundPlan{{payment: payment, amount: amount}}, "Refunded at the payment provider: "+message, "", models.RefundStatusCompleted); err != nil {
+		return models.Payment{}, err
+	}
+	return LockPayment(tx, invoice.InvoiceID, payment.PaymentID)
+}
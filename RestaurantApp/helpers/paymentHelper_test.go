@@ -0,0 +1,51 @@
+package helpers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/RestaurantApp/models"
+)
+
+func TestCheckPaymentEvent(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	event := func(eventType, eventId string, sent time.Time) PaymentEvent {
+		return PaymentEvent{EventID: eventId, Type: eventType, Timestamp: sent.Unix(), Reference: "mock_pay-1"}
+	}
+
+	tests := []struct {
+		name       string
+		event      PaymentEvent
+		wantStatus string
+		wantErr    bool
+	}{
+		{name: "captured just now", event: event("payment.captured", "evt_1", now), wantStatus: models.PaymentStatusCompleted},
+		{name: "declined", event: event("payment.declined", "evt_2", now), wantStatus: models.PaymentStatusDeclined},
+		{name: "refunded", event: event("payment.refunded", "evt_3", now), wantStatus: models.PaymentStatusRefunded},
+		{name: "sent four minutes ago", event: event("payment.voided", "evt_4", now.Add(-4*time.Minute)), wantStatus: models.PaymentStatusVoided},
+		{name: "sent exactly five minutes ago", event: event("payment.captured", "evt_5", now.Add(-5*time.Minute)), wantStatus: models.PaymentStatusCompleted},
+		{name: "clock slightly ahead", event: event("payment.authorized", "evt_6", now.Add(2*time.Minute)), wantStatus: models.PaymentStatusAuthorized},
+		{name: "stale timestamp", event: event("payment.captured", "evt_7", now.Add(-5*time.Minute-time.Second)), wantErr: true},
+		{name: "replayed a day later", event: event("payment.captured", "evt_8", now.Add(-24*time.Hour)), wantErr: true},
+		{name: "timestamp far in the future", event: event("payment.captured", "evt_9", now.Add(6*time.Minute)), wantErr: true},
+		{name: "missing timestamp", event: PaymentEvent{EventID: "evt_10", Type: "payment.captured"}, wantErr: true},
+		{name: "missing event id", event: event("payment.captured", "", now), wantErr: true},
+		{name: "unknown type", event: event("payment.disputed", "evt_11", now), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		status, err := checkPaymentEvent(tt.event, now)
+		if tt.wantErr {
+			var requestErr *RequestError
+			if !errors.As(err, &requestErr) || requestErr.Status != http.StatusBadRequest {
+				t.Errorf("%s: checkPaymentEvent returned (%q, %v), want a bad request error", tt.name, status, err)
+			}
+			continue
+		}
+		if err != nil || status != tt.wantStatus {
+			t.Errorf("%s: checkPaymentEvent returned (%q, %v), want %q", tt.name, status, err, tt.wantStatus)
+		}
+	}
+}
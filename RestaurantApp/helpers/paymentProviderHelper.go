@@ -0,0 +1,152 @@
+package helpers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/RestaurantApp/models"
+)
+
+// PaymentSignatureHeader carries the hex HMAC-SHA256 of a payment webhook's body
+const PaymentSignatureHeader = "X-Payment-Signature"
+
+// ProviderPaymentRequest asks a payment provider to authorize an amount. Token identifies the card
+// or wallet, as read by a card terminal or collected by an online checkout.
+type ProviderPaymentRequest struct {
+	PaymentID      string
+	InvoiceID      string
+	Amount         models.Money
+	Currency       string
+	Channel        string
+	Token          string
+	IdempotencyKey string
+}
+
+// ProviderResult is a payment provider's answer. Status is the payment status it leads to; a
+// pending payment's outcome arrives later through the payment webhook.
+type ProviderResult struct {
+	Reference string
+	Status    string
+	Message   string
+}
+
+// PaymentProvider takes card and online payments through a payment gateway. Every call carries an
+// idempotency key: a call repeated with the same key must not move money again but return the
+// outcome of the first, so a call whose answer was lost can safely be retried. Implementations must
+// be safe for concurrent use.
+type PaymentProvider interface {
+	Name() string
+	Authorize(ctx context.Context, request ProviderPaymentRequest) (ProviderResult, error)
+	Capture(ctx context.Context, reference string, amount models.Money, currency, idempotencyKey string) (ProviderResult, error)
+	Refund(ctx context.Context, reference string, amount models.Money, currency, idempotencyKey string) (ProviderResult, error)
+	Void(ctx context.Context, reference, idempotencyKey string) (ProviderResult, error)
+}
+
+// Tokens that make the mock provider decline a payment or answer it asynchronously
+const (
+	MockTokenDecline = "tok_decline"
+	MockTokenPending = "tok_pending"
+)
+
+// MockProvider is a deterministic payment provider for development and tests. It approves every
+// payment except those whose token starts with tok_decline, which are declined, or tok_pending,
+// which stay pending until a webhook reports their outcome. References are derived from the
+// payment id, so the same payment always gets the same reference.
+type MockProvider struct{}
+
+func (MockProvider) Name() string {
+	return "mock"
+}
+
+func (MockProvider) Authorize(ctx context.Context, request ProviderPaymentRequest) (ProviderResult, error) {
+	result := ProviderResult{Reference: "mock_" + request.PaymentID}
+	switch {
+	case strings.HasPrefix(request.Token, MockTokenDecline):
+		result.Status, result.Message = models.PaymentStatusDeclined, "Card declined"
+	case strings.HasPrefix(request.Token, MockTokenPending):
+		result.Status, result.Message = models.PaymentStatusPending, "Awaiting confirmation"
+	default:
+		result.Status, result.Message = models.PaymentStatusAuthorized, "Approved"
+	}
+	return result, nil
+}
+
+func (MockProvider) Capture(ctx context.Context, reference string, amount models.Money, currency, idempotencyKey string) (ProviderResult, error) {
+	return ProviderResult{Reference: reference, Status: models.PaymentStatusCompleted, Message: "Captured"}, nil
+}
+
+func (MockProvider) Refund(ctx context.Context, reference string, amount models.Money, currency, idempotencyKey string) (ProviderResult, error) {
+	return ProviderResult{Reference: reference, Status: models.PaymentStatusRefunded, Message: "Refunded"}, nil
+}
+
+func (MockProvider) Void(ctx context.Context, reference, idempotencyKey string) (ProviderResult, error) {
+	return ProviderResult{Reference: reference, Status: models.PaymentStatusVoided, Message: "Voided"}, nil
+}
+
+var (
+	paymentConfigMu      sync.RWMutex
+	paymentProvider      PaymentProvider = MockProvider{}
+	paymentWebhookSecret []byte
+)
+
+// ErrWebhookNotConfigured is returned when a payment webhook arrives but no signing secret is set
+var ErrWebhookNotConfigured = errors.New("payment webhook secret is not configured")
+
+// ConfigurePayments selects the payment provider by name and sets the secret payment webhooks are
+// signed with. Only the mock provider is built in; an empty name keeps it.
+func ConfigurePayments(provider, webhookSecret string) error {
+	paymentConfigMu.Lock()
+	defer paymentConfigMu.Unlock()
+
+	switch provider {
+	case "", "mock":
+		paymentProvider = MockProvider{}
+	default:
+		return fmt.Errorf("unknown payment provider %q", provider)
+	}
+
+	paymentWebhookSecret = []byte(webhookSecret)
+	return nil
+}
+
+// SetPaymentProvider replaces the provider card and online payments are taken through
+func SetPaymentProvider(provider PaymentProvider) {
+	paymentConfigMu.Lock()
+	defer paymentConfigMu.Unlock()
+	paymentProvider = provider
+}
+
+// CurrentPaymentProvider returns the provider card and online payments are taken through
+func CurrentPaymentProvider() PaymentProvider {
+	paymentConfigMu.RLock()
+	defer paymentConfigMu.RUnlock()
+	return paymentProvider
+}
+
+// SignPaymentWebhook returns the signature a payment webhook with the given body must carry
+func SignPaymentWebhook(body []byte) (string, error) {
+	paymentConfigMu.RLock()
+	defer paymentConfigMu.RUnlock()
+
+	if len(paymentWebhookSecret) == 0 {
+		return "", ErrWebhookNotConfigured
+	}
+	mac := hmac.New(sha256.New, paymentWebhookSecret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyPaymentWebhook reports whether signature is the valid signature of a payment webhook body
+func VerifyPaymentWebhook(body []byte, signature string) (bool, error) {
+	expected, err := SignPaymentWebhook(body)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(strings.TrimSpace(signature)))), nil
+}
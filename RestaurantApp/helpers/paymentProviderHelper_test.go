@@ -0,0 +1,123 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RestaurantApp/models"
+)
+
+func TestMockProviderAuthorize(t *testing.T) {
+	tests := []struct {
+		token      string
+		wantStatus string
+	}{
+		{token: "tok_visa", wantStatus: models.PaymentStatusAuthorized},
+		{token: "", wantStatus: models.PaymentStatusAuthorized},
+		{token: "tok_decline", wantStatus: models.PaymentStatusDeclined},
+		{token: "tok_decline_insufficient_funds", wantStatus: models.PaymentStatusDeclined},
+		{token: "tok_pending", wantStatus: models.PaymentStatusPending},
+		{token: "tok_pending_3ds", wantStatus: models.PaymentStatusPending},
+		{token: "x_tok_decline", wantStatus: models.PaymentStatusAuthorized},
+	}
+
+	for _, tt := range tests {
+		request := ProviderPaymentRequest{PaymentID: "pay-1", Amount: 125000, Currency: "USD", Token: tt.token}
+		first, err := MockProvider{}.Authorize(context.Background(), request)
+		if err != nil {
+			t.Fatalf("Authorize(%q) returned error %v", tt.token, err)
+		}
+		if first.Status != tt.wantStatus {
+			t.Errorf("Authorize(%q) status = %q, want %q", tt.token, first.Status, tt.wantStatus)
+		}
+		if first.Reference != "mock_pay-1" {
+			t.Errorf("Authorize(%q) reference = %q, want %q", tt.token, first.Reference, "mock_pay-1")
+		}
+
+		// The mock is deterministic, so a retried call gets the same answer
+		second, _ := MockProvider{}.Authorize(context.Background(), request)
+		if second != first {
+			t.Errorf("Authorize(%q) answered %+v, then %+v", tt.token, first, second)
+		}
+	}
+}
+
+func TestMockProviderFollowUpCalls(t *testing.T) {
+	ctx := context.Background()
+	provider := MockProvider{}
+
+	tests := []struct {
+		name       string
+		call       func() (ProviderResult, error)
+		wantStatus string
+	}{
+		{name: "capture", call: func() (ProviderResult, error) { return provider.Capture(ctx, "mock_pay-1", 125000, "USD", "key-1") }, wantStatus: models.PaymentStatusCompleted},
+		{name: "refund", call: func() (ProviderResult, error) { return provider.Refund(ctx, "mock_pay-1", 50000, "USD", "key-2") }, wantStatus: models.PaymentStatusRefunded},
+		{name: "void", call: func() (ProviderResult, error) { return provider.Void(ctx, "mock_pay-1", "key-3") }, wantStatus: models.PaymentStatusVoided},
+	}
+
+	for _, tt := range tests {
+		result, err := tt.call()
+		if err != nil {
+			t.Fatalf("%s returned error %v", tt.name, err)
+		}
+		if result.Status != tt.wantStatus || result.Reference != "mock_pay-1" {
+			t.Errorf("%s = %+v, want status %q on reference mock_pay-1", tt.name, result, tt.wantStatus)
+		}
+	}
+}
+
+func TestVerifyPaymentWebhook(t *testing.T) {
+	if err := ConfigurePayments("mock", "webhook-secret"); err != nil {
+		t.Fatalf("ConfigurePayments returned error %v", err)
+	}
+	t.Cleanup(func() { ConfigurePayments("mock", "") })
+
+	body := []byte(`{"event_id":"evt_1","timestamp":1700000000,"type":"payment.captured","reference":"mock_pay-1"}`)
+	signature, err := SignPaymentWebhook(body)
+	if err != nil {
+		t.Fatalf("SignPaymentWebhook returned error %v", err)
+	}
+	upper := []byte(signature)
+	for i, b := range upper {
+		if b >= 'a' && b <= 'f' {
+			upper[i] = b - 'a' + 'A'
+		}
+	}
+
+	tests := []struct {
+		name      string
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{name: "valid signature", body: body, signature: signature, want: true},
+		{name: "upper case with whitespace", body: body, signature: "  " + string(upper) + "\n", want: true},
+		{name: "tampered body", body: []byte(`{"event_id":"evt_1","timestamp":1700000000,"type":"payment.refunded","reference":"mock_pay-1"}`), signature: signature, want: false},
+		{name: "truncated signature", body: body, signature: signature[:len(signature)-2], want: false},
+		{name: "missing signature", body: body, signature: "", want: false},
+		{name: "signed with another secret", body: body, signature: "0000000000000000000000000000000000000000000000000000000000000000", want: false},
+	}
+
+	for _, tt := range tests {
+		got, err := VerifyPaymentWebhook(tt.body, tt.signature)
+		if err != nil {
+			t.Errorf("%s: VerifyPaymentWebhook returned error %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: VerifyPaymentWebhook = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyPaymentWebhookWithoutSecret(t *testing.T) {
+	if err := ConfigurePayments("mock", ""); err != nil {
+		t.Fatalf("ConfigurePayments returned error %v", err)
+	}
+
+	if valid, err := VerifyPaymentWebhook([]byte(`{}`), "anything"); valid || !errors.Is(err, ErrWebhookNotConfigured) {
+		t.Errorf("VerifyPaymentWebhook without a secret = (%v, %v), want (false, ErrWebhookNotConfigured)", valid, err)
+	}
+}
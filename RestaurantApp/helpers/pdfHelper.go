@@ -0,0 +1,122 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 page size and margins in PDF points
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 50.0
+)
+
+// PDFDocument builds a simple text-only PDF using the built-in Helvetica font. Lines are laid out
+// top to bottom and a new page is started when the current one is full.
+type PDFDocument struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+// NewPDFDocument starts an empty document with one page
+func NewPDFDocument() *PDFDocument {
+	doc := &PDFDocument{}
+	doc.newPage()
+	return doc
+}
+
+func (doc *PDFDocument) newPage() {
+	doc.pages = append(doc.pages, &bytes.Buffer{})
+	doc.y = pdfPageHeight - pdfMargin
+}
+
+// advance moves down by the given height, starting a new page when it would run into the bottom margin
+func (doc *PDFDocument) advance(height float64) {
+	if doc.y-height < pdfMargin {
+		doc.newPage()
+	}
+	doc.y -= height
+}
+
+// Text writes a line of text at the left margin
+func (doc *PDFDocument) Text(size float64, text string) {
+	doc.Row(size, []string{text}, []float64{0})
+}
+
+// Row writes one line of columns, each starting at the given offset from the left margin
+func (doc *PDFDocument) Row(size float64, columns []string, offsets []float64) {
+	doc.advance(size * 1.4)
+	page := doc.pages[len(doc.pages)-1]
+	for i, column := range columns {
+		if i >= len(offsets) || column == "" {
+			continue
+		}
+		fmt.Fprintf(page, "BT /F1 %.1f Tf %.1f %.1f Td (%s) Tj ET\n", size, pdfMargin+offsets[i], doc.y, pdfEscape(column))
+	}
+}
+
+// Gap leaves vertical space between blocks of text
+func (doc *PDFDocument) Gap(height float64) {
+	doc.advance(height)
+}
+
+// Bytes renders the document as a PDF file
+func (doc *PDFDocument) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+
+	// Objects 1-3 are the catalog, page tree and font; each page then takes a page and a content object
+	kids := make([]string, len(doc.pages))
+	for i := range doc.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(doc.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range doc.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 5+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// pdfEscape makes text safe inside a PDF string literal. Characters outside Latin-1 cannot be
+// shown with the standard fonts and are replaced with '?'.
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 128:
+			b.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,351 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Purchase order document formats
+const (
+	DocumentFormatPDF = "pdf"
+	DocumentFormatCSV = "csv"
+)
+
+// LockPurchaseOrder loads a purchase order with SELECT ... FOR UPDATE, serialising receipts against it
+func LockPurchaseOrder(tx *gorm.DB, purchaseOrderId string) (models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("purchase_order_id = ?", purchaseOrderId).First(&order).Error
+	return order, err
+}
+
+// PricePurchaseOrderLines validates the lines of a draft purchase order against the supplier's
+// stock items, defaulting each unit cost to the last price paid, and sets the order total
+func PricePurchaseOrderLines(tx *gorm.DB, order *models.PurchaseOrder) error {
+	if err := checkPurchaseOrderCurrency(order); err != nil {
+		return err
+	}
+	if len(order.Lines) == 0 {
+		return NewRequestError(http.StatusBadRequest, "A purchase order needs at least one line")
+	}
+
+	order.Total = 0
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		if line.Quantity.IsZero() || line.Quantity.IsNegative() || line.UnitCost.IsNegative() {
+			return NewRequestError(http.StatusBadRequest, "Every line needs a positive quantity and a unit cost that is not negative")
+		}
+
+		var item models.StockItem
+		if err := tx.Where("stock_item_id = ? AND supplier_id = ?", line.StockItemID, order.SupplierID).First(&item).Error; err != nil {
+			return NewRequestError(http.StatusBadRequest, fmt.Sprintf("Stock item %q is not supplied by this supplier", line.StockItemID))
+		}
+		if item.Currency != order.Currency {
+			return NewRequestError(http.StatusBadRequest, fmt.Sprintf("Stock item %q is priced in a different currency than the purchase order", item.Name))
+		}
+
+		if line.Description == "" {
+			line.Description = item.Name
+		}
+		if line.UnitCost.IsZero() {
+			line.UnitCost = item.UnitCost
+		}
+		line.UnitCost = line.UnitCost.Round(order.Currency)
+		line.ReceivedQuantity = 0
+		line.LineTotal = line.Quantity.Cost(line.UnitCost).Round(order.Currency)
+		order.Total = order.Total.Add(line.LineTotal)
+	}
+	return nil
+}
+
+// checkPurchaseOrderCurrency rejects purchase orders in a currency other than the default one.
+// Receiving goods sets ingredient costs, which are kept in the default currency, from the prices
+// paid, and there are no exchange rates to convert other currencies with.
+func checkPurchaseOrderCurrency(order *models.PurchaseOrder) error {
+	if currency := models.DefaultCurrency(); order.Currency != currency {
+		return NewRequestError(http.StatusBadRequest, fmt.Sprintf("Purchase orders must be in %s, the currency ingredient costs are kept in", currency))
+	}
+	return nil
+}
+
+// ReceiveGoods records a delivery against a sent purchase order. Each received line adds stock for
+// its ingredient and records the actual unit cost paid, which becomes the latest cost of the stock
+// item and its ingredient; the costs it replaces are kept on the receipt line so a reversal can put
+// them back. Receiving more than is outstanding on a line is rejected. The returned availability
+// changes must be published once the transaction commits.
+func ReceiveGoods(tx *gorm.DB, order *models.PurchaseOrder, receipt *models.GoodsReceipt, actorUid string) ([]AvailabilityChange, error) {
+	if order.Status != models.PurchaseOrderStatusSent && order.Status != models.PurchaseOrderStatusPartiallyReceived {
+		return nil, NewRequestError(http.StatusBadRequest, "Goods can only be received against a purchase order that has been sent")
+	}
+	if err := checkPurchaseOrderCurrency(order); err != nil {
+		return nil, err
+	}
+	if len(receipt.Lines) == 0 {
+		return nil, NewRequestError(http.StatusBadRequest, "A goods receipt needs at least one line")
+	}
+
+	lines := make(map[string]*models.PurchaseOrderLine)
+	for i := range order.Lines {
+		lines[order.Lines[i].LineID] = &order.Lines[i]
+	}
+
+	receipt.ReceiptID = ""
+	receipt.PurchaseOrderID = order.PurchaseOrderID
+	receipt.ReceivedBy = actorUid
+	receipt.ReversedAt, receipt.ReversedBy, receipt.ReversalReason = nil, "", ""
+	receiptLines := receipt.Lines
+	receipt.Lines = nil
+	if err := tx.Create(receipt).Error; err != nil {
+		return nil, err
+	}
+
+	var moved []string
+	for _, received := range receiptLines {
+		line, ok := lines[received.LineID]
+		if !ok {
+			return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Line %q is not part of this purchase order", received.LineID))
+		}
+		if received.Quantity.IsZero() || received.Quantity.IsNegative() || received.UnitCost.IsNegative() {
+			return nil, NewRequestError(http.StatusBadRequest, "Every received line needs a positive quantity and a unit cost that is not negative")
+		}
+		if line.ReceivedQuantity.Add(received.Quantity) > line.Quantity {
+			return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Only %s of %q is still outstanding", line.Quantity.Sub(line.ReceivedQuantity), line.Description))
+		}
+
+		var item models.StockItem
+		if err := tx.Where("stock_item_id = ?", line.StockItemID).First(&item).Error; err != nil {
+			return nil, err
+		}
+		if item.Currency != order.Currency {
+			return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Stock item %q is now priced in a different currency than the purchase order", item.Name))
+		}
+
+		var ingredient models.Ingredient
+		if err := tx.Where("ingredient_id = ?", item.IngredientID).First(&ingredient).Error; err != nil {
+			return nil, err
+		}
+
+		unitCost := received.UnitCost
+		if unitCost.IsZero() {
+			unitCost = line.UnitCost
+		}
+
+		previousUnitCost, previousIngredientUnitCost := item.UnitCost, ingredient.UnitCost
+		receiptLine := models.GoodsReceiptLine{
+			ReceiptID:                  receipt.ReceiptID,
+			LineID:                     line.LineID,
+			StockItemID:                item.StockItemID,
+			IngredientID:               item.IngredientID,
+			Quantity:                   received.Quantity,
+			StockQuantity:              received.Quantity.Scale(item.PackSize),
+			UnitCost:                   unitCost.Round(order.Currency),
+			PreviousUnitCost:           &previousUnitCost,
+			PreviousIngredientUnitCost: &previousIngredientUnitCost,
+		}
+		receiptLine.LineTotal = receiptLine.Quantity.Cost(receiptLine.UnitCost).Round(order.Currency)
+		receiptLine.IngredientUnitCost = IngredientUnitCost(receiptLine.UnitCost, item.PackSize)
+		if err := tx.Create(&receiptLine).Error; err != nil {
+			return nil, err
+		}
+
+		if err := AdjustStock(tx, &models.StockMovement{
+			IngredientID: receiptLine.IngredientID,
+			Quantity:     receiptLine.StockQuantity,
+			SourceType:   models.StockSourceGoodsReceipt,
+			SourceID:     receiptLine.ReceiptLineID,
+			Reason:       "goods received",
+			ActorUID:     actorUid,
+		}); err != nil {
+			return nil, err
+		}
+
+		line.ReceivedQuantity = line.ReceivedQuantity.Add(receiptLine.Quantity)
+		if err := tx.Model(line).Update("received_quantity", line.ReceivedQuantity).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Model(&item).Update("unit_cost", receiptLine.UnitCost).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Model(&ingredient).Update("unit_cost", receiptLine.IngredientUnitCost).Error; err != nil {
+			return nil, err
+		}
+
+		receipt.Lines = append(receipt.Lines, receiptLine)
+		moved = append(moved, receiptLine.IngredientID)
+	}
+
+	if err := refreshPurchaseOrderStatus(tx, order); err != nil {
+		return nil, err
+	}
+	return RefreshStockAvailability(tx, moved)
+}
+
+// ReverseGoodsReceipt takes the stock of a receipt back out and reopens the purchase order lines it
+// received. The stock item and ingredient costs the receipt set go back to what they were before it,
+// unless a later receipt has changed them since. The receipt is kept and marked reversed so the
+// delivery and its reversal stay on record.
+func ReverseGoodsReceipt(tx *gorm.DB, receipt *models.GoodsReceipt, reason, actorUid string) ([]AvailabilityChange, error) {
+	order, err := LockPurchaseOrder(tx, receipt.PurchaseOrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-read under the purchase order lock so a receipt cannot be reversed twice
+	if err := tx.Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("receipt_id = ?", receipt.ReceiptID).First(receipt).Error; err != nil {
+		return nil, err
+	}
+	if receipt.ReversedAt != nil {
+		return nil, NewRequestError(http.StatusBadRequest, "This goods receipt has already been reversed")
+	}
+	if order.Status == models.PurchaseOrderStatusCancelled {
+		return nil, NewRequestError(http.StatusBadRequest, "Receipts of a cancelled purchase order cannot be reversed")
+	}
+
+	var moved []string
+	// Undo the lines last to first, so costs set by several lines of the receipt unwind in turn
+	for i := len(receipt.Lines) - 1; i >= 0; i-- {
+		receiptLine := receipt.Lines[i]
+		if err := AdjustStock(tx, &models.StockMovement{
+			IngredientID: receiptLine.IngredientID,
+			Quantity:     receiptLine.StockQuantity.Neg(),
+			SourceType:   models.StockSourceGoodsReceiptReversal,
+			SourceID:     receiptLine.ReceiptLineID,
+			Reason:       reason,
+			ActorUID:     actorUid,
+		}); err != nil {
+			return nil, err
+		}
+
+		if err := tx.Model(&models.PurchaseOrderLine{}).Where("line_id = ?", receiptLine.LineID).
+			Update("received_quantity", gorm.Expr("received_quantity - ?", receiptLine.Quantity)).Error; err != nil {
+			return nil, err
+		}
+
+		// Receipts recorded before the replaced costs were kept leave the costs as they are
+		if receiptLine.PreviousUnitCost != nil {
+			if err := tx.Model(&models.StockItem{}).
+				Where("stock_item_id = ? AND unit_cost = ?", receiptLine.StockItemID, receiptLine.UnitCost).
+				Update("unit_cost", *receiptLine.PreviousUnitCost).Error; err != nil {
+				return nil, err
+			}
+		}
+		if receiptLine.PreviousIngredientUnitCost != nil {
+			if err := tx.Model(&models.Ingredient{}).
+				Where("ingredient_id = ? AND unit_cost = ?", receiptLine.IngredientID, receiptLine.IngredientUnitCost).
+				Update("unit_cost", *receiptLine.PreviousIngredientUnitCost).Error; err != nil {
+				return nil, err
+			}
+		}
+		moved = append(moved, receiptLine.IngredientID)
+	}
+
+	now := time.Now()
+	receipt.ReversedAt = &now
+	receipt.ReversedBy = actorUid
+	receipt.ReversalReason = reason
+	if err := tx.Model(receipt).Select("reversed_at", "reversed_by", "reversal_reason").Updates(receipt).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("purchase_order_id = ?", order.PurchaseOrderID).Find(&order.Lines).Error; err != nil {
+		return nil, err
+	}
+	if err := refreshPurchaseOrderStatus(tx, &order); err != nil {
+		return nil, err
+	}
+	return RefreshStockAvailability(tx, moved)
+}
+
+// refreshPurchaseOrderStatus derives a sent purchase order's status from how much of its lines has been received
+func refreshPurchaseOrderStatus(tx *gorm.DB, order *models.PurchaseOrder) error {
+	received, complete := false, true
+	for _, line := range order.Lines {
+		if !line.ReceivedQuantity.IsZero() {
+			received = true
+		}
+		if line.ReceivedQuantity < line.Quantity {
+			complete = false
+		}
+	}
+
+	status := models.PurchaseOrderStatusSent
+	switch {
+	case complete:
+		status = models.PurchaseOrderStatusReceived
+	case received:
+		status = models.PurchaseOrderStatusPartiallyReceived
+	}
+
+	if status == order.Status {
+		return nil
+	}
+	order.Status = status
+	return tx.Model(order).Update("status", status).Error
+}
+
+// PurchaseOrderCSV renders a purchase order as CSV, one row per line followed by the total
+func PurchaseOrderCSV(order *models.PurchaseOrder, supplier *models.Supplier, items map[string]models.StockItem) ([]byte, error) {
+	var out bytes.Buffer
+	writer := csv.NewWriter(&out)
+
+	rows := [][]string{
+		{"Purchase order", order.PurchaseOrderID},
+		{"Supplier", supplier.Name},
+		{"Date", order.CreatedAt.In(RestaurantLocation()).Format("2006-01-02")},
+		{"Currency", order.Currency},
+		{},
+		{"Supplier SKU", "Description", "Quantity", "Unit", "Unit cost", "Line total"},
+	}
+	for _, line := range order.Lines {
+		item := items[line.StockItemID]
+		rows = append(rows, []string{item.SupplierSKU, line.Description, line.Quantity.String(), item.PurchaseUnit, line.UnitCost.String(), line.LineTotal.String()})
+	}
+	rows = append(rows, []string{"", "", "", "", "Total", order.Total.String()})
+
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// PurchaseOrderPDF renders a purchase order as a printable PDF to send to the supplier
+func PurchaseOrderPDF(order *models.PurchaseOrder, supplier *models.Supplier, items map[string]models.StockItem) []byte {
+	doc := NewPDFDocument()
+	doc.Text(18, "Purchase Order")
+	doc.Text(10, "Reference: "+order.PurchaseOrderID)
+	doc.Text(10, "Date: "+order.CreatedAt.In(RestaurantLocation()).Format("2006-01-02"))
+	if order.ExpectedDate != nil {
+		doc.Text(10, "Expected delivery: "+order.ExpectedDate.In(RestaurantLocation()).Format("2006-01-02"))
+	}
+
+	doc.Gap(10)
+	doc.Text(12, "Supplier")
+	for _, detail := range []string{supplier.Name, supplier.ContactName, supplier.Address, supplier.Email, supplier.Phone} {
+		if detail != "" {
+			doc.Text(10, detail)
+		}
+	}
+
+	columns := []float64{0, 80, 280, 340, 390, 445}
+	doc.Gap(10)
+	doc.Row(10, []string{"SKU", "Description", "Qty", "Unit", "Unit cost", "Total"}, columns)
+	for _, line := range order.Lines {
+		item := items[line.StockItemID]
+		doc.Row(10, []string{item.SupplierSKU, line.Description, line.Quantity.String(), item.PurchaseUnit, line.UnitCost.String(), line.LineTotal.String()}, columns)
+	}
+	doc.Gap(4)
+	doc.Row(11, []string{"Total (" + order.Currency + ")", order.Total.String()}, []float64{340, 445})
+
+	if order.Notes != "" {
+		doc.Gap(10)
+		doc.Text(10, "Notes: "+order.Notes)
+	}
+	return doc.Bytes()
+}
@@ -0,0 +1,73 @@
+package helpers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RestaurantApp/models"
+	"github.com/gin-gonic/gin"
+)
+
+// reportDateLayout is the format of the from/to report query parameters
+const reportDateLayout = "2006-01-02"
+
+// defaultReportDays is how far back a report looks when no from date is given
+const defaultReportDays = 30
+
+// ErrInvalidReportPeriod is returned when the report dates cannot be parsed or are out of order
+var ErrInvalidReportPeriod = errors.New("report dates must be YYYY-MM-DD with from on or before to")
+
+// SalesOrderStatuses are the statuses of orders that count as sales: confirmed and not cancelled or voided
+var SalesOrderStatuses = []string{
+	models.OrderStatusAccepted,
+	models.OrderStatusPreparing,
+	models.OrderStatusReady,
+	models.OrderStatusServed,
+	models.OrderStatusInvoiced,
+	models.OrderStatusCompleted,
+}
+
+// ReportPeriod is the time range a report covers: From is inclusive and To is exclusive
+type ReportPeriod struct {
+	From time.Time
+	To   time.Time
+}
+
+// GetReportPeriod reads the inclusive from and to dates of a report from the query string.
+// Dates are days in the restaurant's timezone. Without a to date the report runs to the end of today, and without a from date it covers
+// the preceding 30 days.
+func GetReportPeriod(c *gin.Context) (ReportPeriod, error) {
+	now := time.Now().In(RestaurantLocation())
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
+
+	if value := c.Query("to"); value != "" {
+		day, err := time.ParseInLocation(reportDateLayout, value, now.Location())
+		if err != nil {
+			return ReportPeriod{}, ErrInvalidReportPeriod
+		}
+		to = day.AddDate(0, 0, 1)
+	}
+
+	from := to.AddDate(0, 0, -defaultReportDays)
+	if value := c.Query("from"); value != "" {
+		day, err := time.ParseInLocation(reportDateLayout, value, now.Location())
+		if err != nil {
+			return ReportPeriod{}, ErrInvalidReportPeriod
+		}
+		from = day
+	}
+
+	if !from.Before(to) {
+		return ReportPeriod{}, ErrInvalidReportPeriod
+	}
+
+	return ReportPeriod{From: from, To: to}, nil
+}
+
+// Response renders the period with the inclusive dates the client asked for
+func (period ReportPeriod) Response() gin.H {
+	return gin.H{
+		"from": period.From.Format(reportDateLayout),
+		"to":   period.To.AddDate(0, 0, -1).Format(reportDateLayout),
+	}
+}
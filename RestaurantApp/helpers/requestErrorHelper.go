@@ -0,0 +1,40 @@
+package helpers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestError carries the HTTP status and client-facing message out of code that
+// cannot write the response itself, such as a database transaction callback
+type RequestError struct {
+	Status  int
+	Message string
+}
+
+func NewRequestError(status int, message string) *RequestError {
+	return &RequestError{Status: status, Message: message}
+}
+
+func (e *RequestError) Error() string {
+	return e.Message
+}
+
+// RespondWithError writes err to the client, using its own status and message when it is a
+// RequestError or an invalid order transition, and the fallback message otherwise
+func RespondWithError(c *gin.Context, err error, fallback string) {
+	var requestErr *RequestError
+	if errors.As(err, &requestErr) {
+		c.JSON(requestErr.Status, gin.H{"error": requestErr.Message})
+		return
+	}
+
+	if errors.Is(err, ErrInvalidOrderTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
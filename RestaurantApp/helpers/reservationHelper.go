@@ -0,0 +1,327 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+)
+
+// reservationSlotInterval is the spacing of the start times offered by the availability search
+const reservationSlotInterval = 15 * time.Minute
+
+var (
+	reservationConfigMu sync.RWMutex
+	// reservationOpening and reservationClosing are the minutes after midnight between which
+	// reservations must start and end
+	reservationOpening = 11 * 60
+	reservationClosing = 22 * 60
+	// defaultReservationDuration is how long a table is held when no duration is given, and how
+	// long a table seated without a reservation is expected to stay occupied
+	defaultReservationDuration = 90
+)
+
+// ErrInvalidReservationHours is returned when the reservation hours are not "HH:MM-HH:MM" within one day
+var ErrInvalidReservationHours = errors.New("reservation hours must be HH:MM-HH:MM with opening before closing")
+
+// reservationTransitions maps each reservation status to the statuses it may move to next
+var reservationTransitions = map[string][]string{
+	models.ReservationStatusBooked:    {models.ReservationStatusSeated, models.ReservationStatusNoShow, models.ReservationStatusCancelled},
+	models.ReservationStatusSeated:    {},
+	models.ReservationStatusNoShow:    {},
+	models.ReservationStatusCancelled: {},
+}
+
+// ConfigureReservations sets the hours reservations are taken for, e.g. "11:00-22:00", and the
+// default reservation length in minutes. Empty values keep the defaults.
+func ConfigureReservations(hours, durationMinutes string) error {
+	reservationConfigMu.Lock()
+	defer reservationConfigMu.Unlock()
+
+	if hours != "" {
+		parts := strings.Split(hours, "-")
+		if len(parts) != 2 {
+			return ErrInvalidReservationHours
+		}
+		opening, err := time.Parse(scheduleTimeLayout, strings.TrimSpace(parts[0]))
+		if err != nil {
+			return ErrInvalidReservationHours
+		}
+		closing, err := time.Parse(scheduleTimeLayout, strings.TrimSpace(parts[1]))
+		if err != nil || !opening.Before(closing) {
+			return ErrInvalidReservationHours
+		}
+		reservationOpening = opening.Hour()*60 + opening.Minute()
+		reservationClosing = closing.Hour()*60 + closing.Minute()
+	}
+
+	if durationMinutes != "" {
+		minutes, err := strconv.Atoi(durationMinutes)
+		if err != nil || minutes <= 0 {
+			return errors.New("the default reservation duration must be a positive number of minutes")
+		}
+		defaultReservationDuration = minutes
+	}
+
+	return nil
+}
+
+// DefaultReservationDuration returns how long a reservation lasts when no duration is given, in minutes
+func DefaultReservationDuration() int {
+	reservationConfigMu.RLock()
+	defer reservationConfigMu.RUnlock()
+	return defaultReservationDuration
+}
+
+// reservationHours returns the opening and closing time for reservations on the day of the given time
+func reservationHours(day time.Time) (time.Time, time.Time) {
+	reservationConfigMu.RLock()
+	defer reservationConfigMu.RUnlock()
+
+	day = day.In(RestaurantLocation())
+	opening := time.Date(day.Year(), day.Month(), day.Day(), 0, reservationOpening, 0, 0, day.Location())
+	closing := time.Date(day.Year(), day.Month(), day.Day(), 0, reservationClosing, 0, 0, day.Location())
+	return opening, closing
+}
+
+// CanTransitionReservation reports whether a reservation may move from one status to another
+func CanTransitionReservation(from, to string) bool {
+	for _, next := range reservationTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ReservationSlot is a start time at which a party can be seated and the tables free for it
+type ReservationSlot struct {
+	StartTime time.Time      `json:"start_time"`
+	EndTime   time.Time      `json:"end_time"`
+	Tables    []models.Table `json:"tables"`
+}
+
+// busyWindow is a stretch of time during which a table is taken
+type busyWindow struct {
+	start time.Time
+	end   time.Time
+}
+
+// tableBusyWindows returns, per table, when it is held by active reservations or occupied by an
+// active order, including one at another table of its group, between from and to. A seated order is expected to hold its table for the default
+// reservation duration after it was placed, and for at least one more slot if it is still open.
+func tableBusyWindows(tx *gorm.DB, tableIds []string, from, to time.Time, excludeReservationId string) (map[string][]busyWindow, error) {
+	busy := make(map[string][]busyWindow)
+	if len(tableIds) == 0 {
+		return busy, nil
+	}
+
+	query := tx.Where("table_id IN ? AND status IN ? AND start_time < ? AND end_time > ?",
+		tableIds, models.ActiveReservationStatuses, to, from)
+	if excludeReservationId != "" {
+		query = query.Where("reservation_id <> ?", excludeReservationId)
+	}
+	var reservations []models.Reservation
+	if err := query.Find(&reservations).Error; err != nil {
+		return nil, err
+	}
+	for _, reservation := range reservations {
+		busy[reservation.TableID] = append(busy[reservation.TableID], busyWindow{reservation.StartTime, reservation.EndTime})
+	}
+
+	orders, err := ActiveOrdersByTable(tx, tableIds)
+	if err != nil {
+		return nil, err
+	}
+	stay := time.Duration(DefaultReservationDuration()) * time.Minute
+	minimum := time.Now().Add(reservationSlotInterval)
+	for tableId, order := range orders {
+		until := order.OrderDate.Add(stay)
+		if until.Before(minimum) {
+			until = minimum
+		}
+		busy[tableId] = append(busy[tableId], busyWindow{order.OrderDate, until})
+	}
+
+	return busy, nil
+}
+
+// isFree reports whether none of the windows overlap the time from start to end
+func isFree(windows []busyWindow, start, end time.Time) bool {
+	for _, window := range windows {
+		if window.start.Before(end) && window.end.After(start) {
+			return false
+		}
+	}
+	return true
+}
+
+// tablesForParty loads the tables that suit a party size, smallest first
+func tablesForParty(tx *gorm.DB, partySize int) ([]models.Table, error) {
+	var tables []models.Table
+	if err := tx.Order("table_name ASC").Find(&tables).Error; err != nil {
+		return nil, err
+	}
+	return TablesForParty(tables, partySize), nil
+}
+
+// FindReservationSlots lists the start times on a day at which a party of the given size can be
+// seated for the given number of minutes, with the tables free at each
+func FindReservationSlots(tx *gorm.DB, day time.Time, partySize, durationMinutes int) ([]ReservationSlot, error) {
+	tables, err := tablesForParty(tx, partySize)
+	if err != nil {
+		return nil, err
+	}
+
+	tableIds := make([]string, 0, len(tables))
+	for _, table := range tables {
+		tableIds = append(tableIds, table.TableID)
+	}
+
+	opening, closing := reservationHours(day)
+	busy, err := tableBusyWindows(tx, tableIds, opening, closing, "")
+	if err != nil {
+		return nil, err
+	}
+
+	duration := time.Duration(durationMinutes) * time.Minute
+	now := time.Now()
+	slots := []ReservationSlot{}
+	for start := opening; !start.Add(duration).After(closing); start = start.Add(reservationSlotInterval) {
+		if start.Before(now) {
+			continue
+		}
+		end := start.Add(duration)
+
+		slot := ReservationSlot{StartTime: start, EndTime: end, Tables: []models.Table{}}
+		for _, table := range tables {
+			if isFree(busy[table.TableID], start, end) {
+				slot.Tables = append(slot.Tables, table)
+			}
+		}
+		if len(slot.Tables) > 0 {
+			slots = append(slots, slot)
+		}
+	}
+
+	return slots, nil
+}
+
+// AssignReservationTable checks a reservation's party size and time and books it a table: the
+// requested one if it is free, otherwise the smallest suitable table free for the whole slot.
+// Tables are locked while they are checked so two bookings cannot take the same slot.
+func AssignReservationTable(tx *gorm.DB, reservation *models.Reservation) error {
+	if reservation.PartySize <= 0 {
+		return NewRequestError(http.StatusBadRequest, "The party size must be at least one")
+	}
+	if reservation.DurationMinutes == 0 {
+		reservation.DurationMinutes = DefaultReservationDuration()
+	}
+	if reservation.DurationMinutes < 0 {
+		return NewRequestError(http.StatusBadRequest, "The reservation duration must be positive")
+	}
+	if reservation.StartTime.Before(time.Now()) {
+		return NewRequestError(http.StatusBadRequest, "Reservations must start in the future")
+	}
+
+	reservation.EndTime = reservation.StartTime.Add(time.Duration(reservation.DurationMinutes) * time.Minute)
+	opening, closing := reservationHours(reservation.StartTime)
+	if reservation.StartTime.Before(opening) || reservation.EndTime.After(closing) {
+		return NewRequestError(http.StatusBadRequest, fmt.Sprintf("Reservations must start and end between %s and %s",
+			opening.Format(scheduleTimeLayout), closing.Format(scheduleTimeLayout)))
+	}
+
+	var candidates []models.Table
+	if reservation.TableID != "" {
+		var table models.Table
+		if err := tx.Where("table_id = ?", reservation.TableID).First(&table).Error; err != nil {
+			return NewRequestError(http.StatusBadRequest, "The table referenced does not exist")
+		}
+		if !table.Fits(reservation.PartySize) {
+			return NewRequestError(http.StatusBadRequest, fmt.Sprintf("Table %q does not suit a party of %d", table.TableName, reservation.PartySize))
+		}
+		candidates = []models.Table{table}
+	} else {
+		var err error
+		if candidates, err = tablesForParty(tx, reservation.PartySize); err != nil {
+			return err
+		}
+	}
+
+	for _, table := range candidates {
+		if _, err := LockTable(tx, table.TableID); err != nil {
+			return err
+		}
+
+		busy, err := tableBusyWindows(tx, []string{table.TableID}, reservation.StartTime, reservation.EndTime, reservation.ReservationID)
+		if err != nil {
+			return err
+		}
+		if isFree(busy[table.TableID], reservation.StartTime, reservation.EndTime) {
+			reservation.TableID = table.TableID
+			return nil
+		}
+	}
+
+	if reservation.TableID != "" {
+		return NewRequestError(http.StatusConflict, "This table is already booked or occupied for part of that time")
+	}
+	return NewRequestError(http.StatusConflict, fmt.Sprintf("No table is free for a party of %d at that time", reservation.PartySize))
+}
+
+// TransitionReservation moves a booked reservation to seated, no-show or cancelled. Seating needs
+// the table, and any table grouped with it, to be clear of other active orders and can link the
+// order the party was seated with; a no-show can only be recorded once the reservation has started.
+func TransitionReservation(tx *gorm.DB, reservation *models.Reservation, to, reason, orderId string) error {
+	if !CanTransitionReservation(reservation.Status, to) {
+		return NewRequestError(http.StatusConflict, fmt.Sprintf("A %s reservation cannot be marked as %s", reservation.Status, to))
+	}
+
+	now := time.Now()
+	switch to {
+	case models.ReservationStatusSeated:
+		tables, err := LockTableWithGroup(tx, reservation.TableID)
+		if err != nil {
+			return err
+		}
+
+		if orderId != "" {
+			var order models.Order
+			if err := tx.Where("order_id = ?", orderId).First(&order).Error; err != nil {
+				return NewRequestError(http.StatusBadRequest, "The order referenced does not exist")
+			}
+			atTable := false
+			for _, table := range tables {
+				atTable = atTable || table.TableID == order.TableID
+			}
+			if !atTable {
+				return NewRequestError(http.StatusBadRequest, "The order referenced is not at the reserved table")
+			}
+		}
+
+		if err := CheckTablesFree(tx, tables, orderId); err != nil {
+			return err
+		}
+
+		reservation.OrderID = orderId
+		reservation.SeatedAt = &now
+	case models.ReservationStatusNoShow:
+		if now.Before(reservation.StartTime) {
+			return NewRequestError(http.StatusBadRequest, "A reservation cannot be marked as a no-show before it starts")
+		}
+	case models.ReservationStatusCancelled:
+		reservation.CancelledAt = &now
+		reservation.CancellationReason = reason
+	}
+
+	reservation.Status = to
+	return tx.Model(reservation).
+		Select("status", "order_id", "seated_at", "cancelled_at", "cancellation_reason").
+		Updates(reservation).Error
+}
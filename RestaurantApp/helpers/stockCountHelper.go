@@ -0,0 +1,272 @@
+package helpers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LockStockCount loads a stock count with a row lock so entries and closing are serialized
+func LockStockCount(tx *gorm.DB, stockCountId string) (models.StockCount, error) {
+	var count models.StockCount
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("stock_count_id = ?", stockCountId).First(&count).Error
+	return count, err
+}
+
+// OpenStockCount starts a stock count with a count sheet for the given ingredients, or for every
+// ingredient when none are given
+func OpenStockCount(tx *gorm.DB, count *models.StockCount, ingredientIds []string) error {
+	var openCount int64
+	if err := tx.Model(&models.StockCount{}).Where("status = ?", models.StockCountStatusOpen).Count(&openCount).Error; err != nil {
+		return err
+	}
+	if openCount > 0 {
+		return NewRequestError(http.StatusConflict, "Another stock count is already open. Close or cancel it first.")
+	}
+
+	query := tx.Order("name ASC")
+	if len(ingredientIds) > 0 {
+		query = query.Where("ingredient_id IN ?", ingredientIds)
+	}
+	var ingredients []models.Ingredient
+	if err := query.Find(&ingredients).Error; err != nil {
+		return err
+	}
+	if len(ingredients) == 0 {
+		return NewRequestError(http.StatusBadRequest, "There are no ingredients to count")
+	}
+	if len(ingredientIds) > 0 && len(ingredients) != len(uniqueStrings(ingredientIds)) {
+		return NewRequestError(http.StatusBadRequest, "One or more ingredients referenced do not exist")
+	}
+
+	count.Status = models.StockCountStatusOpen
+	count.ClosedBy, count.ClosedAt = "", nil
+	count.Lines = nil
+	if err := tx.Create(count).Error; err != nil {
+		return err
+	}
+
+	for _, ingredient := range ingredients {
+		count.Lines = append(count.Lines, models.StockCountLine{
+			StockCountID: count.StockCountID,
+			IngredientID: ingredient.IngredientID,
+			Name:         ingredient.Name,
+			Unit:         ingredient.Unit,
+		})
+	}
+	return tx.Create(&count.Lines).Error
+}
+
+// RecordStockCountEntries saves the quantities one device has counted, replacing that device's
+// earlier entries for the same ingredients and stock items. Quantities counted by stock item are
+// in purchase units and converted with its pack size.
+func RecordStockCountEntries(tx *gorm.DB, count *models.StockCount, deviceId, actorUid string, entries []models.StockCountEntry) error {
+	if count.Status != models.StockCountStatusOpen {
+		return NewRequestError(http.StatusBadRequest, "This stock count is no longer open and cannot be changed")
+	}
+	if deviceId == "" || len(entries) == 0 {
+		return NewRequestError(http.StatusBadRequest, "A device_id and at least one entry are required")
+	}
+
+	var sheet []string
+	if err := tx.Model(&models.StockCountLine{}).Where("stock_count_id = ?", count.StockCountID).
+		Pluck("ingredient_id", &sheet).Error; err != nil {
+		return err
+	}
+	onSheet := make(map[string]bool)
+	for _, ingredientId := range sheet {
+		onSheet[ingredientId] = true
+	}
+
+	for _, counted := range entries {
+		if counted.Quantity.IsNegative() {
+			return NewRequestError(http.StatusBadRequest, "Counted quantities cannot be negative")
+		}
+
+		stockQuantity := counted.Quantity
+		if counted.StockItemID != "" {
+			var item models.StockItem
+			if err := tx.Where("stock_item_id = ?", counted.StockItemID).First(&item).Error; err != nil {
+				return NewRequestError(http.StatusBadRequest, fmt.Sprintf("Stock item %q does not exist", counted.StockItemID))
+			}
+			counted.IngredientID = item.IngredientID
+			stockQuantity = counted.Quantity.Scale(item.PackSize)
+		}
+		if !onSheet[counted.IngredientID] {
+			return NewRequestError(http.StatusBadRequest, fmt.Sprintf("Ingredient %q is not on this count sheet", counted.IngredientID))
+		}
+
+		var entry models.StockCountEntry
+		err := tx.Where("stock_count_id = ? AND ingredient_id = ? AND stock_item_id = ? AND device_id = ?",
+			count.StockCountID, counted.IngredientID, counted.StockItemID, deviceId).First(&entry).Error
+		switch {
+		case err == nil:
+			if err := tx.Model(&entry).Updates(map[string]interface{}{
+				"quantity":       counted.Quantity,
+				"stock_quantity": stockQuantity,
+				"counted_by":     actorUid,
+			}).Error; err != nil {
+				return err
+			}
+		case err == gorm.ErrRecordNotFound:
+			entry = models.StockCountEntry{
+				StockCountID:  count.StockCountID,
+				IngredientID:  counted.IngredientID,
+				StockItemID:   counted.StockItemID,
+				DeviceID:      deviceId,
+				Quantity:      counted.Quantity,
+				StockQuantity: stockQuantity,
+				CountedBy:     actorUid,
+			}
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StockCountVariance returns the lines of a stock count with their variances. For an open count
+// the variance is worked out live against the current on-hand quantities; once a count is closed
+// the figures recorded when it was closed are returned.
+func StockCountVariance(tx *gorm.DB, count *models.StockCount) ([]models.StockCountLine, error) {
+	if count.Status != models.StockCountStatusOpen {
+		var lines []models.StockCountLine
+		err := tx.Where("stock_count_id = ?", count.StockCountID).Order("name ASC").Find(&lines).Error
+		return lines, err
+	}
+	return computeStockCountLines(tx, count, false)
+}
+
+// computeStockCountLines adds up the entries of each line and compares them with the ingredient's
+// on-hand quantity, optionally locking the ingredients so they cannot move until the transaction ends
+func computeStockCountLines(tx *gorm.DB, count *models.StockCount, lock bool) ([]models.StockCountLine, error) {
+	var lines []models.StockCountLine
+	if err := tx.Where("stock_count_id = ?", count.StockCountID).Order("name ASC").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+
+	var totals []struct {
+		IngredientID string
+		Quantity     models.Quantity
+	}
+	if err := tx.Model(&models.StockCountEntry{}).
+		Select("ingredient_id, SUM(stock_quantity) AS quantity").
+		Where("stock_count_id = ?", count.StockCountID).
+		Group("ingredient_id").
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	counted := make(map[string]models.Quantity)
+	for _, total := range totals {
+		counted[total.IngredientID] = total.Quantity
+	}
+
+	ingredientIds := make([]string, 0, len(lines))
+	for _, line := range lines {
+		ingredientIds = append(ingredientIds, line.IngredientID)
+	}
+	query := tx.Where("ingredient_id IN ?", ingredientIds).Order("ingredient_id ASC")
+	if lock {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var ingredients []models.Ingredient
+	if err := query.Find(&ingredients).Error; err != nil {
+		return nil, err
+	}
+	byId := make(map[string]models.Ingredient)
+	for _, ingredient := range ingredients {
+		byId[ingredient.IngredientID] = ingredient
+	}
+
+	currency := models.DefaultCurrency()
+	for i := range lines {
+		line := &lines[i]
+		ingredient := byId[line.IngredientID]
+		line.ExpectedQuantity = ingredient.OnHand
+		line.UnitCost = ingredient.UnitCost
+
+		quantity, ok := counted[line.IngredientID]
+		line.Counted = ok
+		if !ok {
+			line.CountedQuantity, line.Variance, line.VarianceCost = 0, 0, 0
+			continue
+		}
+		line.CountedQuantity = quantity
+		line.Variance = quantity.Sub(line.ExpectedQuantity)
+		line.VarianceCost = line.Variance.Cost(line.UnitCost).Round(currency)
+	}
+
+	return lines, nil
+}
+
+// CloseStockCount records the variance of every line, sets each counted ingredient's on-hand
+// quantity to the counted quantity through a stock movement and locks the count. Ingredients that
+// were not counted are left as they are. The returned availability changes must be published once
+// the transaction commits.
+func CloseStockCount(tx *gorm.DB, count *models.StockCount, actorUid string) ([]AvailabilityChange, error) {
+	if count.Status != models.StockCountStatusOpen {
+		return nil, NewRequestError(http.StatusBadRequest, "Only an open stock count can be closed")
+	}
+
+	lines, err := computeStockCountLines(tx, count, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var moved []string
+	for i := range lines {
+		line := &lines[i]
+		if err := tx.Model(line).
+			Select("counted", "expected_quantity", "counted_quantity", "variance", "unit_cost", "variance_cost").
+			Updates(line).Error; err != nil {
+			return nil, err
+		}
+		if !line.Counted || line.Variance.IsZero() {
+			continue
+		}
+
+		if err := AdjustStock(tx, &models.StockMovement{
+			IngredientID: line.IngredientID,
+			Quantity:     line.Variance,
+			SourceType:   models.StockSourceStockCount,
+			SourceID:     line.LineID,
+			Reason:       "stock count",
+			ActorUID:     actorUid,
+		}); err != nil {
+			return nil, err
+		}
+		moved = append(moved, line.IngredientID)
+	}
+
+	now := time.Now()
+	count.Status = models.StockCountStatusClosed
+	count.ClosedBy = actorUid
+	count.ClosedAt = &now
+	if err := tx.Model(count).Select("status", "closed_by", "closed_at").Updates(count).Error; err != nil {
+		return nil, err
+	}
+	count.Lines = lines
+
+	return RefreshStockAvailability(tx, moved)
+}
+
+// uniqueStrings returns the distinct values of a slice in their original order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
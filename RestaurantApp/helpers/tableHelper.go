@@ -0,0 +1,456 @@
+package helpers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Live table statuses shown on the floor plan
+const (
+	TableStatusFree     = "free"
+	TableStatusOrdering = "ordering"
+	TableStatusServed   = "served"
+	TableStatusInvoiced = "invoiced"
+)
+
+// ValidateTable checks a table's covers and shape. Fields left at their zero value are not checked,
+// so a partial update can be validated on its own.
+func ValidateTable(table *models.Table) error {
+	if table.MinCovers < 0 || table.MaxCovers < 0 {
+		return NewRequestError(http.StatusBadRequest, "Table covers cannot be negative")
+	}
+	if table.MinCovers > 0 && table.MaxCovers > 0 && table.MinCovers > table.MaxCovers {
+		return NewRequestError(http.StatusBadRequest, "A table's minimum covers cannot be more than its maximum")
+	}
+	if table.Width < 0 || table.Height < 0 {
+		return NewRequestError(http.StatusBadRequest, "A table's width and height cannot be negative")
+	}
+	switch table.Shape {
+	case "", models.TableShapeRound, models.TableShapeSquare, models.TableShapeRectangle:
+		return nil
+	default:
+		return NewRequestError(http.StatusBadRequest, "A table's shape must be round, square or rectangle")
+	}
+}
+
+// TablesForParty keeps the tables that suit a party size and orders them smallest first, so that
+// large tables are kept for large parties. Tables without a maximum go last.
+func TablesForParty(tables []models.Table, partySize int) []models.Table {
+	fitting := make([]models.Table, 0, len(tables))
+	for _, table := range tables {
+		if table.Fits(partySize) {
+			fitting = append(fitting, table)
+		}
+	}
+	sort.SliceStable(fitting, func(i, j int) bool {
+		a, b := fitting[i].MaxCovers, fitting[j].MaxCovers
+		if a == 0 || b == 0 {
+			return b == 0 && a != 0
+		}
+		return a < b
+	})
+	return fitting
+}
+
+// FloorPlanTable is a table with its live status: the active order at it, if any, and the next
+// booked reservation
+type FloorPlanTable struct {
+	models.Table
+	Status          string     `json:"status"`
+	OrderID         string     `json:"order_id,omitempty"`
+	OrderStatus     string     `json:"order_status,omitempty"`
+	SeatedAt        *time.Time `json:"seated_at,omitempty"`
+	InvoiceID       string     `json:"invoice_id,omitempty"`
+	NextReservation *time.Time `json:"next_reservation,omitempty"`
+}
+
+// BuildFloorPlan works out the live status of each table from its active order and that order's
+// invoice: free without an active order, invoiced once the bill has been raised, served once the
+// food is on the table and ordering before that. Grouped tables share the status of the group's order.
+func BuildFloorPlan(tx *gorm.DB, tables []models.Table) ([]FloorPlanTable, error) {
+	plan := make([]FloorPlanTable, 0, len(tables))
+	if len(tables) == 0 {
+		return plan, nil
+	}
+
+	tableIds := make([]string, 0, len(tables))
+	for _, table := range tables {
+		tableIds = append(tableIds, table.TableID)
+	}
+
+	activeOrders, err := ActiveOrdersByTable(tx, tableIds)
+	if err != nil {
+		return nil, err
+	}
+	orderIds := make([]string, 0, len(activeOrders))
+	for _, order := range activeOrders {
+		orderIds = append(orderIds, order.OrderID)
+	}
+
+	invoiceIds := make(map[string]string)
+	if len(orderIds) > 0 {
+		var invoices []models.Invoice
+		if err := tx.Select("invoice_id, order_id").Where("order_id IN ?", orderIds).Find(&invoices).Error; err != nil {
+			return nil, err
+		}
+		for _, invoice := range invoices {
+			invoiceIds[invoice.OrderID] = invoice.InvoiceID
+		}
+	}
+
+	var reservations []models.Reservation
+	if err := tx.Select("table_id, MIN(start_time) AS start_time").
+		Where("table_id IN ? AND status = ? AND start_time > ?", tableIds, models.ReservationStatusBooked, time.Now()).
+		Group("table_id").Find(&reservations).Error; err != nil {
+		return nil, err
+	}
+	nextReservations := make(map[string]time.Time)
+	for _, reservation := range reservations {
+		nextReservations[reservation.TableID] = reservation.StartTime
+	}
+
+	for _, table := range tables {
+		entry := FloorPlanTable{Table: table, Status: TableStatusFree}
+		if order, ok := activeOrders[table.TableID]; ok {
+			orderDate := order.OrderDate
+			entry.OrderID = order.OrderID
+			entry.OrderStatus = order.OrderStatus
+			entry.SeatedAt = &orderDate
+			entry.InvoiceID = invoiceIds[order.OrderID]
+
+			switch {
+			case entry.InvoiceID != "" || order.OrderStatus == models.OrderStatusInvoiced:
+				entry.Status = TableStatusInvoiced
+			case order.OrderStatus == models.OrderStatusServed:
+				entry.Status = TableStatusServed
+			default:
+				entry.Status = TableStatusOrdering
+			}
+		}
+		if start, ok := nextReservations[table.TableID]; ok {
+			entry.NextReservation = &start
+		}
+		plan = append(plan, entry)
+	}
+
+	return plan, nil
+}
+
+// ActiveOrdersByTable returns the active order occupying each of the given tables, whether it was
+// placed at the table itself or at another table of the same group
+func ActiveOrdersByTable(tx *gorm.DB, tableIds []string) (map[string]models.Order, error) {
+	occupied := make(map[string]models.Order)
+	if len(tableIds) == 0 {
+		return occupied, nil
+	}
+
+	var tables []models.Table
+	if err := tx.Select("table_id, group_id").Where("table_id IN ?", tableIds).Find(&tables).Error; err != nil {
+		return nil, err
+	}
+
+	// seats maps each table an order could be placed at to the requested tables it would occupy
+	seats := make(map[string][]string)
+	grouped := make(map[string][]string)
+	for _, table := range tables {
+		seats[table.TableID] = append(seats[table.TableID], table.TableID)
+		if table.GroupID != "" {
+			grouped[table.GroupID] = append(grouped[table.GroupID], table.TableID)
+		}
+	}
+	if len(grouped) > 0 {
+		groupIds := make([]string, 0, len(grouped))
+		for groupId := range grouped {
+			groupIds = append(groupIds, groupId)
+		}
+
+		var mates []models.Table
+		if err := tx.Select("table_id, group_id").Where("group_id IN ?", groupIds).Find(&mates).Error; err != nil {
+			return nil, err
+		}
+		for _, mate := range mates {
+			for _, tableId := range grouped[mate.GroupID] {
+				if tableId != mate.TableID {
+					seats[mate.TableID] = append(seats[mate.TableID], tableId)
+				}
+			}
+		}
+	}
+
+	seatIds := make([]string, 0, len(seats))
+	for tableId := range seats {
+		seatIds = append(seatIds, tableId)
+	}
+
+	var orders []models.Order
+	if err := tx.Where("table_id IN ? AND order_status NOT IN ?", seatIds, models.TerminalOrderStatuses).
+		Order("order_date ASC").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		for _, tableId := range seats[order.TableID] {
+			if _, ok := occupied[tableId]; !ok {
+				occupied[tableId] = order
+			}
+		}
+	}
+
+	return occupied, nil
+}
+
+// LockTableWithGroup locks a table and, if it is grouped, every other table of its group. The table
+// is locked first so its group cannot change while the group is read; the rest of the group is then
+// locked in table id order. It returns the locked tables.
+func LockTableWithGroup(tx *gorm.DB, tableId string) ([]models.Table, error) {
+	table, err := LockTable(tx, tableId)
+	if err != nil {
+		return nil, err
+	}
+	if table.GroupID == "" {
+		return []models.Table{table}, nil
+	}
+
+	var groupMates []models.Table
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("group_id = ? AND table_id <> ?", table.GroupID, table.TableID).
+		Order("table_id ASC").Find(&groupMates).Error; err != nil {
+		return nil, err
+	}
+	return append([]models.Table{table}, groupMates...), nil
+}
+
+// CheckTablesFree returns a conflict error if any of the tables is occupied by an active order
+// other than the one excluded
+func CheckTablesFree(tx *gorm.DB, tables []models.Table, excludeOrderId string) error {
+	tableIds := make([]string, 0, len(tables))
+	for _, table := range tables {
+		tableIds = append(tableIds, table.TableID)
+	}
+
+	query := tx.Model(&models.Order{}).Where("table_id IN ? AND order_status NOT IN ?", tableIds, models.TerminalOrderStatuses)
+	if excludeOrderId != "" {
+		query = query.Where("order_id <> ?", excludeOrderId)
+	}
+
+	var activeOrderCount int64
+	if err := query.Count(&activeOrderCount).Error; err != nil {
+		return err
+	}
+	if activeOrderCount > 0 {
+		return NewRequestError(http.StatusConflict, "This table is already occupied by an active order. Please choose a different table.")
+	}
+	return nil
+}
+
+// Reasons a free table is kept for a party rather than given to whoever asks first
+const (
+	tableHeldForWaitlist    = "waitlist"
+	tableHeldForReservation = "reservation"
+)
+
+// heldTables returns why each of the tables that is kept for a party is held: it has been offered
+// to a notified waitlist party, or it is booked by a reservation that has started or starts before
+// a party seated now would be expected to leave. Holds for the excluded waitlist entry and
+// reservation are ignored.
+func heldTables(tx *gorm.DB, tableIds []string, excludeWaitlistId, excludeReservationId string) (map[string]string, error) {
+	held := make(map[string]string)
+	if len(tableIds) == 0 {
+		return held, nil
+	}
+
+	waitlistQuery := tx.Model(&models.WaitlistEntry{}).
+		Where("status = ? AND table_id IN ?", models.WaitlistStatusNotified, tableIds)
+	if excludeWaitlistId != "" {
+		waitlistQuery = waitlistQuery.Where("waitlist_id <> ?", excludeWaitlistId)
+	}
+	var offered []string
+	if err := waitlistQuery.Pluck("table_id", &offered).Error; err != nil {
+		return nil, err
+	}
+	for _, tableId := range offered {
+		held[tableId] = tableHeldForWaitlist
+	}
+
+	now := time.Now()
+	stay := time.Duration(DefaultReservationDuration()) * time.Minute
+	reservationQuery := tx.Model(&models.Reservation{}).
+		Where("status = ? AND table_id IN ? AND start_time < ? AND end_time > ?", models.ReservationStatusBooked, tableIds, now.Add(stay), now)
+	if excludeReservationId != "" {
+		reservationQuery = reservationQuery.Where("reservation_id <> ?", excludeReservationId)
+	}
+	var booked []string
+	if err := reservationQuery.Pluck("table_id", &booked).Error; err != nil {
+		return nil, err
+	}
+	for _, tableId := range booked {
+		if _, ok := held[tableId]; !ok {
+			held[tableId] = tableHeldForReservation
+		}
+	}
+
+	return held, nil
+}
+
+// CheckTablesNotHeld returns a conflict error if any of the tables is kept for a notified waitlist
+// party or an imminent reservation, other than the waitlist entry and reservation being seated
+func CheckTablesNotHeld(tx *gorm.DB, tables []models.Table, waitlistId, reservationId string) error {
+	tableIds := make([]string, 0, len(tables))
+	for _, table := range tables {
+		tableIds = append(tableIds, table.TableID)
+	}
+
+	held, err := heldTables(tx, tableIds, waitlistId, reservationId)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		switch held[table.TableID] {
+		case tableHeldForWaitlist:
+			return NewRequestError(http.StatusConflict, fmt.Sprintf("Table %q is being held for a party on the waitlist", table.TableName))
+		case tableHeldForReservation:
+			return NewRequestError(http.StatusConflict, fmt.Sprintf("Table %q is reserved for a party arriving soon", table.TableName))
+		}
+	}
+	return nil
+}
+
+// CreateTableGroup pushes two or more ungrouped tables together. At most one of them may already
+// have an active order, which then occupies the whole group.
+func CreateTableGroup(tx *gorm.DB, group *models.TableGroup, tableIds []string) error {
+	tableIds = uniqueStrings(tableIds)
+	if len(tableIds) < 2 {
+		return NewRequestError(http.StatusBadRequest, "A table group needs at least two tables")
+	}
+
+	var tables []models.Table
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("table_id IN ?", tableIds).Order("table_id ASC").Find(&tables).Error; err != nil {
+		return err
+	}
+	if len(tables) != len(tableIds) {
+		return NewRequestError(http.StatusBadRequest, "One or more tables referenced do not exist")
+	}
+
+	names := make([]string, 0, len(tables))
+	for _, table := range tables {
+		if table.GroupID != "" {
+			return NewRequestError(http.StatusConflict, fmt.Sprintf("Table %q is already part of another group", table.TableName))
+		}
+		names = append(names, table.TableName)
+	}
+
+	var orderIds []string
+	if err := tx.Model(&models.Order{}).
+		Where("table_id IN ? AND order_status NOT IN ?", tableIds, models.TerminalOrderStatuses).
+		Pluck("order_id", &orderIds).Error; err != nil {
+		return err
+	}
+	if len(orderIds) > 1 {
+		return NewRequestError(http.StatusConflict, "Tables with different active orders cannot be grouped")
+	}
+
+	if group.Name == "" {
+		group.Name = strings.Join(names, " + ")
+	}
+	if err := tx.Create(group).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.Table{}).Where("table_id IN ?", tableIds).Update("group_id", group.TableGroupID).Error; err != nil {
+		return err
+	}
+	for i := range tables {
+		tables[i].GroupID = group.TableGroupID
+	}
+	group.Tables = tables
+	return nil
+}
+
+// ReleaseTableGroup splits a group back into separate tables and returns their ids. An active
+// order stays at the table it was placed at.
+func ReleaseTableGroup(tx *gorm.DB, groupId string) ([]string, error) {
+	var tables []models.Table
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("group_id = ?", groupId).Order("table_id ASC").Find(&tables).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(&models.Table{}).Where("group_id = ?", groupId).Update("group_id", "").Error; err != nil {
+		return nil, err
+	}
+
+	result := tx.Where("table_group_id = ?", groupId).Delete(&models.TableGroup{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	tableIds := make([]string, 0, len(tables))
+	for _, table := range tables {
+		tableIds = append(tableIds, table.TableID)
+	}
+	return tableIds, nil
+}
+
+// TransferOrder moves an active order, with its items and open kitchen tickets, to another table
+// that is not occupied by a different order. The returned kitchen events must be published once
+// the transaction commits.
+func TransferOrder(tx *gorm.DB, order *models.Order, tableId string) ([]models.KitchenEvent, error) {
+	for _, status := range models.TerminalOrderStatuses {
+		if order.OrderStatus == status {
+			return nil, NewRequestError(http.StatusBadRequest, "Only an active order can be moved to another table")
+		}
+	}
+	if tableId == order.TableID {
+		return nil, NewRequestError(http.StatusBadRequest, "The order is already at this table")
+	}
+
+	tables, err := LockTableWithGroup(tx, tableId)
+	if err != nil {
+		return nil, NewRequestError(http.StatusBadRequest, "The table referenced does not exist")
+	}
+	if err := CheckTablesFree(tx, tables, order.OrderID); err != nil {
+		return nil, err
+	}
+
+	order.TableID = tableId
+	if err := tx.Model(order).Update("table_id", tableId).Error; err != nil {
+		return nil, err
+	}
+
+	var tickets []models.KitchenTicket
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status IN ?", order.OrderID, []string{models.TicketStatusQueued, models.TicketStatusInProgress}).
+		Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+
+	var events []models.KitchenEvent
+	for i := range tickets {
+		ticket := &tickets[i]
+		ticket.TableID = tableId
+		if err := tx.Model(ticket).Update("table_id", tableId).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("ticket_id = ?", ticket.TicketID).Order("id ASC").Find(&ticket.Items).Error; err != nil {
+			return nil, err
+		}
+
+		event, err := RecordKitchenEvent(tx, KitchenEventTicketUpdated, ticket)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
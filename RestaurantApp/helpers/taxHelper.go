@@ -0,0 +1,221 @@
+package helpers
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+)
+
+// Tax pricing modes: menu prices either include tax or have tax added on top
+const (
+	TaxPricingExclusive = "exclusive"
+	TaxPricingInclusive = "inclusive"
+)
+
+// basisPointsPerUnit is the number of basis points in a whole, so 1500 basis points are 15%
+const basisPointsPerUnit = 10000
+
+var (
+	taxConfigMu      sync.RWMutex
+	pricesIncludeTax = false
+	taxRounding      = models.TaxRoundingLine
+)
+
+// ConfigureTax sets whether menu prices are "exclusive" or "inclusive" of tax, and whether tax is
+// rounded per "line" or per "invoice". Empty values keep the defaults: exclusive, per line.
+func ConfigureTax(pricing, rounding string) error {
+	taxConfigMu.Lock()
+	defer taxConfigMu.Unlock()
+
+	switch pricing {
+	case "":
+	case TaxPricingExclusive:
+		pricesIncludeTax = false
+	case TaxPricingInclusive:
+		pricesIncludeTax = true
+	default:
+		return fmt.Errorf("tax pricing must be %s or %s, not %q", TaxPricingExclusive, TaxPricingInclusive, pricing)
+	}
+
+	switch rounding {
+	case "":
+	case models.TaxRoundingLine, models.TaxRoundingInvoice:
+		taxRounding = rounding
+	default:
+		return fmt.Errorf("tax rounding must be %s or %s, not %q", models.TaxRoundingLine, models.TaxRoundingInvoice, rounding)
+	}
+
+	return nil
+}
+
+// TaxSettings reports whether menu prices include tax and how tax is rounded
+func TaxSettings() (bool, string) {
+	taxConfigMu.RLock()
+	defer taxConfigMu.RUnlock()
+	return pricesIncludeTax, taxRounding
+}
+
+// itemTax is the part of one tax rate's taxable amount and tax that comes from one order item
+type itemTax struct {
+	taxable models.Money
+	tax     models.Money
+}
+
+// TaxBreakdown is the tax on a set of order items, broken down by rate. Subtotal is the amount
+// before tax and Total the amount the guest pays, so Total is always Subtotal plus TaxAmount.
+type TaxBreakdown struct {
+	PricesIncludeTax bool
+	Rounding         string
+	Subtotal         models.Money
+	TaxAmount        models.Money
+	Total            models.Money
+	Lines            []models.InvoiceTaxLine
+
+	// items holds what each order item contributes to each rate, by order item id and tax rate id
+	items map[string]map[string]itemTax
+}
+
+// ComputeOrderTax works out the tax on order items from the tax categories of their foods and the
+// current rates of those categories. With inclusive pricing the tax is taken out of the line
+// totals, otherwise it is added to them. With per line rounding each item's tax is rounded to the
+// currency before it is added up; with per invoice rounding each rate's total is rounded once.
+func ComputeOrderTax(tx *gorm.DB, currency string, items []models.OrderItem) (TaxBreakdown, error) {
+	foodIds := make([]string, 0, len(items))
+	for _, item := range items {
+		foodIds = append(foodIds, item.FoodID)
+	}
+	var foods []models.Food
+	if len(foodIds) > 0 {
+		if err := tx.Select("food_id", "tax_category_id").Where("food_id IN ? AND tax_category_id <> ''", foodIds).Find(&foods).Error; err != nil {
+			return TaxBreakdown{}, err
+		}
+	}
+	categoryOf := make(map[string]string, len(foods))
+	categoryIds := make([]string, 0, len(foods))
+	for _, food := range foods {
+		categoryOf[food.FoodID] = food.TaxCategoryID
+		categoryIds = append(categoryIds, food.TaxCategoryID)
+	}
+
+	var rates []models.TaxRate
+	if len(categoryIds) > 0 {
+		if err := tx.Where("tax_category_id IN ?", categoryIds).Order("id ASC").Find(&rates).Error; err != nil {
+			return TaxBreakdown{}, err
+		}
+	}
+
+	includeTax, rounding := TaxSettings()
+	return taxOrderItems(currency, items, categoryOf, rates, includeTax, rounding), nil
+}
+
+// taxOrderItems works out the tax on order items given the tax category of each food, by food id,
+// and the rates of those categories, in the order their lines are listed
+func taxOrderItems(currency string, items []models.OrderItem, categoryOf map[string]string, rates []models.TaxRate, includeTax bool, rounding string) TaxBreakdown {
+	breakdown := TaxBreakdown{
+		PricesIncludeTax: includeTax,
+		Rounding:         rounding,
+		items:            make(map[string]map[string]itemTax),
+	}
+
+	ratesOf := make(map[string][]models.TaxRate)
+	for _, rate := range rates {
+		ratesOf[rate.TaxCategoryID] = append(ratesOf[rate.TaxCategoryID], rate)
+	}
+
+	mode := models.LookupCurrency(currency).Mode
+	totals := make(map[string]*itemTax)
+	lineTotal := models.Money(0)
+	for _, item := range items {
+		lineTotal = lineTotal.Add(item.LineTotal)
+
+		itemRates := ratesOf[categoryOf[item.FoodID]]
+		if len(itemRates) == 0 {
+			continue
+		}
+
+		// Every rate is charged on the net price, so an inclusive price holds the net price plus all of them
+		den := int64(basisPointsPerUnit)
+		if includeTax {
+			for _, rate := range itemRates {
+				den += rate.RateBasisPoints
+			}
+		}
+
+		taxes := make([]models.Money, len(itemRates))
+		net := item.LineTotal
+		for i, rate := range itemRates {
+			taxes[i] = item.LineTotal.MulRatio(rate.RateBasisPoints, den, mode)
+			if rounding == models.TaxRoundingLine {
+				taxes[i] = taxes[i].Round(currency)
+			}
+			if includeTax {
+				net = net.Sub(taxes[i])
+			}
+		}
+
+		contributions := make(map[string]itemTax, len(itemRates))
+		for i, rate := range itemRates {
+			contributions[rate.TaxRateID] = itemTax{taxable: net, tax: taxes[i]}
+			total, ok := totals[rate.TaxRateID]
+			if !ok {
+				total = &itemTax{}
+				totals[rate.TaxRateID] = total
+			}
+			total.taxable = total.taxable.Add(net)
+			total.tax = total.tax.Add(taxes[i])
+		}
+		breakdown.items[item.OrderItemID] = contributions
+	}
+
+	for _, rate := range rates {
+		total, ok := totals[rate.TaxRateID]
+		if !ok {
+			continue
+		}
+		line := models.InvoiceTaxLine{
+			TaxRateID:       rate.TaxRateID,
+			Name:            rate.Name,
+			RateBasisPoints: rate.RateBasisPoints,
+			TaxableAmount:   total.taxable.Round(currency),
+			TaxAmount:       total.tax.Round(currency),
+			Currency:        currency,
+		}
+		breakdown.TaxAmount = breakdown.TaxAmount.Add(line.TaxAmount)
+		breakdown.Lines = append(breakdown.Lines, line)
+	}
+
+	if includeTax {
+		breakdown.Total = lineTotal.Round(currency)
+		breakdown.Subtotal = breakdown.Total.Sub(breakdown.TaxAmount)
+	} else {
+		breakdown.Subtotal = lineTotal.Round(currency)
+		breakdown.Total = breakdown.Subtotal.Add(breakdown.TaxAmount)
+	}
+	return breakdown
+}
+
+// TaxInvoice prices an invoice for a whole order: it works out the order's tax and sets the
+// invoice's subtotal, tax, total and tax breakdown. The breakdown is stored with the invoice, so
+// later changes to the rates do not alter it.
+func TaxInvoice(tx *gorm.DB, invoice *models.Invoice, order *models.Order) error {
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", order.OrderID).Order("id ASC").Find(&items).Error; err != nil {
+		return err
+	}
+
+	breakdown, err := ComputeOrderTax(tx, order.Currency, items)
+	if err != nil {
+		return err
+	}
+
+	invoice.Currency = order.Currency
+	invoice.Subtotal = breakdown.Subtotal
+	invoice.TaxAmount = breakdown.TaxAmount
+	invoice.TotalAmount = breakdown.Total
+	invoice.PricesIncludeTax = breakdown.PricesIncludeTax
+	invoice.TaxRounding = breakdown.Rounding
+	invoice.TaxLines = breakdown.Lines
+	return nil
+}
@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/RestaurantApp/models"
+)
+
+// testTaxCategories puts two foods in a food category taxed at 15% and a drink in an alcohol
+// category taxed at 15% plus a 10% excise; water is not taxed
+var testTaxCategories = map[string]string{
+	"burger": "food",
+	"salad":  "food",
+	"beer":   "alcohol",
+}
+
+var testTaxRates = []models.TaxRate{
+	{TaxRateID: "vat", TaxCategoryID: "food", Name: "VAT", RateBasisPoints: 1500},
+	{TaxRateID: "alcohol-vat", TaxCategoryID: "alcohol", Name: "VAT", RateBasisPoints: 1500},
+	{TaxRateID: "excise", TaxCategoryID: "alcohol", Name: "Excise", RateBasisPoints: 1000},
+}
+
+func testOrderItem(id, foodId string, lineTotal models.Money, seat int) models.OrderItem {
+	return models.OrderItem{OrderItemID: id, FoodID: foodId, Quantity: 1, UnitPrice: lineTotal, LineTotal: lineTotal, Seat: seat}
+}
+
+func TestTaxOrderItems(t *testing.T) {
+	mixed := []models.OrderItem{
+		testOrderItem("a", "burger", 101000, 1),
+		testOrderItem("b", "salad", 101000, 2),
+		testOrderItem("c", "beer", 77700, 1),
+		testOrderItem("d", "water", 10000, 2),
+	}
+
+	tests := []struct {
+		name         string
+		currency     string
+		items        []models.OrderItem
+		includeTax   bool
+		rounding     string
+		wantSubtotal models.Money
+		wantTax      models.Money
+		wantTotal    models.Money
+		wantLines    []models.Money
+	}{
+		{
+			// 1.515 on each 10.10 rounds up to 1.52 twice, where the rate's total of 3.03 would not
+			name: "exclusive per line", currency: "USD", items: mixed, rounding: models.TaxRoundingLine,
+			wantSubtotal: 289700, wantTax: 49900, wantTotal: 339600, wantLines: []models.Money{30400, 11700, 7800},
+		},
+		{
+			name: "exclusive per invoice", currency: "USD", items: mixed, rounding: models.TaxRoundingInvoice,
+			wantSubtotal: 289700, wantTax: 49800, wantTotal: 339500, wantLines: []models.Money{30300, 11700, 7800},
+		},
+		{
+			// 10.10 including 15% holds 1.3174 of tax, and 7.77 including 25% holds 0.9324 and 0.6216
+			name: "inclusive per line", currency: "USD", items: mixed, includeTax: true, rounding: models.TaxRoundingLine,
+			wantSubtotal: 247800, wantTax: 41900, wantTotal: 289700, wantLines: []models.Money{26400, 9300, 6200},
+		},
+		{
+			name: "inclusive per invoice", currency: "USD", items: mixed, includeTax: true, rounding: models.TaxRoundingInvoice,
+			wantSubtotal: 247900, wantTax: 41800, wantTotal: 289700, wantLines: []models.Money{26300, 9300, 6200},
+		},
+		{
+			name: "inclusive whole yen", currency: "JPY", items: []models.OrderItem{testOrderItem("a", "burger", 10000000, 0)},
+			includeTax: true, rounding: models.TaxRoundingLine,
+			wantSubtotal: 8700000, wantTax: 1300000, wantTotal: 10000000, wantLines: []models.Money{1300000},
+		},
+		{
+			name: "untaxed items only", currency: "USD", items: []models.OrderItem{testOrderItem("d", "water", 10000, 0)},
+			includeTax: false, rounding: models.TaxRoundingLine,
+			wantSubtotal: 10000, wantTax: 0, wantTotal: 10000, wantLines: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		breakdown := taxOrderItems(tt.currency, tt.items, testTaxCategories, testTaxRates, tt.includeTax, tt.rounding)
+
+		if breakdown.Subtotal != tt.wantSubtotal || breakdown.TaxAmount != tt.wantTax || breakdown.Total != tt.wantTotal {
+			t.Errorf("%s: subtotal, tax, total = %d, %d, %d, want %d, %d, %d", tt.name,
+				breakdown.Subtotal, breakdown.TaxAmount, breakdown.Total, tt.wantSubtotal, tt.wantTax, tt.wantTotal)
+		}
+		if breakdown.Subtotal.Add(breakdown.TaxAmount) != breakdown.Total {
+			t.Errorf("%s: subtotal %d plus tax %d is not the total %d", tt.name, breakdown.Subtotal, breakdown.TaxAmount, breakdown.Total)
+		}
+
+		if len(breakdown.Lines) != len(tt.wantLines) {
+			t.Errorf("%s: %d tax lines, want %d", tt.name, len(breakdown.Lines), len(tt.wantLines))
+			continue
+		}
+		lineTax := models.Money(0)
+		for i, line := range breakdown.Lines {
+			lineTax = lineTax.Add(line.TaxAmount)
+			if line.TaxAmount != tt.wantLines[i] {
+				t.Errorf("%s: %s line tax = %d, want %d", tt.name, line.TaxRateID, line.TaxAmount, tt.wantLines[i])
+			}
+			if line.TaxAmount != line.TaxAmount.Round(tt.currency) || line.TaxableAmount != line.TaxableAmount.Round(tt.currency) {
+				t.Errorf("%s: %s line %d on %d is not in whole minor units", tt.name, line.TaxRateID, line.TaxAmount, line.TaxableAmount)
+			}
+		}
+		if lineTax != breakdown.TaxAmount {
+			t.Errorf("%s: tax lines add up to %d, want the tax amount %d", tt.name, lineTax, breakdown.TaxAmount)
+		}
+	}
+}
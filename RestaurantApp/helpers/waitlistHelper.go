@@ -0,0 +1,302 @@
+package helpers
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// turnTimeHistoryDays is how far back completed orders are used to work out table turn times
+const turnTimeHistoryDays = 30
+
+// waitlistTransitions maps each waitlist status to the statuses it may move to next
+var waitlistTransitions = map[string][]string{
+	models.WaitlistStatusWaiting:   {models.WaitlistStatusNotified, models.WaitlistStatusSeated, models.WaitlistStatusCancelled},
+	models.WaitlistStatusNotified:  {models.WaitlistStatusSeated, models.WaitlistStatusCancelled},
+	models.WaitlistStatusSeated:    {},
+	models.WaitlistStatusCancelled: {},
+}
+
+// ActiveWaitlistStatuses lists the statuses of parties still waiting for a table
+var ActiveWaitlistStatuses = []string{models.WaitlistStatusWaiting, models.WaitlistStatusNotified}
+
+// CanTransitionWaitlist reports whether a waitlist entry may move from one status to another
+func CanTransitionWaitlist(from, to string) bool {
+	for _, next := range waitlistTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// averageTurnMinutes is the average time from placing an order to completing it at the given
+// tables over the last 30 days, or the default reservation duration without enough history
+func averageTurnMinutes(tx *gorm.DB, tableIds []string) (float64, error) {
+	var average sql.NullFloat64
+	if err := tx.Table("orders").
+		Select("AVG(EXTRACT(EPOCH FROM (order_status_history.created_at - orders.order_date)) / 60)").
+		Joins("JOIN order_status_history ON order_status_history.order_id = orders.order_id AND order_status_history.to_status = ?", models.OrderStatusCompleted).
+		Where("orders.table_id IN ? AND order_status_history.created_at >= ?", tableIds, time.Now().AddDate(0, 0, -turnTimeHistoryDays)).
+		Row().Scan(&average); err != nil {
+		return 0, err
+	}
+	if !average.Valid || average.Float64 <= 0 {
+		return float64(DefaultReservationDuration()), nil
+	}
+	return average.Float64, nil
+}
+
+// EstimateWait estimates how many minutes a party would wait for a table. Each table that suits the
+// party is expected to free up one average turn time after its order was placed; the parties ahead
+// in the queue that the same tables suit take the earliest of those tables first.
+func EstimateWait(tx *gorm.DB, partySize int, queuedBefore time.Time) (int, error) {
+	var allTables []models.Table
+	if err := tx.Find(&allTables).Error; err != nil {
+		return 0, err
+	}
+	tables := TablesForParty(allTables, partySize)
+	if len(tables) == 0 {
+		return 0, NewRequestError(http.StatusBadRequest, fmt.Sprintf("No table can seat a party of %d", partySize))
+	}
+
+	tableIds := make([]string, 0, len(tables))
+	for _, table := range tables {
+		tableIds = append(tableIds, table.TableID)
+	}
+
+	occupied, err := ActiveOrdersByTable(tx, tableIds)
+	if err != nil {
+		return 0, err
+	}
+	turnMinutes, err := averageTurnMinutes(tx, tableIds)
+	if err != nil {
+		return 0, err
+	}
+	turn := time.Duration(turnMinutes * float64(time.Minute))
+
+	var held []string
+	if err := tx.Model(&models.WaitlistEntry{}).
+		Where("status = ? AND table_id IN ?", models.WaitlistStatusNotified, tableIds).
+		Pluck("table_id", &held).Error; err != nil {
+		return 0, err
+	}
+	heldTables := make(map[string]bool)
+	for _, tableId := range held {
+		heldTables[tableId] = true
+	}
+
+	now := time.Now()
+	freeAt := make([]time.Time, 0, len(tables))
+	for _, table := range tables {
+		switch order, ok := occupied[table.TableID]; {
+		case ok:
+			at := order.OrderDate.Add(turn)
+			if at.Before(now) {
+				at = now
+			}
+			freeAt = append(freeAt, at)
+		case heldTables[table.TableID]:
+			freeAt = append(freeAt, now.Add(turn))
+		default:
+			freeAt = append(freeAt, now)
+		}
+	}
+	sort.Slice(freeAt, func(i, j int) bool { return freeAt[i].Before(freeAt[j]) })
+
+	var queued []models.WaitlistEntry
+	if err := tx.Select("party_size").
+		Where("status = ? AND created_at < ?", models.WaitlistStatusWaiting, queuedBefore).
+		Find(&queued).Error; err != nil {
+		return 0, err
+	}
+	ahead := 0
+	for _, entry := range queued {
+		for _, table := range tables {
+			if table.Fits(entry.PartySize) {
+				ahead++
+				break
+			}
+		}
+	}
+
+	at := freeAt[ahead%len(freeAt)].Add(time.Duration(ahead/len(freeAt)) * turn)
+	return int(math.Ceil(at.Sub(now).Minutes())), nil
+}
+
+// AddToWaitlist quotes a wait time for a party and puts it at the back of the queue
+func AddToWaitlist(tx *gorm.DB, entry *models.WaitlistEntry) error {
+	if entry.PartySize <= 0 {
+		return NewRequestError(http.StatusBadRequest, "The party size must be at least one")
+	}
+
+	now := time.Now()
+	quoted, err := EstimateWait(tx, entry.PartySize, now)
+	if err != nil {
+		return err
+	}
+
+	entry.Status = models.WaitlistStatusWaiting
+	entry.QuotedWaitMinutes = quoted
+	entry.EstimatedWaitMinutes = quoted
+	entry.TableID, entry.OrderID = "", ""
+	entry.NotifiedAt, entry.SeatedAt, entry.CancelledAt, entry.WaitedMinutes = nil, nil, nil, nil
+	return tx.Create(entry).Error
+}
+
+// tableReadyNotification tells a waiting party that their table is ready
+func tableReadyNotification(entry *models.WaitlistEntry, table *models.Table) Notification {
+	return Notification{
+		To:      entry.Phone,
+		Message: fmt.Sprintf("Hi %s, your table for %d (%s) is ready. Please come to the host stand.", entry.PartyName, entry.PartySize, table.TableName),
+	}
+}
+
+// NotifyWaitlistEntry offers a table to a waiting party. The returned notification must be sent
+// once the transaction commits; it is empty when the party left no phone number.
+func NotifyWaitlistEntry(tx *gorm.DB, entry *models.WaitlistEntry, table *models.Table) ([]Notification, error) {
+	if !CanTransitionWaitlist(entry.Status, models.WaitlistStatusNotified) {
+		return nil, NewRequestError(http.StatusConflict, fmt.Sprintf("A %s party cannot be notified", entry.Status))
+	}
+	if !table.Fits(entry.PartySize) {
+		return nil, NewRequestError(http.StatusBadRequest, fmt.Sprintf("Table %q does not suit a party of %d", table.TableName, entry.PartySize))
+	}
+
+	now := time.Now()
+	entry.Status = models.WaitlistStatusNotified
+	entry.TableID = table.TableID
+	entry.NotifiedAt = &now
+	if err := tx.Model(entry).Select("status", "table_id", "notified_at").Updates(entry).Error; err != nil {
+		return nil, err
+	}
+
+	if entry.Phone == "" {
+		return nil, nil
+	}
+	return []Notification{tableReadyNotification(entry, table)}, nil
+}
+
+// OfferFreedTables offers each of the given tables that is now free, not grouped, not already
+// offered and not booked by an imminent reservation to the first waiting party it suits. The
+// returned notifications must be sent once the transaction commits.
+func OfferFreedTables(tx *gorm.DB, tableIds []string) ([]Notification, error) {
+	if len(tableIds) == 0 {
+		return nil, nil
+	}
+
+	var tables []models.Table
+	if err := tx.Where("table_id IN ? AND group_id = ''", tableIds).Order("table_id ASC").Find(&tables).Error; err != nil {
+		return nil, err
+	}
+	occupied, err := ActiveOrdersByTable(tx, tableIds)
+	if err != nil {
+		return nil, err
+	}
+	held, err := heldTables(tx, tableIds, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var notifications []Notification
+	for i := range tables {
+		table := &tables[i]
+		if _, ok := occupied[table.TableID]; ok {
+			continue
+		}
+
+		if _, ok := held[table.TableID]; ok {
+			continue
+		}
+
+		var waiting []models.WaitlistEntry
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ?", models.WaitlistStatusWaiting).Order("created_at ASC, id ASC").Find(&waiting).Error; err != nil {
+			return nil, err
+		}
+		for j := range waiting {
+			if !table.Fits(waiting[j].PartySize) {
+				continue
+			}
+			sent, err := NotifyWaitlistEntry(tx, &waiting[j], table)
+			if err != nil {
+				return nil, err
+			}
+			notifications = append(notifications, sent...)
+			break
+		}
+	}
+
+	return notifications, nil
+}
+
+// OfferFinishedOrderTable offers the table of an order that has just been completed, cancelled or
+// voided to the waitlist. The returned notifications must be sent once the transaction commits.
+func OfferFinishedOrderTable(tx *gorm.DB, order *models.Order) ([]Notification, error) {
+	for _, status := range models.TerminalOrderStatuses {
+		if order.OrderStatus == status {
+			return OfferFreedTables(tx, []string{order.TableID})
+		}
+	}
+	return nil, nil
+}
+
+// SeatWaitlistEntry records that a party has been seated and how long it actually waited.
+// The order it was seated with can be linked.
+func SeatWaitlistEntry(tx *gorm.DB, entry *models.WaitlistEntry, tableId, orderId string) error {
+	if !CanTransitionWaitlist(entry.Status, models.WaitlistStatusSeated) {
+		return NewRequestError(http.StatusConflict, fmt.Sprintf("A %s party cannot be seated", entry.Status))
+	}
+
+	if orderId != "" {
+		var order models.Order
+		if err := tx.Where("order_id = ?", orderId).First(&order).Error; err != nil {
+			return NewRequestError(http.StatusBadRequest, "The order referenced does not exist")
+		}
+		if tableId == "" {
+			tableId = order.TableID
+		}
+	}
+	if tableId != "" {
+		entry.TableID = tableId
+	}
+
+	now := time.Now()
+	waited := int(math.Round(now.Sub(entry.CreatedAt).Minutes()))
+	entry.Status = models.WaitlistStatusSeated
+	entry.OrderID = orderId
+	entry.SeatedAt = &now
+	entry.WaitedMinutes = &waited
+	return tx.Model(entry).Select("status", "table_id", "order_id", "seated_at", "waited_minutes").Updates(entry).Error
+}
+
+// CancelWaitlistEntry takes a party off the waitlist. A table it had been offered is passed on to
+// the next party; the returned notifications must be sent once the transaction commits.
+func CancelWaitlistEntry(tx *gorm.DB, entry *models.WaitlistEntry) ([]Notification, error) {
+	if !CanTransitionWaitlist(entry.Status, models.WaitlistStatusCancelled) {
+		return nil, NewRequestError(http.StatusConflict, fmt.Sprintf("A %s party cannot be cancelled", entry.Status))
+	}
+
+	offeredTable := ""
+	if entry.Status == models.WaitlistStatusNotified {
+		offeredTable = entry.TableID
+	}
+
+	now := time.Now()
+	entry.Status = models.WaitlistStatusCancelled
+	entry.CancelledAt = &now
+	if err := tx.Model(entry).Select("status", "cancelled_at").Updates(entry).Error; err != nil {
+		return nil, err
+	}
+
+	if offeredTable == "" {
+		return nil, nil
+	}
+	return OfferFreedTables(tx, []string{offeredTable})
+}
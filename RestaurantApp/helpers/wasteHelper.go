@@ -0,0 +1,144 @@
+package helpers
+
+import (
+	"net/http"
+
+	"github.com/RestaurantApp/models"
+	"gorm.io/gorm"
+)
+
+// IsValidWasteReason reports whether reason is one of the waste reason codes
+func IsValidWasteReason(reason string) bool {
+	for _, known := range models.WasteReasons {
+		if known == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordWaste values a waste entry at current cost, saves it and takes the wasted ingredients out of
+// stock. Wasted food uses up its recipe, except a returned dish linked to an order item, whose
+// ingredients were already taken when the order was accepted. The returned availability changes
+// must be published once the transaction commits.
+func RecordWaste(tx *gorm.DB, entry *models.WasteEntry) ([]AvailabilityChange, error) {
+	if !IsValidWasteReason(entry.Reason) {
+		return nil, NewRequestError(http.StatusBadRequest, "Unknown waste reason")
+	}
+	if (entry.FoodID == "") == (entry.StockItemID == "") {
+		return nil, NewRequestError(http.StatusBadRequest, "A waste entry must name either a food_id or a stock_item_id")
+	}
+	if entry.Quantity.IsZero() || entry.Quantity.IsNegative() {
+		return nil, NewRequestError(http.StatusBadRequest, "The wasted quantity must be positive")
+	}
+
+	usage := make(map[string]models.Quantity)
+	if entry.StockItemID != "" {
+		var item models.StockItem
+		if err := tx.Where("stock_item_id = ?", entry.StockItemID).First(&item).Error; err != nil {
+			return nil, NewRequestError(http.StatusBadRequest, "The stock item referenced does not exist")
+		}
+
+		entry.FoodID, entry.VariantID, entry.OrderItemID = "", "", ""
+		entry.Name = item.Name
+		entry.Currency = item.Currency
+		entry.UnitCost = item.UnitCost
+		usage[item.IngredientID] = entry.Quantity.Scale(item.PackSize)
+	} else {
+		if entry.OrderItemID != "" {
+			var orderItem models.OrderItem
+			if err := tx.Where("order_item_id = ?", entry.OrderItemID).First(&orderItem).Error; err != nil {
+				return nil, NewRequestError(http.StatusBadRequest, "The order item referenced does not exist")
+			}
+			entry.FoodID, entry.VariantID = orderItem.FoodID, orderItem.VariantID
+		}
+
+		var food models.Food
+		if err := tx.Where("food_id = ?", entry.FoodID).First(&food).Error; err != nil {
+			return nil, NewRequestError(http.StatusBadRequest, "The food item referenced does not exist")
+		}
+
+		entry.Name = food.Name
+		if entry.VariantID != "" {
+			var variant models.FoodVariant
+			if err := tx.Where("variant_id = ? AND food_id = ?", entry.VariantID, food.FoodID).First(&variant).Error; err != nil {
+				return nil, NewRequestError(http.StatusBadRequest, "The variant referenced does not belong to this food item")
+			}
+			entry.Name = food.Name + " (" + variant.Name + ")"
+		}
+
+		unitCost, err := FoodUnitCost(tx, entry.FoodID, entry.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		entry.Currency = models.DefaultCurrency()
+		entry.UnitCost = unitCost
+
+		if entry.OrderItemID == "" {
+			var lines []models.Recipe
+			if err := tx.Where("food_id = ? AND (variant_id = '' OR variant_id = ?)", entry.FoodID, entry.VariantID).Find(&lines).Error; err != nil {
+				return nil, err
+			}
+			for _, line := range lines {
+				usage[line.IngredientID] = usage[line.IngredientID].Add(line.Quantity.Scale(entry.Quantity))
+			}
+		}
+	}
+
+	entry.Cost = entry.Quantity.Cost(entry.UnitCost).Round(entry.Currency)
+	if err := tx.Create(entry).Error; err != nil {
+		return nil, err
+	}
+
+	var moved []string
+	for ingredientId, quantity := range usage {
+		if err := AdjustStock(tx, &models.StockMovement{
+			IngredientID: ingredientId,
+			Quantity:     quantity.Neg(),
+			SourceType:   models.StockSourceWaste,
+			SourceID:     entry.WasteID,
+			Reason:       entry.Reason,
+			ActorUID:     entry.RecordedBy,
+		}); err != nil {
+			return nil, err
+		}
+		moved = append(moved, ingredientId)
+	}
+
+	return RefreshStockAvailability(tx, moved)
+}
+
+// DeleteWaste removes a waste entry recorded in error and puts back the stock it took
+func DeleteWaste(tx *gorm.DB, entry *models.WasteEntry, actorUid string) ([]AvailabilityChange, error) {
+	var recorded []struct {
+		IngredientID string
+		Quantity     models.Quantity
+	}
+	if err := tx.Model(&models.StockMovement{}).
+		Select("ingredient_id, SUM(quantity) AS quantity").
+		Where("source_type = ? AND source_id = ?", models.StockSourceWaste, entry.WasteID).
+		Group("ingredient_id").
+		Scan(&recorded).Error; err != nil {
+		return nil, err
+	}
+
+	var moved []string
+	for _, row := range recorded {
+		if err := AdjustStock(tx, &models.StockMovement{
+			IngredientID: row.IngredientID,
+			Quantity:     row.Quantity.Neg(),
+			SourceType:   models.StockSourceWaste,
+			SourceID:     entry.WasteID,
+			Reason:       "waste entry deleted",
+			ActorUID:     actorUid,
+		}); err != nil {
+			return nil, err
+		}
+		moved = append(moved, row.IngredientID)
+	}
+
+	if err := tx.Delete(entry).Error; err != nil {
+		return nil, err
+	}
+	return RefreshStockAvailability(tx, moved)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
 	"github.com/RestaurantApp/middleware"
 	"github.com/RestaurantApp/models"
 	routes "github.com/RestaurantApp/routes"
@@ -13,6 +14,11 @@ import (
 )
 
 func InitializeDatabase(db *gorm.DB) error {
+	// Convert legacy float money columns before GORM compares column types
+	if err := databases.ConvertMoneyColumns(db); err != nil {
+		return err
+	}
+
 	// Skip foreign key constraint checks during migration
 	// Migrate all models one by one
 	if err := db.AutoMigrate(&models.User{}); err != nil {
@@ -21,30 +27,180 @@ func InitializeDatabase(db *gorm.DB) error {
 	if err := db.AutoMigrate(&models.Table{}); err != nil {
 		return err
 	}
+	if err := db.AutoMigrate(&models.TableGroup{}); err != nil {
+		return err
+	}
 	if err := db.AutoMigrate(&models.Menu{}); err != nil {
 		return err
 	}
+	if err := db.AutoMigrate(&models.MenuSchedule{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.TaxCategory{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.TaxRate{}); err != nil {
+		return err
+	}
 	if err := db.AutoMigrate(&models.Food{}); err != nil {
 		return err
 	}
+	if err := db.AutoMigrate(&models.FoodVariant{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.ModifierGroup{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.Modifier{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.Ingredient{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.Recipe{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.StockMovement{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.Supplier{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.StockItem{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.PurchaseOrder{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.PurchaseOrderLine{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.GoodsReceipt{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.GoodsReceiptLine{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.WasteEntry{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.StockCount{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.StockCountLine{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.StockCountEntry{}); err != nil {
+		return err
+	}
 	if err := db.AutoMigrate(&models.Note{}); err != nil {
 		return err
 	}
 	if err := db.AutoMigrate(&models.Order{}); err != nil {
 		return err
 	}
+	if err := db.AutoMigrate(&models.Reservation{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.WaitlistEntry{}); err != nil {
+		return err
+	}
 	if err := db.AutoMigrate(&models.OrderItem{}); err != nil {
 		return err
 	}
+	if err := db.AutoMigrate(&models.OrderItemModifier{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.Bundle{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.BundleSlot{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.BundleSlotOption{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.OrderBundle{}); err != nil {
+		return err
+	}
+	if err := databases.BackfillOrderItemSnapshots(db); err != nil {
+		return err
+	}
 	if err := db.AutoMigrate(&models.Invoice{}); err != nil {
 		return err
 	}
+	if err := db.AutoMigrate(&models.InvoiceTaxLine{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.Payment{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.ProcessedPaymentEvent{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.DocumentSequence{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.CreditNote{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.Refund{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.OrderStatusHistory{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.Station{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.StationRoute{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.KitchenTicket{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.KitchenTicketItem{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.KitchenEvent{}); err != nil {
+		return err
+	}
+	if err := databases.BackfillKitchenEventPositions(db); err != nil {
+		return err
+	}
+	if err := databases.BackfillCurrencies(db, models.DefaultCurrency()); err != nil {
+		return err
+	}
+	if err := databases.BackfillInvoicePayments(db); err != nil {
+		return err
+	}
+	if err := databases.BackfillInvoiceSubtotals(db); err != nil {
+		return err
+	}
 
 	return nil
 }
 
 func main() {
 	db := databases.InitDB()
+	if err := models.ConfigureCurrencies(os.Getenv("CURRENCY"), os.Getenv("CURRENCY_ROUNDING")); err != nil {
+		log.Fatal("Invalid currency configuration: ", err)
+	}
+	if err := helpers.ConfigureTimezone(os.Getenv("RESTAURANT_TIMEZONE")); err != nil {
+		log.Fatal("Invalid restaurant timezone: ", err)
+	}
+	if err := helpers.ConfigureReservations(os.Getenv("RESERVATION_HOURS"), os.Getenv("RESERVATION_DURATION_MINUTES")); err != nil {
+		log.Fatal("Invalid reservation configuration: ", err)
+	}
+	if err := helpers.ConfigureTax(os.Getenv("TAX_PRICING"), os.Getenv("TAX_ROUNDING")); err != nil {
+		log.Fatal("Invalid tax configuration: ", err)
+	}
+	if err := helpers.ConfigurePayments(os.Getenv("PAYMENT_PROVIDER"), os.Getenv("PAYMENT_WEBHOOK_SECRET")); err != nil {
+		log.Fatal("Invalid payment configuration: ", err)
+	}
+	if url := os.Getenv("NOTIFY_WEBHOOK_URL"); url != "" {
+		helpers.SetNotifier(helpers.NewWebhookNotifier(url))
+	}
 	if err := InitializeDatabase(db); err != nil {
 		log.Fatal("Failed to migrate database: ", err)
 	}
@@ -58,15 +214,31 @@ func main() {
 	router.Use(gin.Logger())
 	router.Use(middleware.CORSMiddleware())
 	routes.AuthRoutes(router)
+	routes.PaymentWebhookRoutes(router)
 	router.Use(middleware.Authenticate())
 
 	routes.UserRoutes(router)
 	routes.FoodRoutes(router)
 	routes.MenuRoutes(router)
 	routes.TableRoutes(router)
+	routes.ReservationRoutes(router)
+	routes.WaitlistRoutes(router)
 	routes.OrderRoutes(router)
 	routes.OrderItemRoutes(router)
 	routes.InvoiceRoutes(router)
+	routes.CreditNoteRoutes(router)
+	routes.TaxRoutes(router)
+	routes.KitchenRoutes(router)
+	routes.StationRoutes(router)
+	routes.ModifierRoutes(router)
+	routes.FoodVariantRoutes(router)
+	routes.BundleRoutes(router)
+	routes.AvailabilityRoutes(router)
+	routes.IngredientRoutes(router)
+	routes.PurchasingRoutes(router)
+	routes.WasteRoutes(router)
+	routes.StockCountRoutes(router)
+	routes.ReportRoutes(router)
 
 	router.GET("/api-1", func(c *gin.Context) {
 		c.JSON(200, gin.H{"success": "Access granted for api-1"})
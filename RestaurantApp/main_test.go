@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RestaurantApp/databases"
+	"github.com/RestaurantApp/helpers"
+	"github.com/RestaurantApp/models"
+	routes "github.com/RestaurantApp/routes"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// concurrentRequests is how many identical requests each test fires at once
+const concurrentRequests = 12
+
+var (
+	testDBOnce sync.Once
+	testDBErr  error
+)
+
+// testRouter connects to the scratch database named by TEST_DATABASE_DSN, migrating it once, and
+// returns a router whose requests are made as an admin. The tests are skipped when it is unset,
+// since the row locks they exercise need PostgreSQL.
+func testRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set; skipping tests that need PostgreSQL")
+	}
+
+	testDBOnce.Do(func() {
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			testDBErr = err
+			return
+		}
+		databases.DB = db
+		testDBErr = InitializeDatabase(db)
+	})
+	if testDBErr != nil {
+		t.Fatalf("unable to prepare the test database: %v", testDBErr)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("uid", "concurrency-test-admin")
+		c.Set("user_type", "ADMIN")
+		c.Next()
+	})
+	routes.OrderRoutes(router)
+	routes.OrderItemRoutes(router)
+	routes.InvoiceRoutes(router)
+	routes.PaymentWebhookRoutes(router)
+	return router
+}
+
+// fireConcurrently sends the requests built by request all at once and returns their status codes
+func fireConcurrently(router http.Handler, n int, request func(i int) (string, string, interface{})) []int {
+	codes := make([]int, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		method, path, body := request(i)
+		payload, _ := json.Marshal(body)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+
+			req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			codes[i] = recorder.Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return codes
+}
+
+func countCodes(codes []int, code int) int {
+	count := 0
+	for _, c := range codes {
+		if c == code {
+			count++
+		}
+	}
+	return count
+}
+
+// createTestTable adds a table no other test seats
+func createTestTable(t *testing.T) models.Table {
+	t.Helper()
+	table := models.Table{TableID: uuid.New().String(), TableName: "Concurrency " + t.Name()}
+	if err := databases.DB.Create(&table).Error; err != nil {
+		t.Fatalf("unable to create table: %v", err)
+	}
+	return table
+}
+
+// createTestFood adds a food priced at 10.00 on an always-served menu
+func createTestFood(t *testing.T) models.Food {
+	t.Helper()
+	menu := models.Menu{MenuID: uuid.New().String(), Name: "Concurrency " + t.Name(), Category: "test"}
+	if err := databases.DB.Create(&menu).Error; err != nil {
+		t.Fatalf("unable to create menu: %v", err)
+	}
+
+	image := ""
+	food := models.Food{FoodID: uuid.New().String(), Name: "Concurrency " + t.Name(), Price: 100000, FoodImage: &image, MenuID: menu.MenuID}
+	if err := databases.DB.Create(&food).Error; err != nil {
+		t.Fatalf("unable to create food: %v", err)
+	}
+	return food
+}
+
+// createTestOrder adds an order in the given status on a fresh table
+func createTestOrder(t *testing.T, status string) models.Order {
+	t.Helper()
+	order := models.Order{
+		OrderID:     uuid.New().String(),
+		OrderDate:   time.Now(),
+		OrderStatus: status,
+		UserID:      "concurrency-test-admin",
+		TableID:     createTestTable(t).TableID,
+	}
+	if err := databases.DB.Create(&order).Error; err != nil {
+		t.Fatalf("unable to create order: %v", err)
+	}
+	return order
+}
+
+// assertOrderTotal checks that every item is priced at its quantity and the order total is their sum
+func assertOrderTotal(t *testing.T, orderId string) []models.OrderItem {
+	t.Helper()
+
+	var items []models.OrderItem
+	if err := databases.DB.Where("order_id = ?", orderId).Find(&items).Error; err != nil {
+		t.Fatalf("unable to load order items: %v", err)
+	}
+
+	sum := models.Money(0)
+	for _, item := range items {
+		want, err := item.UnitPrice.Mul(int64(item.Quantity))
+		if err != nil || item.LineTotal != want {
+			t.Errorf("item %s has line total %s for %d at %s", item.OrderItemID, item.LineTotal, item.Quantity, item.UnitPrice)
+		}
+		sum = sum.Add(item.LineTotal)
+	}
+
+	var order models.Order
+	if err := databases.DB.Where("order_id = ?", orderId).First(&order).Error; err != nil {
+		t.Fatalf("unable to load order: %v", err)
+	}
+	if order.OrderTotal != sum {
+		t.Errorf("order total is %s, want the sum of its items %s", order.OrderTotal, sum)
+	}
+	return items
+}
+
+func TestCreateOrderSeatsTableOnce(t *testing.T) {
+	router := testRouter(t)
+	t.Parallel()
+
+	table := createTestTable(t)
+	codes := fireConcurrently(router, concurrentRequests, func(i int) (string, string, interface{}) {
+		return http.MethodPost, "/orders", gin.H{"order_id": uuid.New().String(), "table_id": table.TableID}
+	})
+
+	if created := countCodes(codes, http.StatusCreated); created != 1 {
+		t.Errorf("%d orders were created on one table, want 1 (status codes %v)", created, codes)
+	}
+	if conflicts := countCodes(codes, http.StatusConflict); conflicts != concurrentRequests-1 {
+		t.Errorf("%d requests were refused as a conflict, want %d (status codes %v)", conflicts, concurrentRequests-1, codes)
+	}
+
+	var active int64
+	if err := databases.DB.Model(&models.Order{}).
+		Where("table_id = ? AND order_status NOT IN ?", table.TableID, models.TerminalOrderStatuses).
+		Count(&active).Error; err != nil {
+		t.Fatalf("unable to count orders: %v", err)
+	}
+	if active != 1 {
+		t.Errorf("table has %d active orders, want 1", active)
+	}
+}
+
+func TestCreateOrderItemMergesConcurrentAdds(t *testing.T) {
+	router := testRouter(t)
+	t.Parallel()
+
+	order := createTestOrder(t, models.OrderStatusPending)
+	food := createTestFood(t)
+	codes := fireConcurrently(router, concurrentRequests, func(i int) (string, string, interface{}) {
+		return http.MethodPost, "/orderItems", gin.H{
+			"order_item_id": uuid.New().String(),
+			"order_id":      order.OrderID,
+			"food_id":       food.FoodID,
+			"quantity":      1,
+		}
+	})
+
+	if created := countCodes(codes, http.StatusCreated); created != concurrentRequests {
+		t.Fatalf("%d of %d adds succeeded (status codes %v)", created, concurrentRequests, codes)
+	}
+
+	items := assertOrderTotal(t, order.OrderID)
+	if len(items) != 1 {
+		t.Fatalf("order has %d lines for the same food and seat, want 1", len(items))
+	}
+	if items[0].Quantity != concurrentRequests {
+		t.Errorf("merged line has quantity %d, want %d", items[0].Quantity, concurrentRequests)
+	}
+}
+
+func TestOrderItemConcurrentUpdatesAndDeletes(t *testing.T) {
+	router := testRouter(t)
+	t.Parallel()
+
+	order := createTestOrder(t, models.OrderStatusPending)
+	food := createTestFood(t)
+
+	// One line per seat, so each request below changes a different line of the same order
+	itemIds := make([]string, concurrentRequests)
+	for i := range itemIds {
+		itemIds[i] = uuid.New().String()
+		item := models.OrderItem{
+			OrderItemID: itemIds[i],
+			OrderID:     order.OrderID,
+			FoodID:      food.FoodID,
+			FoodName:    food.Name,
+			Quantity:    1,
+			UnitPrice:   food.Price,
+			LineTotal:   food.Price,
+			Seat:        i + 1,
+		}
+		if err := databases.DB.Create(&item).Error; err != nil {
+			t.Fatalf("unable to create order item: %v", err)
+		}
+	}
+
+	// Even lines are updated to a quantity of three and odd lines are removed
+	codes := fireConcurrently(router, concurrentRequests, func(i int) (string, string, interface{}) {
+		if i%2 == 0 {
+			return http.MethodPatch, "/orderItems/" + itemIds[i], gin.H{"order_item_id": itemIds[i], "quantity": 3}
+		}
+		return http.MethodDelete, "/orderItems/" + itemIds[i], nil
+	})
+
+	if ok := countCodes(codes, http.StatusOK); ok != concurrentRequests {
+		t.Fatalf("%d of %d changes succeeded (status codes %v)", ok, concurrentRequests, codes)
+	}
+
+	items := assertOrderTotal(t, order.OrderID)
+	if len(items) != concurrentRequests/2 {
+		t.Errorf("order has %d items left, want %d", len(items), concurrentRequests/2)
+	}
+	for _, item := range items {
+		if item.Quantity != 3 {
+			t.Errorf("item on seat %d has quantity %d, want 3", item.Seat, item.Quantity)
+		}
+	}
+}
+
+func TestCreateInvoiceInvoicesOrderOnce(t *testing.T) {
+	router := testRouter(t)
+	t.Parallel()
+
+	order := createTestOrder(t, models.OrderStatusServed)
+	food := createTestFood(t)
+	item := models.OrderItem{
+		OrderItemID: uuid.New().String(),
+		OrderID:     order.OrderID,
+		FoodID:      food.FoodID,
+		FoodName:    food.Name,
+		Quantity:    2,
+		UnitPrice:   food.Price,
+		LineTotal:   food.Price * 2,
+	}
+	if err := databases.DB.Create(&item).Error; err != nil {
+		t.Fatalf("unable to create order item: %v", err)
+	}
+
+	codes := fireConcurrently(router, concurrentRequests, func(i int) (string, string, interface{}) {
+		return http.MethodPost, "/invoices", gin.H{"order_id": order.OrderID}
+	})
+
+	if created := countCodes(codes, http.StatusCreated); created != 1 {
+		t.Errorf("%d invoices were created for one order, want 1 (status codes %v)", created, codes)
+	}
+	if conflicts := countCodes(codes, http.StatusConflict); conflicts != concurrentRequests-1 {
+		t.Errorf("%d requests were refused as a conflict, want %d (status codes %v)", conflicts, concurrentRequests-1, codes)
+	}
+
+	var invoices []models.Invoice
+	if err := databases.DB.Where("order_id = ?", order.OrderID).Find(&invoices).Error; err != nil {
+		t.Fatalf("unable to load invoices: %v", err)
+	}
+	if len(invoices) != 1 {
+		t.Fatalf("order has %d invoices, want 1", len(invoices))
+	}
+	if invoices[0].TotalAmount != item.LineTotal {
+		t.Errorf("invoice total is %s, want %s", invoices[0].TotalAmount, item.LineTotal)
+	}
+
+	if err := databases.DB.Where("order_id = ?", order.OrderID).First(&order).Error; err != nil {
+		t.Fatalf("unable to load order: %v", err)
+	}
+	if order.OrderStatus != models.OrderStatusInvoiced {
+		t.Errorf("order status is %q, want %q", order.OrderStatus, models.OrderStatusInvoiced)
+	}
+}
+
+func TestPaymentWebhookAppliesEventOnce(t *testing.T) {
+	router := testRouter(t)
+	if err := helpers.ConfigurePayments("mock", "concurrency-test-secret"); err != nil {
+		t.Fatalf("unable to configure payments: %v", err)
+	}
+
+	order := createTestOrder(t, models.OrderStatusInvoiced)
+	invoice := models.Invoice{
+		OrderID:        order.OrderID,
+		PaymentStatus:  models.InvoiceStatusPending,
+		PaymentMethod:  models.PaymentMethodCard,
+		PaymentDueDate: time.Now().AddDate(0, 0, 7),
+		Subtotal:       100000,
+		TotalAmount:    100000,
+		BalanceDue:     100000,
+	}
+	if err := databases.DB.Create(&invoice).Error; err != nil {
+		t.Fatalf("unable to create invoice: %v", err)
+	}
+	payment := models.Payment{
+		PaymentID:         uuid.New().String(),
+		InvoiceID:         invoice.InvoiceID,
+		Amount:            100000,
+		Currency:          invoice.Currency,
+		Method:            models.PaymentMethodCard,
+		Status:            models.PaymentStatusAuthorized,
+		Provider:          helpers.CurrentPaymentProvider().Name(),
+		ProviderReference: "mock_" + uuid.New().String(),
+		PaidAt:            time.Now(),
+	}
+	if err := databases.DB.Create(&payment).Error; err != nil {
+		t.Fatalf("unable to create payment: %v", err)
+	}
+
+	// The same signed event is delivered several times at once, as a provider retrying would
+	event := gin.H{
+		"event_id":  "evt_" + uuid.New().String(),
+		"timestamp": time.Now().Unix(),
+		"type":      "payment.captured",
+		"reference": payment.ProviderReference,
+	}
+	body, _ := json.Marshal(event)
+	signature, err := helpers.SignPaymentWebhook(body)
+	if err != nil {
+		t.Fatalf("unable to sign the event: %v", err)
+	}
+	signed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set(helpers.PaymentSignatureHeader, signature)
+		router.ServeHTTP(w, r)
+	})
+
+	codes := fireConcurrently(signed, concurrentRequests, func(i int) (string, string, interface{}) {
+		return http.MethodPost, "/payments/webhook", event
+	})
+	if ok := countCodes(codes, http.StatusOK); ok != concurrentRequests {
+		t.Fatalf("%d of %d deliveries were acknowledged (status codes %v)", ok, concurrentRequests, codes)
+	}
+
+	var processed int64
+	if err := databases.DB.Model(&models.ProcessedPaymentEvent{}).Where("event_id = ?", event["event_id"]).Count(&processed).Error; err != nil {
+		t.Fatalf("unable to count processed events: %v", err)
+	}
+	if processed != 1 {
+		t.Errorf("event was processed %d times, want 1", processed)
+	}
+
+	if err := databases.DB.Where("payment_id = ?", payment.PaymentID).First(&payment).Error; err != nil {
+		t.Fatalf("unable to load payment: %v", err)
+	}
+	if payment.Status != models.PaymentStatusCompleted {
+		t.Errorf("payment status is %q, want %q", payment.Status, models.PaymentStatusCompleted)
+	}
+
+	// A replay of the event signed afresh but sent too long ago is refused
+	event["event_id"] = "evt_" + uuid.New().String()
+	event["timestamp"] = time.Now().Add(-10 * time.Minute).Unix()
+	body, _ = json.Marshal(event)
+	if signature, err = helpers.SignPaymentWebhook(body); err != nil {
+		t.Fatalf("unable to sign the event: %v", err)
+	}
+	codes = fireConcurrently(signed, 1, func(i int) (string, string, interface{}) {
+		return http.MethodPost, "/payments/webhook", event
+	})
+	if codes[0] != http.StatusBadRequest {
+		t.Errorf("stale event got status %d, want %d", codes[0], http.StatusBadRequest)
+	}
+}
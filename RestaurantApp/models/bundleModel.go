@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Bundle is a combo meal sold at its own price. Each slot is filled with one of its options,
+// e.g. a "Main" slot with a single burger and a "Side" slot offering fries or salad.
+type Bundle struct {
+	ID        uint         `json:"id" gorm:"primary_key"`
+	BundleID  string       `json:"bundle_id" gorm:"required;uniqueIndex"`
+	Name      string       `json:"name" gorm:"required"`
+	Price     Money        `json:"price" gorm:"required"`
+	Currency  string       `json:"currency" gorm:"size:3"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	Slots     []BundleSlot `json:"slots" gorm:"foreignKey:BundleID;references:BundleID"`
+}
+
+type BundleSlot struct {
+	ID        uint               `json:"id" gorm:"primary_key"`
+	SlotID    string             `json:"slot_id" gorm:"required;uniqueIndex"`
+	BundleID  string             `json:"bundle_id" gorm:"required;index"`
+	Name      string             `json:"name" gorm:"required"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+	Options   []BundleSlotOption `json:"options" gorm:"foreignKey:SlotID;references:SlotID"`
+}
+
+// BundleSlotOption is a food that may fill a bundle slot. An empty VariantID lets the guest pick
+// any variant of the food; PriceDelta is charged on top of the bundle price, e.g. for a large side.
+type BundleSlotOption struct {
+	ID         uint      `json:"id" gorm:"primary_key"`
+	OptionID   string    `json:"option_id" gorm:"required;uniqueIndex"`
+	SlotID     string    `json:"slot_id" gorm:"required;index"`
+	FoodID     string    `json:"food_id" gorm:"required"`
+	VariantID  string    `json:"variant_id"`
+	PriceDelta Money     `json:"price_delta"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Food       Food      `json:"-" gorm:"foreignKey:FoodID;references:FoodID"`
+}
+
+// OrderBundle is a bundle ordered on an order. Its components are ordinary order items that carry
+// the line's BundleLineID, priced so that their line totals add up to the bundle line total.
+type OrderBundle struct {
+	ID           uint        `json:"id" gorm:"primary_key"`
+	BundleLineID string      `json:"bundle_line_id" gorm:"required;uniqueIndex"`
+	OrderID      string      `json:"order_id" gorm:"required;index"`
+	BundleID     string      `json:"bundle_id" gorm:"required"`
+	BundleName   string      `json:"bundle_name"`
+	Quantity     int         `json:"quantity" gorm:"required"`
+	UnitPrice    Money       `json:"unit_price"`
+	LineTotal    Money       `json:"line_total"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+	Items        []OrderItem `json:"items" gorm:"-"`
+	Order        Order       `json:"-" gorm:"foreignKey:OrderID;references:OrderID"`
+}
+
+func (bundle *Bundle) BeforeCreate(tx *gorm.DB) (err error) {
+	if bundle.BundleID == "" {
+		bundle.BundleID = uuid.New().String()
+	}
+	if bundle.Currency == "" {
+		bundle.Currency = DefaultCurrency()
+	}
+	return nil
+}
+
+func (slot *BundleSlot) BeforeCreate(tx *gorm.DB) (err error) {
+	if slot.SlotID == "" {
+		slot.SlotID = uuid.New().String()
+	}
+	return nil
+}
+
+func (option *BundleSlotOption) BeforeCreate(tx *gorm.DB) (err error) {
+	if option.OptionID == "" {
+		option.OptionID = uuid.New().String()
+	}
+	return nil
+}
+
+func (line *OrderBundle) BeforeCreate(tx *gorm.DB) (err error) {
+	if line.BundleLineID == "" {
+		line.BundleLineID = uuid.New().String()
+	}
+	return nil
+}
+
+// ComponentIDs returns the order item IDs of the bundle's loaded component items
+func (line *OrderBundle) ComponentIDs() []string {
+	ids := make([]string, 0, len(line.Items))
+	for _, item := range line.Items {
+		ids = append(ids, item.OrderItemID)
+	}
+	return ids
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DocumentSequence hands out the gapless numbers of a kind of document, such as credit notes.
+// Its row is locked while a number is taken, so numbers are never skipped or issued twice.
+type DocumentSequence struct {
+	ID         uint      `json:"id" gorm:"primary_key"`
+	Name       string    `json:"name" gorm:"required;uniqueIndex"`
+	LastNumber int64     `json:"last_number" gorm:"not null;default:0"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CreditNote documents money given back on an invoice. Its number comes from its own sequence,
+// and its amount is the sum of the refunds made against the invoice's payments. TaxAmount is the
+// part of the amount that was tax, in the same proportion as on the invoice.
+type CreditNote struct {
+	ID           uint      `json:"id" gorm:"primary_key"`
+	CreditNoteID string    `json:"credit_note_id" gorm:"required;uniqueIndex"`
+	Number       string    `json:"number" gorm:"required;uniqueIndex"`
+	InvoiceID    string    `json:"invoice_id" gorm:"required;index"`
+	OrderID      string    `json:"order_id"`
+	Amount       Money     `json:"amount" gorm:"required"`
+	TaxAmount    Money     `json:"tax_amount"`
+	Currency     string    `json:"currency" gorm:"size:3"`
+	Reason       string    `json:"reason" gorm:"required"`
+	IssuedBy     string    `json:"issued_by"`
+	IssuedAt     time.Time `json:"issued_at" gorm:"required;index"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Refunds      []Refund  `json:"refunds" gorm:"foreignKey:CreditNoteID;references:CreditNoteID"`
+	Invoice      Invoice   `json:"-" gorm:"foreignKey:InvoiceID;references:InvoiceID"`
+}
+
+// Refund statuses. A refund through the payment provider is pending until the provider confirms it;
+// other refunds are handed back by staff and completed straight away.
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusCompleted = "completed"
+)
+
+// Refund is money given back against one payment, as part of a credit note
+type Refund struct {
+	ID                uint      `json:"id" gorm:"primary_key"`
+	RefundID          string    `json:"refund_id" gorm:"required;uniqueIndex"`
+	CreditNoteID      string    `json:"credit_note_id" gorm:"required;index"`
+	InvoiceID         string    `json:"invoice_id" gorm:"required;index"`
+	PaymentID         string    `json:"payment_id" gorm:"required;index"`
+	Amount            Money     `json:"amount" gorm:"required"`
+	Currency          string    `json:"currency" gorm:"size:3"`
+	Method            string    `json:"method"`
+	Provider          string    `json:"provider"`
+	ProviderReference string    `json:"provider_reference"`
+	Status            string    `json:"status" gorm:"not null;default:'completed';index"`
+	ProviderMessage   string    `json:"provider_message"`
+	CreatedAt         time.Time `json:"created_at"`
+	Payment           Payment   `json:"-" gorm:"foreignKey:PaymentID;references:PaymentID"`
+}
+
+func (note *CreditNote) BeforeCreate(tx *gorm.DB) (err error) {
+	if note.CreditNoteID == "" {
+		note.CreditNoteID = uuid.New().String()
+	}
+	if note.Currency == "" {
+		note.Currency = DefaultCurrency()
+	}
+	return nil
+}
+
+func (refund *Refund) BeforeCreate(tx *gorm.DB) (err error) {
+	if refund.RefundID == "" {
+		refund.RefundID = uuid.New().String()
+	}
+	if refund.Currency == "" {
+		refund.Currency = DefaultCurrency()
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RoundingMode controls how amounts are rounded to a currency's precision
+type RoundingMode string
+
+const (
+	RoundHalfUp   RoundingMode = "half_up"
+	RoundHalfEven RoundingMode = "half_even"
+	RoundDown     RoundingMode = "down"
+	RoundUp       RoundingMode = "up"
+)
+
+// CurrencyRule describes the precision and rounding applied to amounts in an ISO 4217 currency
+type CurrencyRule struct {
+	Code     string       `json:"code"`
+	Exponent int          `json:"exponent"` // number of minor unit digits, e.g. 2 for cents
+	Step     int64        `json:"step"`     // smallest payable amount in minor units, e.g. 5 for CHF cash rounding
+	Mode     RoundingMode `json:"mode"`
+}
+
+var (
+	currencyMu      sync.RWMutex
+	defaultCurrency = "USD"
+	currencyRules   = map[string]CurrencyRule{
+		"USD": {Code: "USD", Exponent: 2, Step: 1, Mode: RoundHalfUp},
+		"EUR": {Code: "EUR", Exponent: 2, Step: 1, Mode: RoundHalfUp},
+		"GBP": {Code: "GBP", Exponent: 2, Step: 1, Mode: RoundHalfUp},
+		"ETB": {Code: "ETB", Exponent: 2, Step: 1, Mode: RoundHalfUp},
+		"CHF": {Code: "CHF", Exponent: 2, Step: 5, Mode: RoundHalfUp},
+		"JPY": {Code: "JPY", Exponent: 0, Step: 1, Mode: RoundHalfUp},
+		"KWD": {Code: "KWD", Exponent: 3, Step: 1, Mode: RoundHalfUp},
+	}
+)
+
+// DefaultCurrency returns the currency assigned to records created without one
+func DefaultCurrency() string {
+	currencyMu.RLock()
+	defer currencyMu.RUnlock()
+	return defaultCurrency
+}
+
+// LookupCurrency returns the rounding rule for a currency code, falling back to two decimals half-up
+func LookupCurrency(code string) CurrencyRule {
+	currencyMu.RLock()
+	defer currencyMu.RUnlock()
+	if rule, ok := currencyRules[strings.ToUpper(code)]; ok {
+		return rule
+	}
+	return CurrencyRule{Code: strings.ToUpper(code), Exponent: 2, Step: 1, Mode: RoundHalfUp}
+}
+
+// RegisterCurrency adds or replaces the rounding rule for a currency
+func RegisterCurrency(rule CurrencyRule) error {
+	rule.Code = strings.ToUpper(strings.TrimSpace(rule.Code))
+	if len(rule.Code) != 3 {
+		return fmt.Errorf("invalid currency code %q", rule.Code)
+	}
+	if rule.Exponent < 0 || rule.Exponent > moneyFractionDigits {
+		return fmt.Errorf("currency %s: exponent must be between 0 and %d", rule.Code, moneyFractionDigits)
+	}
+	if rule.Step <= 0 {
+		rule.Step = 1
+	}
+	switch rule.Mode {
+	case RoundHalfUp, RoundHalfEven, RoundDown, RoundUp:
+	case "":
+		rule.Mode = RoundHalfUp
+	default:
+		return fmt.Errorf("currency %s: unknown rounding mode %q", rule.Code, rule.Mode)
+	}
+
+	currencyMu.Lock()
+	defer currencyMu.Unlock()
+	currencyRules[rule.Code] = rule
+	return nil
+}
+
+// ConfigureCurrencies sets the default currency and applies rounding overrides written as
+// comma separated CODE:EXPONENT[:MODE[:STEP]] entries, e.g. "CHF:2:half_up:5,JPY:0"
+func ConfigureCurrencies(defaultCode, rules string) error {
+	for _, entry := range strings.Split(rules, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 {
+			return fmt.Errorf("invalid currency rule %q", entry)
+		}
+
+		exponent, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return fmt.Errorf("invalid exponent in currency rule %q", entry)
+		}
+
+		rule := CurrencyRule{Code: parts[0], Exponent: exponent}
+		if len(parts) > 2 {
+			rule.Mode = RoundingMode(parts[2])
+		}
+		if len(parts) > 3 {
+			if rule.Step, err = strconv.ParseInt(parts[3], 10, 64); err != nil {
+				return fmt.Errorf("invalid step in currency rule %q", entry)
+			}
+		}
+
+		if err := RegisterCurrency(rule); err != nil {
+			return err
+		}
+	}
+
+	if defaultCode != "" {
+		defaultCode = strings.ToUpper(strings.TrimSpace(defaultCode))
+		if len(defaultCode) != 3 {
+			return fmt.Errorf("invalid default currency %q", defaultCode)
+		}
+		currencyMu.Lock()
+		defaultCurrency = defaultCode
+		currencyMu.Unlock()
+	}
+
+	return nil
+}
@@ -2,16 +2,59 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Food struct {
 	ID        uint      `json:"id" gorm:"primary_key"`
 	Name      string    `json:"name" gorm:"required"`
-	Price     float64   `json:"price" gorm:"required"`
+	Price     Money     `json:"price" gorm:"required"`
+	Currency  string    `json:"currency" gorm:"size:3"`
 	FoodImage *string   `json:"food_image" gorm:"required"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	FoodID    string    `json:"food_id" gorm:"required;uniqueIndex"`
 	MenuID    string    `json:"menu_id" gorm:"required"`
 	Menu      Menu      `json:"-" gorm:"foreignKey:MenuID;references:MenuID"`
+
+	// TaxCategoryID selects the taxes charged on the food; foods without one are not taxed
+	TaxCategoryID string `json:"tax_category_id" gorm:"not null;default:'';index"`
+
+	// UnavailableUntil marks the food as 86'd until the given time and StockedOut as out of ingredients;
+	// Available is derived from both when loaded
+	UnavailableUntil  *time.Time `json:"unavailable_until"`
+	UnavailableReason string     `json:"unavailable_reason"`
+	StockedOut        bool       `json:"stocked_out" gorm:"not null;default:false"`
+	Available         bool       `json:"available" gorm:"-"`
+
+	Variants       []FoodVariant   `json:"variants,omitempty" gorm:"foreignKey:FoodID;references:FoodID"`
+	ModifierGroups []ModifierGroup `json:"modifier_groups,omitempty" gorm:"foreignKey:FoodID;references:FoodID"`
+}
+
+func (food *Food) BeforeCreate(tx *gorm.DB) (err error) {
+	if food.Currency == "" {
+		food.Currency = DefaultCurrency()
+	}
+	return nil
+}
+
+func (food *Food) AfterFind(tx *gorm.DB) (err error) {
+	food.Available = food.AvailableAt(time.Now())
+	return nil
+}
+
+func (food *Food) AfterSave(tx *gorm.DB) (err error) {
+	food.Available = food.AvailableAt(time.Now())
+	return nil
+}
+
+// AvailableAt reports whether the food can be ordered at the given time
+func (food *Food) AvailableAt(t time.Time) bool {
+	return !food.StockedOut && availableAt(food.UnavailableUntil, t)
+}
+
+// availableAt reports whether something marked unavailable until the given time can be ordered at t
+func availableAt(unavailableUntil *time.Time, t time.Time) bool {
+	return unavailableUntil == nil || !t.Before(*unavailableUntil)
 }
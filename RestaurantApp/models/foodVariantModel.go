@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FoodVariant is a sellable size or version of a food, such as a small or large drink,
+// priced on its own and tracked under its own SKU
+type FoodVariant struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	VariantID string    `json:"variant_id" gorm:"required;uniqueIndex"`
+	FoodID    string    `json:"food_id" gorm:"required;index"`
+	Name      string    `json:"name" gorm:"required"`
+	SKU       string    `json:"sku" gorm:"required;uniqueIndex"`
+	Price     Money     `json:"price" gorm:"required"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Food      Food      `json:"-" gorm:"foreignKey:FoodID;references:FoodID"`
+}
+
+func (variant *FoodVariant) BeforeCreate(tx *gorm.DB) (err error) {
+	if variant.VariantID == "" {
+		variant.VariantID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Stock movement sources
+const (
+	StockSourceOrderItem            = "order_item"
+	StockSourceAdjustment           = "adjustment"
+	StockSourceGoodsReceipt         = "goods_receipt"
+	StockSourceGoodsReceiptReversal = "goods_receipt_reversal"
+	StockSourceWaste                = "waste"
+	StockSourceStockCount           = "stock_count"
+)
+
+// Ingredient is a stocked raw material, measured in its own unit (g, ml, pcs, ...). UnitCost is the
+// cost of one unit in the default currency, taken from the latest goods receipt.
+type Ingredient struct {
+	ID           uint      `json:"id" gorm:"primary_key"`
+	IngredientID string    `json:"ingredient_id" gorm:"required;uniqueIndex"`
+	Name         string    `json:"name" gorm:"required"`
+	Unit         string    `json:"unit" gorm:"required"`
+	OnHand       Quantity  `json:"on_hand" gorm:"not null;default:0"`
+	ReorderPoint Quantity  `json:"reorder_point" gorm:"not null;default:0"`
+	UnitCost     Money     `json:"unit_cost" gorm:"not null;default:0"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Recipe is one line of a food's bill of materials: how much of an ingredient one portion uses.
+// Lines without a variant apply to every portion; lines with a variant are added for that variant only.
+type Recipe struct {
+	ID           uint       `json:"id" gorm:"primary_key"`
+	RecipeID     string     `json:"recipe_id" gorm:"required;uniqueIndex"`
+	FoodID       string     `json:"food_id" gorm:"required;index"`
+	VariantID    string     `json:"variant_id" gorm:"not null;default:''"`
+	IngredientID string     `json:"ingredient_id" gorm:"required;index"`
+	Quantity     Quantity   `json:"quantity" gorm:"required"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Food         Food       `json:"-" gorm:"foreignKey:FoodID;references:FoodID"`
+	Ingredient   Ingredient `json:"-" gorm:"foreignKey:IngredientID;references:IngredientID"`
+}
+
+// StockMovement is a signed change to an ingredient's on-hand quantity and the record it came from,
+// such as an order item or a manual adjustment
+type StockMovement struct {
+	ID           uint       `json:"id" gorm:"primary_key"`
+	MovementID   string     `json:"movement_id" gorm:"required;uniqueIndex"`
+	IngredientID string     `json:"ingredient_id" gorm:"required;index"`
+	Quantity     Quantity   `json:"quantity" gorm:"required"`
+	SourceType   string     `json:"source_type" gorm:"required;index:idx_stock_movement_source"`
+	SourceID     string     `json:"source_id" gorm:"index:idx_stock_movement_source"`
+	Reason       string     `json:"reason"`
+	ActorUID     string     `json:"actor_uid"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Ingredient   Ingredient `json:"-" gorm:"foreignKey:IngredientID;references:IngredientID"`
+}
+
+func (ingredient *Ingredient) BeforeCreate(tx *gorm.DB) (err error) {
+	if ingredient.IngredientID == "" {
+		ingredient.IngredientID = uuid.New().String()
+	}
+	return nil
+}
+
+func (recipe *Recipe) BeforeCreate(tx *gorm.DB) (err error) {
+	if recipe.RecipeID == "" {
+		recipe.RecipeID = uuid.New().String()
+	}
+	return nil
+}
+
+func (movement *StockMovement) BeforeCreate(tx *gorm.DB) (err error) {
+	if movement.MovementID == "" {
+		movement.MovementID = uuid.New().String()
+	}
+	return nil
+}
@@ -2,8 +2,28 @@ package models
 
 import (
 	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Invoice payment statuses
+const (
+	InvoiceStatusPending       = "pending"
+	InvoiceStatusPartiallyPaid = "partially_paid"
+	InvoiceStatusPaid          = "paid"
+	InvoiceStatusRefunded      = "refunded"
 )
 
+// InvoiceStatuses lists the payment statuses an invoice can have
+var InvoiceStatuses = []string{InvoiceStatusPending, InvoiceStatusPartiallyPaid, InvoiceStatusPaid, InvoiceStatusRefunded}
+
+// InvoicePaymentMethodMixed is the payment method of an invoice settled with more than one method
+const InvoicePaymentMethodMixed = "mixed"
+
+// Invoice bills an order. AmountPaid, CreditedAmount and BalanceDue are kept in step with the
+// invoice's payments, net of refunds, and its credit notes, and PaymentStatus follows from them.
+// TotalAmount includes tax: it is Subtotal plus TaxAmount, and TaxLines break the tax down by rate.
 type Invoice struct {
 	ID             uint      `json:"id" gorm:"primary_key"`
 	InvoiceID      string    `json:"invoice_id" gorm:"required;uniqueIndex"`
@@ -11,8 +31,30 @@ type Invoice struct {
 	PaymentStatus  string    `json:"payment_status" gorm:"required"`
 	PaymentMethod  string    `json:"payment_method" gorm:"required"`
 	PaymentDueDate time.Time `json:"payment_due_date" gorm:"required"`
-	TotalAmount    float64   `json:"total_amount" gorm:"required"`
+	Subtotal       Money     `json:"subtotal"`
+	TaxAmount      Money     `json:"tax_amount"`
+	TotalAmount    Money     `json:"total_amount" gorm:"required"`
+	AmountPaid     Money     `json:"amount_paid"`
+	BalanceDue     Money     `json:"balance_due"`
+	CreditedAmount Money     `json:"credited_amount"`
+	Currency       string    `json:"currency" gorm:"size:3"`
+	SplitLabel     string    `json:"split_label"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 	Order          Order     `json:"-" gorm:"foreignKey:OrderID;references:OrderID"`
+
+	// PricesIncludeTax and TaxRounding record how the invoice was taxed
+	PricesIncludeTax bool             `json:"prices_include_tax" gorm:"not null;default:false"`
+	TaxRounding      string           `json:"tax_rounding" gorm:"not null;default:''"`
+	TaxLines         []InvoiceTaxLine `json:"tax_lines" gorm:"foreignKey:InvoiceID;references:InvoiceID"`
+}
+
+func (invoice *Invoice) BeforeCreate(tx *gorm.DB) (err error) {
+	if invoice.InvoiceID == "" {
+		invoice.InvoiceID = uuid.New().String()
+	}
+	if invoice.Currency == "" {
+		invoice.Currency = DefaultCurrency()
+	}
+	return nil
 }
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Kitchen ticket and ticket item statuses
+const (
+	TicketStatusQueued     = "queued"
+	TicketStatusInProgress = "in_progress"
+	TicketStatusReady      = "ready"
+	TicketStatusCancelled  = "cancelled"
+)
+
+type KitchenTicket struct {
+	ID        uint                `json:"id" gorm:"primary_key"`
+	TicketID  string              `json:"ticket_id" gorm:"required;uniqueIndex"`
+	OrderID   string              `json:"order_id" gorm:"required;index"`
+	StationID string              `json:"station_id" gorm:"index"`
+	TableID   string              `json:"table_id"`
+	Status    string              `json:"status" gorm:"required;index"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+	Items     []KitchenTicketItem `json:"items" gorm:"foreignKey:TicketID;references:TicketID"`
+	Order     Order               `json:"-" gorm:"foreignKey:OrderID;references:OrderID"`
+}
+
+type KitchenTicketItem struct {
+	ID           uint      `json:"id" gorm:"primary_key"`
+	TicketItemID string    `json:"ticket_item_id" gorm:"required;uniqueIndex"`
+	TicketID     string    `json:"ticket_id" gorm:"required;index"`
+	OrderItemID  string    `json:"order_item_id" gorm:"required"`
+	FoodID       string    `json:"food_id" gorm:"required"`
+	FoodName     string    `json:"food_name"`
+	VariantName  string    `json:"variant_name"`
+	Modifiers    string    `json:"modifiers"`
+	Quantity     int       `json:"quantity" gorm:"required"`
+	Status       string    `json:"status" gorm:"required"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// KitchenEvent is a persisted change to the kitchen feed. Its sequence number is taken when the
+// event is recorded; its position is given once the event has committed, in commit order, and
+// lets kitchen screens replay everything they missed after reconnecting.
+type KitchenEvent struct {
+	Sequence  uint64    `json:"sequence" gorm:"primaryKey;autoIncrement"`
+	Position  *uint64   `json:"position" gorm:"uniqueIndex"`
+	EventType string    `json:"event_type" gorm:"required"`
+	TicketID  string    `json:"ticket_id" gorm:"required;index"`
+	StationID string    `json:"station_id" gorm:"index"`
+	Payload   string    `json:"payload" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (ticket *KitchenTicket) BeforeCreate(tx *gorm.DB) (err error) {
+	if ticket.TicketID == "" {
+		ticket.TicketID = uuid.New().String()
+	}
+	return nil
+}
+
+func (item *KitchenTicketItem) BeforeCreate(tx *gorm.DB) (err error) {
+	if item.TicketItemID == "" {
+		item.TicketItemID = uuid.New().String()
+	}
+	return nil
+}
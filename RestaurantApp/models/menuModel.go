@@ -13,4 +13,7 @@ type Menu struct {
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	MenuID    string     `json:"menu_id" gorm:"required;uniqueIndex"`
+
+	// Schedules limits when the menu is served inside its StartDate/EndDate window
+	Schedules []MenuSchedule `json:"schedules,omitempty" gorm:"foreignKey:MenuID;references:MenuID"`
 }
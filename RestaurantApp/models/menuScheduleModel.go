@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MenuSchedule is a weekly window in which a menu is served, e.g. breakfast from 07:00 to 11:00
+// on Mondays. Times are "HH:MM" in the restaurant's timezone; a window whose end is not after its
+// start runs past midnight into the next day. A menu without schedules is served all day.
+type MenuSchedule struct {
+	ID         uint      `json:"id" gorm:"primary_key"`
+	ScheduleID string    `json:"schedule_id" gorm:"required;uniqueIndex"`
+	MenuID     string    `json:"menu_id" gorm:"required;index"`
+	DayOfWeek  int       `json:"day_of_week"` // 0 = Sunday ... 6 = Saturday
+	StartTime  string    `json:"start_time" gorm:"size:5;required"`
+	EndTime    string    `json:"end_time" gorm:"size:5;required"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (schedule *MenuSchedule) BeforeCreate(tx *gorm.DB) (err error) {
+	if schedule.ScheduleID == "" {
+		schedule.ScheduleID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ModifierGroup is a set of options offered on a food, such as "Doneness" or "Extras".
+// A required group needs at least one selection; MaxSelections of zero means no upper limit.
+type ModifierGroup struct {
+	ID            uint       `json:"id" gorm:"primary_key"`
+	GroupID       string     `json:"group_id" gorm:"required;uniqueIndex"`
+	FoodID        string     `json:"food_id" gorm:"required;index"`
+	Name          string     `json:"name" gorm:"required"`
+	Required      bool       `json:"required"`
+	MinSelections int        `json:"min_selections"`
+	MaxSelections int        `json:"max_selections"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Modifiers     []Modifier `json:"modifiers" gorm:"foreignKey:GroupID;references:GroupID"`
+	Food          Food       `json:"-" gorm:"foreignKey:FoodID;references:FoodID"`
+}
+
+type Modifier struct {
+	ID         uint      `json:"id" gorm:"primary_key"`
+	ModifierID string    `json:"modifier_id" gorm:"required;uniqueIndex"`
+	GroupID    string    `json:"group_id" gorm:"required;index"`
+	Name       string    `json:"name" gorm:"required"`
+	PriceDelta Money     `json:"price_delta"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// UnavailableUntil marks the modifier as 86'd until the given time; Available is derived from it when loaded
+	UnavailableUntil  *time.Time `json:"unavailable_until"`
+	UnavailableReason string     `json:"unavailable_reason"`
+	Available         bool       `json:"available" gorm:"-"`
+}
+
+// OrderItemModifier is a modifier selected on an order item, with its name and price
+// captured at order time like the item's own snapshot
+type OrderItemModifier struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	OrderItemID string    `json:"order_item_id" gorm:"required;index"`
+	GroupID     string    `json:"group_id"`
+	GroupName   string    `json:"group_name"`
+	ModifierID  string    `json:"modifier_id" gorm:"required"`
+	Name        string    `json:"name"`
+	PriceDelta  Money     `json:"price_delta"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// MinimumSelections returns how many modifiers must be chosen from the group
+func (group *ModifierGroup) MinimumSelections() int {
+	if group.Required && group.MinSelections < 1 {
+		return 1
+	}
+	return group.MinSelections
+}
+
+func (group *ModifierGroup) BeforeCreate(tx *gorm.DB) (err error) {
+	if group.GroupID == "" {
+		group.GroupID = uuid.New().String()
+	}
+	return nil
+}
+
+func (modifier *Modifier) BeforeCreate(tx *gorm.DB) (err error) {
+	if modifier.ModifierID == "" {
+		modifier.ModifierID = uuid.New().String()
+	}
+	return nil
+}
+
+func (modifier *Modifier) AfterFind(tx *gorm.DB) (err error) {
+	modifier.Available = modifier.AvailableAt(time.Now())
+	return nil
+}
+
+func (modifier *Modifier) AfterSave(tx *gorm.DB) (err error) {
+	modifier.Available = modifier.AvailableAt(time.Now())
+	return nil
+}
+
+// AvailableAt reports whether the modifier can be chosen at the given time
+func (modifier *Modifier) AvailableAt(t time.Time) bool {
+	return availableAt(modifier.UnavailableUntil, t)
+}
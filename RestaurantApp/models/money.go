@@ -0,0 +1,318 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// moneyFractionDigits is the number of decimal places every Money value carries
+const moneyFractionDigits = 4
+
+// moneyScale is 10^moneyFractionDigits
+const moneyScale = 10000
+
+// Money is an exact fixed-point decimal amount stored with four fractional digits,
+// so 12.50 is held as 125000. It is stored in Postgres as numeric(19,4) and encoded
+// in JSON as a plain decimal number. The currency lives alongside it on the owning model.
+type Money int64
+
+// ErrMoneyOverflow is returned when an amount does not fit in Money's range
+var ErrMoneyOverflow = errors.New("money amount is out of range")
+
+// plainDecimal matches an optionally signed decimal number without exponents or fractions, e.g. "-12.50"
+var plainDecimal = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+
+// ParseMoney converts a plain decimal string such as "12.50" into Money without going through
+// float64. Fractions such as "1/3" and exponents such as "1e3" are rejected.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	if !plainDecimal.MatchString(s) {
+		return 0, fmt.Errorf("invalid money amount %q", s)
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid money amount %q", s)
+	}
+
+	return moneyFromRat(r, RoundHalfEven)
+}
+
+// MoneyFromMinor builds Money from an amount in a currency's minor units, e.g. cents
+func MoneyFromMinor(minor int64, currency string) Money {
+	return Money(minor * pow10(moneyFractionDigits-LookupCurrency(currency).Exponent))
+}
+
+// Minor returns the amount in the currency's minor units, rounding with the currency's rule
+func (m Money) Minor(currency string) int64 {
+	rule := LookupCurrency(currency)
+	return int64(m.Round(currency)) / pow10(moneyFractionDigits-rule.Exponent)
+}
+
+func (m Money) Add(other Money) Money {
+	return m + other
+}
+
+func (m Money) Sub(other Money) Money {
+	return m - other
+}
+
+// Mul multiplies the amount by a whole quantity, failing with ErrMoneyOverflow if the product
+// does not fit in Money
+func (m Money) Mul(quantity int64) (Money, error) {
+	if m == 0 || quantity == 0 {
+		return 0, nil
+	}
+	product := int64(m) * quantity
+	if product/quantity != int64(m) || (quantity == -1 && int64(m) == math.MinInt64) {
+		return 0, ErrMoneyOverflow
+	}
+	return Money(product), nil
+}
+
+// MulRatio multiplies the amount by num/den, rounding the result to Money's precision
+func (m Money) MulRatio(num, den int64, mode RoundingMode) Money {
+	r := new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(num)), big.NewInt(den))
+	result, _ := moneyFromRat(r.Quo(r, big.NewRat(moneyScale, 1)), mode)
+	return result
+}
+
+// Round rounds the amount to the precision and step of the given currency using its rounding mode
+func (m Money) Round(currency string) Money {
+	rule := LookupCurrency(currency)
+	unit := pow10(moneyFractionDigits-rule.Exponent) * rule.Step
+	return Money(divRound(int64(m), unit, rule.Mode) * unit)
+}
+
+// Allocate splits the amount into parts proportional to weights, in whole minor units of the
+// currency, so that the parts always add up to the original amount. Minor units left over by
+// rounding go to the parts with the largest remainders; if no weight is positive the amount is
+// split evenly.
+func (m Money) Allocate(weights []int64, currency string) []Money {
+	parts := make([]Money, len(weights))
+	if len(weights) == 0 {
+		return parts
+	}
+
+	shares := make([]int64, len(weights))
+	var totalWeight int64
+	for i, weight := range weights {
+		if weight > 0 {
+			shares[i] = weight
+			totalWeight += weight
+		}
+	}
+	if totalWeight == 0 {
+		for i := range shares {
+			shares[i] = 1
+		}
+		totalWeight = int64(len(shares))
+	}
+
+	sign := int64(1)
+	amount := int64(m)
+	if amount < 0 {
+		sign, amount = -1, -amount
+	}
+
+	unit := pow10(moneyFractionDigits - LookupCurrency(currency).Exponent)
+	units := amount / unit
+	leftover := amount - units*unit
+
+	remainders := make([]*big.Int, len(shares))
+	allocated := int64(0)
+	total := big.NewInt(totalWeight)
+	for i, share := range shares {
+		quotient, remainder := new(big.Int).QuoRem(new(big.Int).Mul(big.NewInt(units), big.NewInt(share)), total, new(big.Int))
+		parts[i] = Money(quotient.Int64())
+		remainders[i] = remainder
+		allocated += quotient.Int64()
+	}
+
+	for ; allocated < units; allocated++ {
+		largest := 0
+		for i := range remainders {
+			if remainders[i].Cmp(remainders[largest]) > 0 {
+				largest = i
+			}
+		}
+		parts[largest]++
+		remainders[largest].SetInt64(-1)
+	}
+
+	for i := range parts {
+		parts[i] = Money(sign * int64(parts[i]) * unit)
+	}
+	parts[len(parts)-1] += Money(sign * leftover)
+	return parts
+}
+
+func (m Money) IsZero() bool {
+	return m == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m < 0
+}
+
+// String formats the amount with at least two decimal places, e.g. "12.50" or "0.1235"
+func (m Money) String() string {
+	sign := ""
+	value := int64(m)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	fraction := fmt.Sprintf("%04d", value%moneyScale)
+	fraction = strings.TrimRight(fraction, "0")
+	for len(fraction) < 2 {
+		fraction += "0"
+	}
+
+	return fmt.Sprintf("%s%d.%s", sign, value/moneyScale, fraction)
+}
+
+// MarshalJSON encodes the amount as a JSON number with its exact decimal digits
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*m = 0
+		return nil
+	}
+
+	parsed, err := ParseMoney(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the amount as an exact decimal string
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads numeric columns returned as text or integers, and legacy float columns
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		parsed, err := ParseMoney(string(v))
+		*m = parsed
+		return err
+	case string:
+		parsed, err := ParseMoney(v)
+		*m = parsed
+		return err
+	case int64:
+		*m = Money(v * moneyScale)
+		return nil
+	case float64:
+		parsed, err := ParseMoney(fmt.Sprintf("%.4f", v))
+		*m = parsed
+		return err
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+}
+
+// GormDataType makes GORM create money columns as exact fixed-point numerics
+func (Money) GormDataType() string {
+	return "numeric(19,4)"
+}
+
+func moneyFromRat(r *big.Rat, mode RoundingMode) (Money, error) {
+	scaled := new(big.Rat).Mul(r, big.NewRat(moneyScale, 1))
+	num := scaled.Num()
+	den := scaled.Denom()
+
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if remainder.Sign() != 0 && roundsAway(remainder, den, quotient, mode) {
+		if num.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	if !quotient.IsInt64() {
+		return 0, fmt.Errorf("money amount %s: %w", r.FloatString(moneyFractionDigits), ErrMoneyOverflow)
+	}
+	return Money(quotient.Int64()), nil
+}
+
+// roundsAway reports whether a truncated quotient must be moved one step away from zero
+func roundsAway(remainder, den, quotient *big.Int, mode RoundingMode) bool {
+	switch mode {
+	case RoundDown:
+		return false
+	case RoundUp:
+		return true
+	}
+
+	twiceRemainder := new(big.Int).Abs(remainder)
+	twiceRemainder.Lsh(twiceRemainder, 1)
+	cmp := twiceRemainder.Cmp(den)
+	if mode == RoundHalfEven {
+		return cmp > 0 || (cmp == 0 && quotient.Bit(0) == 1)
+	}
+	return cmp >= 0
+}
+
+// divRound divides n by a positive d and rounds the quotient with the given mode
+func divRound(n, d int64, mode RoundingMode) int64 {
+	quotient, remainder := n/d, n%d
+	if remainder == 0 {
+		return quotient
+	}
+
+	away := false
+	if remainder < 0 {
+		remainder = -remainder
+	}
+	switch mode {
+	case RoundDown:
+		away = false
+	case RoundUp:
+		away = true
+	case RoundHalfEven:
+		away = 2*remainder > d || (2*remainder == d && quotient%2 != 0)
+	default:
+		away = 2*remainder >= d
+	}
+
+	if !away {
+		return quotient
+	}
+	if n < 0 {
+		return quotient - 1
+	}
+	return quotient + 1
+}
+
+func pow10(exponent int) int64 {
+	result := int64(1)
+	for i := 0; i < exponent; i++ {
+		result *= 10
+	}
+	return result
+}
@@ -0,0 +1,203 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{input: "", want: 0},
+		{input: "12.50", want: 125000},
+		{input: " 12.5 ", want: 125000},
+		{input: "-0.0001", want: -1},
+		{input: "+3", want: 30000},
+		{input: ".25", want: 2500},
+		{input: "7.", want: 70000},
+		{input: "0.00005", want: 0},
+		{input: "0.00015", want: 2},
+		{input: "1/3", wantErr: true},
+		{input: "1e3", wantErr: true},
+		{input: "1E-2", wantErr: true},
+		{input: "0x10", wantErr: true},
+		{input: "12,50", wantErr: true},
+		{input: "abc", wantErr: true},
+		{input: "-", wantErr: true},
+		{input: ".", wantErr: true},
+		{input: "9223372036854775808", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) = %v, want an error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMoney(%q) returned error %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyMul(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Money
+		quantity int64
+		want     Money
+		wantErr  bool
+	}{
+		{name: "whole quantity", amount: 125000, quantity: 3, want: 375000},
+		{name: "negative amount", amount: -125000, quantity: 2, want: -250000},
+		{name: "zero quantity", amount: math.MaxInt64, quantity: 0, want: 0},
+		{name: "largest fitting product", amount: math.MaxInt64, quantity: 1, want: math.MaxInt64},
+		{name: "overflow", amount: math.MaxInt64 / 2, quantity: 3, wantErr: true},
+		{name: "negative overflow", amount: math.MinInt64 / 2, quantity: 3, wantErr: true},
+		{name: "negating the minimum", amount: math.MinInt64, quantity: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.amount.Mul(tt.quantity)
+		if tt.wantErr {
+			if !errors.Is(err, ErrMoneyOverflow) {
+				t.Errorf("%s: Mul returned (%d, %v), want ErrMoneyOverflow", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: Mul returned (%d, %v), want %d", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestMoneyMulRatio(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Money
+		num, den int64
+		mode     RoundingMode
+		want     Money
+	}{
+		{name: "15 percent", amount: 100000, num: 1500, den: 10000, mode: RoundHalfUp, want: 15000},
+		{name: "inclusive tax", amount: 115000, num: 1500, den: 11500, mode: RoundHalfUp, want: 15000},
+		{name: "third half up", amount: 100000, num: 1, den: 3, mode: RoundHalfUp, want: 33333},
+		{name: "two thirds half up", amount: 100000, num: 2, den: 3, mode: RoundHalfUp, want: 66667},
+		{name: "two thirds down", amount: 100000, num: 2, den: 3, mode: RoundDown, want: 66666},
+		{name: "third up", amount: 100000, num: 1, den: 3, mode: RoundUp, want: 33334},
+		{name: "half even stays even", amount: 5, num: 1, den: 2, mode: RoundHalfEven, want: 2},
+		{name: "half even goes even", amount: 7, num: 1, den: 2, mode: RoundHalfEven, want: 4},
+		{name: "half up", amount: 5, num: 1, den: 2, mode: RoundHalfUp, want: 3},
+		{name: "negative half up rounds away from zero", amount: -5, num: 1, den: 2, mode: RoundHalfUp, want: -3},
+		{name: "negative down rounds towards zero", amount: -100000, num: 2, den: 3, mode: RoundDown, want: -66666},
+		{name: "large amount does not overflow", amount: math.MaxInt64 / 2, num: 2, den: 3, mode: RoundDown, want: 3074457345618258602},
+	}
+
+	for _, tt := range tests {
+		if got := tt.amount.MulRatio(tt.num, tt.den, tt.mode); got != tt.want {
+			t.Errorf("%s: %d.MulRatio(%d, %d, %s) = %d, want %d", tt.name, tt.amount, tt.num, tt.den, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyRound(t *testing.T) {
+	tests := []struct {
+		amount   Money
+		currency string
+		want     Money
+	}{
+		{amount: 12345, currency: "USD", want: 12300},
+		{amount: 12350, currency: "USD", want: 12400},
+		{amount: -12350, currency: "USD", want: -12400},
+		{amount: 12349, currency: "USD", want: 12300},
+		{amount: 15000, currency: "JPY", want: 20000},
+		{amount: 14999, currency: "JPY", want: 10000},
+		{amount: 12345, currency: "KWD", want: 12350},
+		{amount: 12344, currency: "KWD", want: 12340},
+		{amount: 12250, currency: "CHF", want: 12500},
+		{amount: 12240, currency: "CHF", want: 12000},
+		{amount: 12750, currency: "CHF", want: 13000},
+	}
+
+	for _, tt := range tests {
+		if got := tt.amount.Round(tt.currency); got != tt.want {
+			t.Errorf("%d.Round(%s) = %d, want %d", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestDivRoundModes(t *testing.T) {
+	tests := []struct {
+		n, d int64
+		mode RoundingMode
+		want int64
+	}{
+		{n: 25, d: 10, mode: RoundHalfUp, want: 3},
+		{n: 25, d: 10, mode: RoundHalfEven, want: 2},
+		{n: 35, d: 10, mode: RoundHalfEven, want: 4},
+		{n: 26, d: 10, mode: RoundHalfEven, want: 3},
+		{n: 29, d: 10, mode: RoundDown, want: 2},
+		{n: 21, d: 10, mode: RoundUp, want: 3},
+		{n: 20, d: 10, mode: RoundUp, want: 2},
+		{n: -25, d: 10, mode: RoundHalfUp, want: -3},
+		{n: -25, d: 10, mode: RoundHalfEven, want: -2},
+		{n: -29, d: 10, mode: RoundDown, want: -2},
+		{n: -21, d: 10, mode: RoundUp, want: -3},
+	}
+
+	for _, tt := range tests {
+		if got := divRound(tt.n, tt.d, tt.mode); got != tt.want {
+			t.Errorf("divRound(%d, %d, %s) = %d, want %d", tt.n, tt.d, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyAllocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Money
+		weights  []int64
+		currency string
+		want     []Money
+	}{
+		{name: "even split", amount: 100000, weights: []int64{1, 1}, currency: "USD", want: []Money{50000, 50000}},
+		{name: "thirds", amount: 100000, weights: []int64{1, 1, 1}, currency: "USD", want: []Money{33400, 33300, 33300}},
+		{name: "largest remainder wins", amount: 100000, weights: []int64{1, 2}, currency: "USD", want: []Money{33300, 66700}},
+		{name: "proportional", amount: 100000, weights: []int64{3, 1}, currency: "USD", want: []Money{75000, 25000}},
+		{name: "no positive weights splits evenly", amount: 100000, weights: []int64{0, -1, 0}, currency: "USD", want: []Money{33400, 33300, 33300}},
+		{name: "zero weight gets nothing", amount: 100000, weights: []int64{1, 0}, currency: "USD", want: []Money{100000, 0}},
+		{name: "negative amount", amount: -100000, weights: []int64{1, 1, 1}, currency: "USD", want: []Money{-33400, -33300, -33300}},
+		{name: "whole yen", amount: 1000000, weights: []int64{1, 1, 1}, currency: "JPY", want: []Money{340000, 330000, 330000}},
+		{name: "sub-unit leftover goes to the last part", amount: 100005, weights: []int64{1, 1}, currency: "USD", want: []Money{50000, 50005}},
+		{name: "no parts", amount: 100000, weights: nil, currency: "USD", want: []Money{}},
+	}
+
+	for _, tt := range tests {
+		got := tt.amount.Allocate(tt.weights, tt.currency)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: Allocate returned %d parts, want %d", tt.name, len(got), len(tt.want))
+			continue
+		}
+
+		sum := Money(0)
+		for i := range got {
+			sum = sum.Add(got[i])
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: part %d = %d, want %d", tt.name, i, got[i], tt.want[i])
+			}
+		}
+		if len(got) > 0 && sum != tt.amount {
+			t.Errorf("%s: parts add up to %d, want %d", tt.name, sum, tt.amount)
+		}
+	}
+}
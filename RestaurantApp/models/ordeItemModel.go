@@ -8,9 +8,25 @@ type OrderItem struct {
 	ID          uint      `json:"id" gorm:"primary_key"`
 	OrderID     string    `json:"order_id" gorm:"required"`
 	FoodID      string    `json:"food_id" gorm:"required"`
+	VariantID   string    `json:"variant_id" gorm:"not null;default:'';index"`
 	Quantity    int       `json:"quantity" gorm:"required"`
+	FoodName    string    `json:"food_name"`
+	VariantName string    `json:"variant_name"`
+	SKU         string    `json:"sku"`
+	UnitPrice   Money     `json:"unit_price"`
+	LineTotal   Money     `json:"line_total"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Food        Food      `json:"-" gorm:"foreignKey:FoodID;references:FoodID"`
 	OrderItemID string    `json:"order_item_id" gorm:"required;uniqueIndex"`
+
+	// Modifiers holds the selected options; ModifierKey identifies the selection so identical items can be merged
+	Modifiers   []OrderItemModifier `json:"modifiers" gorm:"foreignKey:OrderItemID;references:OrderItemID"`
+	ModifierKey string              `json:"-" gorm:"not null;default:''"`
+
+	// BundleLineID links the item to the ordered bundle it is a component of
+	BundleLineID string `json:"bundle_line_id" gorm:"not null;default:'';index"`
+
+	// Seat is the seat number of the guest the item is for, used to split the bill; 0 means shared
+	Seat int `json:"seat" gorm:"not null;default:0"`
 }
@@ -2,14 +2,34 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// Order lifecycle statuses
+const (
+	OrderStatusDraft     = "draft"
+	OrderStatusPending   = "pending"
+	OrderStatusAccepted  = "accepted"
+	OrderStatusPreparing = "preparing"
+	OrderStatusReady     = "ready"
+	OrderStatusServed    = "served"
+	OrderStatusInvoiced  = "invoiced"
+	OrderStatusCompleted = "completed"
+	OrderStatusCancelled = "cancelled"
+	OrderStatusVoided    = "voided"
 )
 
+// TerminalOrderStatuses lists the statuses in which an order no longer occupies its table
+var TerminalOrderStatuses = []string{OrderStatusCompleted, OrderStatusCancelled, OrderStatusVoided}
+
 type Order struct {
 	ID          uint        `json:"id" gorm:"primary_key"`
 	OrderID     string      `json:"order_id" gorm:"required;uniqueIndex"`
 	OrderDate   time.Time   `json:"order_date" gorm:"required"`
 	OrderStatus string      `json:"order_status" gorm:"required"`
-	OrderTotal  float64     `json:"order_total" gorm:"required"`
+	OrderTotal  Money       `json:"order_total" gorm:"required"`
+	Currency    string      `json:"currency" gorm:"size:3"`
 	CreatedAt   time.Time   `json:"created_at"`
 	UpdatedAt   time.Time   `json:"updated_at"`
 	OrderItems  []OrderItem `json:"order_items" gorm:"foreignKey:OrderID;references:OrderID"`
@@ -18,3 +38,10 @@ type Order struct {
 	TableID     string      `json:"table_id" gorm:"required"`
 	Table       Table       `json:"-" gorm:"foreignKey:TableID;references:TableID"`
 }
+
+func (order *Order) BeforeCreate(tx *gorm.DB) (err error) {
+	if order.Currency == "" {
+		order.Currency = DefaultCurrency()
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OrderStatusHistory struct {
+	ID         uint      `json:"id" gorm:"primary_key"`
+	HistoryID  string    `json:"history_id" gorm:"required;uniqueIndex"`
+	OrderID    string    `json:"order_id" gorm:"required;index"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status" gorm:"required"`
+	ActorUID   string    `json:"actor_uid" gorm:"required"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+	Order      Order     `json:"-" gorm:"foreignKey:OrderID;references:OrderID"`
+}
+
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
+
+func (history *OrderStatusHistory) BeforeCreate(tx *gorm.DB) (err error) {
+	if history.HistoryID == "" {
+		history.HistoryID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Payment statuses. Only completed payments count towards an invoice; pending and authorized
+// ones are in flight with the payment provider.
+const (
+	PaymentStatusPending    = "pending"
+	PaymentStatusAuthorized = "authorized"
+	PaymentStatusCompleted  = "completed"
+	PaymentStatusDeclined   = "declined"
+	PaymentStatusVoided     = "voided"
+	PaymentStatusRefunded   = "refunded"
+)
+
+// InFlightPaymentStatuses lists the statuses of provider payments that may still complete
+var InFlightPaymentStatuses = []string{PaymentStatusPending, PaymentStatusAuthorized}
+
+// Payment channels of provider payments
+const (
+	PaymentChannelCardPresent = "card_present"
+	PaymentChannelOnline      = "online"
+)
+
+// Payment methods
+const (
+	PaymentMethodCash         = "cash"
+	PaymentMethodCard         = "card"
+	PaymentMethodMobile       = "mobile"
+	PaymentMethodBankTransfer = "bank_transfer"
+	PaymentMethodVoucher      = "voucher"
+	PaymentMethodOther        = "other"
+)
+
+// PaymentMethods lists the methods a payment can be taken with
+var PaymentMethods = []string{PaymentMethodCash, PaymentMethodCard, PaymentMethodMobile, PaymentMethodBankTransfer, PaymentMethodVoucher, PaymentMethodOther}
+
+// Payment is one amount paid towards an invoice. Tendered is what the guest handed over, which for
+// cash can be more than Amount; the difference is the change given back. RefundedAmount is what has
+// since been given back through credit notes. A voided payment stays on the ledger but no longer
+// counts towards the invoice. Payments taken through a payment provider record the provider, the
+// channel and the provider's reference for the transaction.
+type Payment struct {
+	ID                uint       `json:"id" gorm:"primary_key"`
+	PaymentID         string     `json:"payment_id" gorm:"required;uniqueIndex"`
+	InvoiceID         string     `json:"invoice_id" gorm:"required;index"`
+	Amount            Money      `json:"amount" gorm:"required"`
+	Tendered          Money      `json:"tendered"`
+	RefundedAmount    Money      `json:"refunded_amount"`
+	Change            Money      `json:"change"`
+	Currency          string     `json:"currency" gorm:"size:3"`
+	Method            string     `json:"method" gorm:"required"`
+	Reference         string     `json:"reference"`
+	Status            string     `json:"status" gorm:"required;index"`
+	Provider          string     `json:"provider"`
+	Channel           string     `json:"channel"`
+	ProviderReference string     `json:"provider_reference" gorm:"index"`
+	ProviderMessage   string     `json:"provider_message"`
+	PaidAt            time.Time  `json:"paid_at" gorm:"required"`
+	ReceivedBy        string     `json:"received_by"`
+	VoidedAt          *time.Time `json:"voided_at"`
+	VoidedBy          string     `json:"voided_by"`
+	VoidReason        string     `json:"void_reason"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	Invoice           Invoice    `json:"-" gorm:"foreignKey:InvoiceID;references:InvoiceID"`
+}
+
+// ProcessedPaymentEvent records a payment webhook event that has been applied, so an event that is
+// delivered again or replayed is not applied twice
+type ProcessedPaymentEvent struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	EventID   string    `json:"event_id" gorm:"required;uniqueIndex"`
+	Type      string    `json:"type"`
+	PaymentID string    `json:"payment_id" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (payment *Payment) BeforeCreate(tx *gorm.DB) (err error) {
+	if payment.PaymentID == "" {
+		payment.PaymentID = uuid.New().String()
+	}
+	if payment.Currency == "" {
+		payment.Currency = DefaultCurrency()
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Purchase order statuses
+const (
+	PurchaseOrderStatusDraft             = "draft"
+	PurchaseOrderStatusSent              = "sent"
+	PurchaseOrderStatusPartiallyReceived = "partially_received"
+	PurchaseOrderStatusReceived          = "received"
+	PurchaseOrderStatusCancelled         = "cancelled"
+)
+
+type PurchaseOrder struct {
+	ID              uint                `json:"id" gorm:"primary_key"`
+	PurchaseOrderID string              `json:"purchase_order_id" gorm:"required;uniqueIndex"`
+	SupplierID      string              `json:"supplier_id" gorm:"required;index"`
+	Status          string              `json:"status" gorm:"required;index"`
+	Currency        string              `json:"currency" gorm:"size:3"`
+	Total           Money               `json:"total" gorm:"not null;default:0"`
+	Notes           string              `json:"notes"`
+	ExpectedDate    *time.Time          `json:"expected_date"`
+	SentAt          *time.Time          `json:"sent_at"`
+	CreatedBy       string              `json:"created_by"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+	Lines           []PurchaseOrderLine `json:"lines" gorm:"foreignKey:PurchaseOrderID;references:PurchaseOrderID"`
+	Supplier        Supplier            `json:"-" gorm:"foreignKey:SupplierID;references:SupplierID"`
+}
+
+// PurchaseOrderLine is a quantity of a stock item ordered at an expected unit cost, both in purchase units
+type PurchaseOrderLine struct {
+	ID               uint      `json:"id" gorm:"primary_key"`
+	LineID           string    `json:"line_id" gorm:"required;uniqueIndex"`
+	PurchaseOrderID  string    `json:"purchase_order_id" gorm:"required;index"`
+	StockItemID      string    `json:"stock_item_id" gorm:"required"`
+	Description      string    `json:"description"`
+	Quantity         Quantity  `json:"quantity" gorm:"required"`
+	ReceivedQuantity Quantity  `json:"received_quantity" gorm:"not null;default:0"`
+	UnitCost         Money     `json:"unit_cost" gorm:"not null;default:0"`
+	LineTotal        Money     `json:"line_total" gorm:"not null;default:0"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	StockItem        StockItem `json:"-" gorm:"foreignKey:StockItemID;references:StockItemID"`
+}
+
+// GoodsReceipt records one delivery against a purchase order. A receipt is never edited or deleted;
+// a wrong receipt is reversed, which takes its stock back out and keeps both entries for the audit trail.
+type GoodsReceipt struct {
+	ID              uint               `json:"id" gorm:"primary_key"`
+	ReceiptID       string             `json:"receipt_id" gorm:"required;uniqueIndex"`
+	PurchaseOrderID string             `json:"purchase_order_id" gorm:"required;index"`
+	ReceivedBy      string             `json:"received_by"`
+	Note            string             `json:"note"`
+	ReversedAt      *time.Time         `json:"reversed_at"`
+	ReversedBy      string             `json:"reversed_by"`
+	ReversalReason  string             `json:"reversal_reason"`
+	CreatedAt       time.Time          `json:"created_at"`
+	Lines           []GoodsReceiptLine `json:"lines" gorm:"foreignKey:ReceiptID;references:ReceiptID"`
+}
+
+// GoodsReceiptLine is the quantity of a purchase order line delivered, in purchase units, the stock
+// it added in ingredient units and the actual unit cost paid. It also keeps the cost it gave the
+// ingredient and the stock item and ingredient costs it replaced, which reversing the receipt restores.
+type GoodsReceiptLine struct {
+	ID            uint      `json:"id" gorm:"primary_key"`
+	ReceiptLineID string    `json:"receipt_line_id" gorm:"required;uniqueIndex"`
+	ReceiptID     string    `json:"receipt_id" gorm:"required;index"`
+	LineID        string    `json:"line_id" gorm:"required;index"`
+	StockItemID   string    `json:"stock_item_id" gorm:"required"`
+	IngredientID  string    `json:"ingredient_id" gorm:"required"`
+	Quantity      Quantity  `json:"quantity" gorm:"required"`
+	StockQuantity Quantity  `json:"stock_quantity" gorm:"required"`
+	UnitCost      Money     `json:"unit_cost" gorm:"not null;default:0"`
+	LineTotal     Money     `json:"line_total" gorm:"not null;default:0"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	IngredientUnitCost         Money  `json:"ingredient_unit_cost" gorm:"not null;default:0"`
+	PreviousUnitCost           *Money `json:"previous_unit_cost"`
+	PreviousIngredientUnitCost *Money `json:"previous_ingredient_unit_cost"`
+}
+
+func (order *PurchaseOrder) BeforeCreate(tx *gorm.DB) (err error) {
+	if order.PurchaseOrderID == "" {
+		order.PurchaseOrderID = uuid.New().String()
+	}
+	if order.Currency == "" {
+		order.Currency = DefaultCurrency()
+	}
+	return nil
+}
+
+func (line *PurchaseOrderLine) BeforeCreate(tx *gorm.DB) (err error) {
+	if line.LineID == "" {
+		line.LineID = uuid.New().String()
+	}
+	return nil
+}
+
+func (receipt *GoodsReceipt) BeforeCreate(tx *gorm.DB) (err error) {
+	if receipt.ReceiptID == "" {
+		receipt.ReceiptID = uuid.New().String()
+	}
+	return nil
+}
+
+func (line *GoodsReceiptLine) BeforeCreate(tx *gorm.DB) (err error) {
+	if line.ReceiptLineID == "" {
+		line.ReceiptLineID = uuid.New().String()
+	}
+	return nil
+}
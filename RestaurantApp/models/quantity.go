@@ -0,0 +1,113 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// Quantity is an exact fixed-point stock quantity with four fractional digits, such as 0.25 kg
+// or 12 pieces. Like Money it is stored as numeric(19,4) and encoded in JSON as a plain number.
+// The unit lives on the ingredient it measures.
+type Quantity int64
+
+// ParseQuantity converts a decimal string such as "0.25" into a Quantity without going through float64
+func ParseQuantity(s string) (Quantity, error) {
+	m, err := ParseMoney(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	return Quantity(m), nil
+}
+
+// QuantityFromInt builds a whole-number Quantity
+func QuantityFromInt(n int64) Quantity {
+	return Quantity(n * moneyScale)
+}
+
+func (q Quantity) Add(other Quantity) Quantity {
+	return q + other
+}
+
+func (q Quantity) Sub(other Quantity) Quantity {
+	return q - other
+}
+
+// Mul multiplies the quantity by a whole number, e.g. a recipe quantity by the number of portions
+func (q Quantity) Mul(n int64) Quantity {
+	return q * Quantity(n)
+}
+
+// Scale multiplies the quantity by a fractional factor, e.g. purchase units by the pack size,
+// rounding to Quantity's precision
+func (q Quantity) Scale(factor Quantity) Quantity {
+	return Quantity(Money(q).MulRatio(int64(factor), moneyScale, RoundHalfUp))
+}
+
+// Neg returns the quantity with its sign flipped
+func (q Quantity) Neg() Quantity {
+	return -q
+}
+
+// Cost values the quantity at a unit cost, rounding to Money's precision
+func (q Quantity) Cost(unitCost Money) Money {
+	return unitCost.MulRatio(int64(q), moneyScale, RoundHalfUp)
+}
+
+func (q Quantity) IsZero() bool {
+	return q == 0
+}
+
+func (q Quantity) IsNegative() bool {
+	return q < 0
+}
+
+// String formats the quantity without trailing zeros, e.g. "2", "0.25" or "-1.5"
+func (q Quantity) String() string {
+	sign := ""
+	value := int64(q)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	fraction := strings.TrimRight(fmt.Sprintf("%04d", value%moneyScale), "0")
+	if fraction == "" {
+		return fmt.Sprintf("%s%d", sign, value/moneyScale)
+	}
+	return fmt.Sprintf("%s%d.%s", sign, value/moneyScale, fraction)
+}
+
+// MarshalJSON encodes the quantity as a JSON number with its exact decimal digits
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	var m Money
+	if err := m.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*q = Quantity(m)
+	return nil
+}
+
+// Value stores the quantity as an exact decimal string for the numeric column
+func (q Quantity) Value() (driver.Value, error) {
+	return Money(q).Value()
+}
+
+// Scan reads a numeric column value
+func (q *Quantity) Scan(value interface{}) error {
+	var m Money
+	if err := m.Scan(value); err != nil {
+		return err
+	}
+	*q = Quantity(m)
+	return nil
+}
+
+func (Quantity) GormDataType() string {
+	return Money(0).GormDataType()
+}
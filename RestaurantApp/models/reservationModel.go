@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Reservation statuses
+const (
+	ReservationStatusBooked    = "booked"
+	ReservationStatusSeated    = "seated"
+	ReservationStatusNoShow    = "no_show"
+	ReservationStatusCancelled = "cancelled"
+)
+
+// ActiveReservationStatuses lists the statuses in which a reservation holds its table for its time slot
+var ActiveReservationStatuses = []string{ReservationStatusBooked, ReservationStatusSeated}
+
+// Reservation books a table for a party from StartTime for DurationMinutes. EndTime is derived from
+// the two and stored so overlapping bookings can be found in the database.
+type Reservation struct {
+	ID                 uint       `json:"id" gorm:"primary_key"`
+	ReservationID      string     `json:"reservation_id" gorm:"required;uniqueIndex"`
+	GuestName          string     `json:"guest_name" gorm:"required"`
+	GuestPhone         string     `json:"guest_phone"`
+	PartySize          int        `json:"party_size" gorm:"required"`
+	StartTime          time.Time  `json:"start_time" gorm:"required;index:idx_reservation_slot"`
+	DurationMinutes    int        `json:"duration_minutes" gorm:"required"`
+	EndTime            time.Time  `json:"end_time" gorm:"required;index:idx_reservation_slot"`
+	TableID            string     `json:"table_id" gorm:"required;index"`
+	Status             string     `json:"status" gorm:"required;index"`
+	Notes              string     `json:"notes"`
+	OrderID            string     `json:"order_id"`
+	CreatedBy          string     `json:"created_by"`
+	SeatedAt           *time.Time `json:"seated_at"`
+	CancelledAt        *time.Time `json:"cancelled_at"`
+	CancellationReason string     `json:"cancellation_reason"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	Table              Table      `json:"-" gorm:"foreignKey:TableID;references:TableID"`
+}
+
+func (reservation *Reservation) BeforeCreate(tx *gorm.DB) (err error) {
+	if reservation.ReservationID == "" {
+		reservation.ReservationID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Station struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	StationID string    `json:"station_id" gorm:"required;uniqueIndex"`
+	Name      string    `json:"name" gorm:"required"`
+	Kind      string    `json:"kind" gorm:"required"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StationRoute sends a food, or every food on menus of a category, to a kitchen station.
+// Exactly one of FoodID and Category is set; a food route wins over a category route.
+type StationRoute struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	RouteID   string    `json:"route_id" gorm:"required;uniqueIndex"`
+	StationID string    `json:"station_id" gorm:"required;index"`
+	FoodID    string    `json:"food_id" gorm:"index"`
+	Category  string    `json:"category" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Station   Station   `json:"-" gorm:"foreignKey:StationID;references:StationID"`
+}
+
+func (station *Station) BeforeCreate(tx *gorm.DB) (err error) {
+	if station.StationID == "" {
+		station.StationID = uuid.New().String()
+	}
+	return nil
+}
+
+func (route *StationRoute) BeforeCreate(tx *gorm.DB) (err error) {
+	if route.RouteID == "" {
+		route.RouteID = uuid.New().String()
+	}
+	return nil
+}
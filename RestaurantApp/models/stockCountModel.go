@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Stock count statuses
+const (
+	StockCountStatusOpen      = "open"
+	StockCountStatusClosed    = "closed"
+	StockCountStatusCancelled = "cancelled"
+)
+
+// StockCount is a stock-take session. While it is open, counted quantities are entered against its
+// lines; closing it sets each counted ingredient's on-hand quantity to what was counted and locks
+// the count. Only one count can be open at a time.
+type StockCount struct {
+	ID           uint             `json:"id" gorm:"primary_key"`
+	StockCountID string           `json:"stock_count_id" gorm:"required;uniqueIndex"`
+	Status       string           `json:"status" gorm:"required;index;uniqueIndex:idx_single_open_stock_count,where:status = 'open'"`
+	Note         string           `json:"note"`
+	OpenedBy     string           `json:"opened_by"`
+	ClosedBy     string           `json:"closed_by"`
+	ClosedAt     *time.Time       `json:"closed_at"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+	Lines        []StockCountLine `json:"lines,omitempty" gorm:"foreignKey:StockCountID;references:StockCountID"`
+}
+
+// StockCountLine is one ingredient on a count sheet. The expected quantity, counted quantity and
+// variance are filled in when the count is closed; Counted is false for ingredients nobody counted,
+// which are left as they were.
+type StockCountLine struct {
+	ID               uint      `json:"id" gorm:"primary_key"`
+	LineID           string    `json:"line_id" gorm:"required;uniqueIndex"`
+	StockCountID     string    `json:"stock_count_id" gorm:"required;uniqueIndex:idx_stock_count_line"`
+	IngredientID     string    `json:"ingredient_id" gorm:"required;uniqueIndex:idx_stock_count_line"`
+	Name             string    `json:"name"`
+	Unit             string    `json:"unit"`
+	Counted          bool      `json:"counted" gorm:"not null;default:false"`
+	ExpectedQuantity Quantity  `json:"expected_quantity" gorm:"not null;default:0"`
+	CountedQuantity  Quantity  `json:"counted_quantity" gorm:"not null;default:0"`
+	Variance         Quantity  `json:"variance" gorm:"not null;default:0"`
+	UnitCost         Money     `json:"unit_cost" gorm:"not null;default:0"`
+	VarianceCost     Money     `json:"variance_cost" gorm:"not null;default:0"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// StockCountEntry is a quantity counted by one device, either directly in the ingredient's unit or
+// in purchase units of a stock item. Each device keeps one entry per ingredient and stock item,
+// which it overwrites when it recounts; entries from different devices, such as one per storage
+// area, add up.
+type StockCountEntry struct {
+	ID            uint      `json:"id" gorm:"primary_key"`
+	EntryID       string    `json:"entry_id" gorm:"required;uniqueIndex"`
+	StockCountID  string    `json:"stock_count_id" gorm:"required;uniqueIndex:idx_stock_count_entry"`
+	IngredientID  string    `json:"ingredient_id" gorm:"required;uniqueIndex:idx_stock_count_entry"`
+	StockItemID   string    `json:"stock_item_id" gorm:"not null;default:'';uniqueIndex:idx_stock_count_entry"`
+	DeviceID      string    `json:"device_id" gorm:"required;uniqueIndex:idx_stock_count_entry"`
+	Quantity      Quantity  `json:"quantity" gorm:"required"`
+	StockQuantity Quantity  `json:"stock_quantity" gorm:"required"`
+	CountedBy     string    `json:"counted_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (count *StockCount) BeforeCreate(tx *gorm.DB) (err error) {
+	if count.StockCountID == "" {
+		count.StockCountID = uuid.New().String()
+	}
+	return nil
+}
+
+func (line *StockCountLine) BeforeCreate(tx *gorm.DB) (err error) {
+	if line.LineID == "" {
+		line.LineID = uuid.New().String()
+	}
+	return nil
+}
+
+func (entry *StockCountEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	if entry.EntryID == "" {
+		entry.EntryID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Supplier struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	SupplierID  string    `json:"supplier_id" gorm:"required;uniqueIndex"`
+	Name        string    `json:"name" gorm:"required"`
+	ContactName string    `json:"contact_name"`
+	Email       string    `json:"email"`
+	Phone       string    `json:"phone"`
+	Address     string    `json:"address"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// StockItem is something bought from a supplier, such as a 25 kg sack of flour. Receiving one
+// purchase unit adds PackSize of the linked ingredient to stock. UnitCost is the last price paid
+// per purchase unit.
+type StockItem struct {
+	ID           uint       `json:"id" gorm:"primary_key"`
+	StockItemID  string     `json:"stock_item_id" gorm:"required;uniqueIndex"`
+	SupplierID   string     `json:"supplier_id" gorm:"required;index"`
+	IngredientID string     `json:"ingredient_id" gorm:"required;index"`
+	Name         string     `json:"name" gorm:"required"`
+	SupplierSKU  string     `json:"supplier_sku"`
+	PurchaseUnit string     `json:"purchase_unit" gorm:"required"`
+	PackSize     Quantity   `json:"pack_size" gorm:"required"`
+	UnitCost     Money      `json:"unit_cost" gorm:"not null;default:0"`
+	Currency     string     `json:"currency" gorm:"size:3"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Supplier     Supplier   `json:"-" gorm:"foreignKey:SupplierID;references:SupplierID"`
+	Ingredient   Ingredient `json:"-" gorm:"foreignKey:IngredientID;references:IngredientID"`
+}
+
+func (supplier *Supplier) BeforeCreate(tx *gorm.DB) (err error) {
+	if supplier.SupplierID == "" {
+		supplier.SupplierID = uuid.New().String()
+	}
+	return nil
+}
+
+func (item *StockItem) BeforeCreate(tx *gorm.DB) (err error) {
+	if item.StockItemID == "" {
+		item.StockItemID = uuid.New().String()
+	}
+	if item.Currency == "" {
+		item.Currency = DefaultCurrency()
+	}
+	return nil
+}
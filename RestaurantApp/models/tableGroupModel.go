@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TableGroup is a temporary set of tables pushed together for a large party. One order placed at any
+// of its tables occupies them all until the group is released. Members are the tables whose GroupID
+// points at the group; they are loaded separately because ungrouped tables have an empty GroupID.
+type TableGroup struct {
+	ID           uint      `json:"id" gorm:"primary_key"`
+	TableGroupID string    `json:"table_group_id" gorm:"required;uniqueIndex"`
+	Name         string    `json:"name"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Tables       []Table   `json:"tables" gorm:"-"`
+}
+
+func (group *TableGroup) BeforeCreate(tx *gorm.DB) (err error) {
+	if group.TableGroupID == "" {
+		group.TableGroupID = uuid.New().String()
+	}
+	return nil
+}
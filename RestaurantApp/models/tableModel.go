@@ -4,11 +4,38 @@ import (
 	"time"
 )
 
+// Table shapes drawn on the floor plan
+const (
+	TableShapeRound     = "round"
+	TableShapeSquare    = "square"
+	TableShapeRectangle = "rectangle"
+)
+
+// Table is a dining table. MinCovers and MaxCovers bound the party sizes it suits; zero means no limit.
+// PosX, PosY, Width and Height place it on the floor plan of its section, in the client's plan units.
+// GroupID is set while the table is pushed together with others into a TableGroup.
 type Table struct {
 	ID        uint      `json:"id" gorm:"primary_key"`
 	TableID   string    `json:"table_id" gorm:"required;uniqueIndex"`
 	TableName string    `json:"table_name" gorm:"required"`
+	MinCovers int       `json:"min_covers" gorm:"not null;default:0"`
+	MaxCovers int       `json:"max_covers" gorm:"not null;default:0"`
+	Section   string    `json:"section" gorm:"not null;default:'';index"`
+	Shape     string    `json:"shape" gorm:"not null;default:'square'"`
+	PosX      float64   `json:"pos_x" gorm:"not null;default:0"`
+	PosY      float64   `json:"pos_y" gorm:"not null;default:0"`
+	Width     float64   `json:"width" gorm:"not null;default:1"`
+	Height    float64   `json:"height" gorm:"not null;default:1"`
+	GroupID   string    `json:"group_id" gorm:"not null;default:'';index"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Fits reports whether a party of the given size suits the table
+func (table *Table) Fits(partySize int) bool {
+	if table.MinCovers > 0 && partySize < table.MinCovers {
+		return false
+	}
+	return table.MaxCovers == 0 || partySize <= table.MaxCovers
+}
+
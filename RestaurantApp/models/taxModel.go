@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Tax rounding modes: each line's tax is rounded to the currency before the lines are added up,
+// or the unrounded line taxes are added up and each rate's total is rounded once per invoice
+const (
+	TaxRoundingLine    = "line"
+	TaxRoundingInvoice = "invoice"
+)
+
+// TaxCategory groups foods that are taxed alike, e.g. food or alcohol. Foods without a category
+// are not taxed.
+type TaxCategory struct {
+	ID            uint      `json:"id" gorm:"primary_key"`
+	TaxCategoryID string    `json:"tax_category_id" gorm:"required;uniqueIndex"`
+	Name          string    `json:"name" gorm:"required;uniqueIndex"`
+	Description   string    `json:"description"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Rates         []TaxRate `json:"rates,omitempty" gorm:"foreignKey:TaxCategoryID;references:TaxCategoryID"`
+}
+
+// TaxRate is a tax charged on the foods of a category, in basis points of the net price, e.g. 1500
+// for 15%. Every rate of a category applies to the same net price. Invoices keep a copy of
+// the rates they were taxed at, so changing a rate only affects invoices issued afterwards.
+type TaxRate struct {
+	ID              uint      `json:"id" gorm:"primary_key"`
+	TaxRateID       string    `json:"tax_rate_id" gorm:"required;uniqueIndex"`
+	TaxCategoryID   string    `json:"tax_category_id" gorm:"required;index"`
+	Name            string    `json:"name" gorm:"required"`
+	RateBasisPoints int64     `json:"rate_basis_points" gorm:"not null"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// InvoiceTaxLine is one rate of an invoice's tax breakdown, as it was when the invoice was issued
+type InvoiceTaxLine struct {
+	ID              uint      `json:"id" gorm:"primary_key"`
+	InvoiceID       string    `json:"invoice_id" gorm:"required;index"`
+	TaxRateID       string    `json:"tax_rate_id"`
+	Name            string    `json:"name"`
+	RateBasisPoints int64     `json:"rate_basis_points"`
+	TaxableAmount   Money     `json:"taxable_amount"`
+	TaxAmount       Money     `json:"tax_amount"`
+	Currency        string    `json:"currency" gorm:"size:3"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (category *TaxCategory) BeforeCreate(tx *gorm.DB) (err error) {
+	if category.TaxCategoryID == "" {
+		category.TaxCategoryID = uuid.New().String()
+	}
+	return nil
+}
+
+func (rate *TaxRate) BeforeCreate(tx *gorm.DB) (err error) {
+	if rate.TaxRateID == "" {
+		rate.TaxRateID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Waitlist entry statuses
+const (
+	WaitlistStatusWaiting   = "waiting"
+	WaitlistStatusNotified  = "notified"
+	WaitlistStatusSeated    = "seated"
+	WaitlistStatusCancelled = "cancelled"
+)
+
+// WaitlistEntry is a walk-in party waiting for a table. QuotedWaitMinutes is the estimate given when
+// the party was added; WaitedMinutes is how long they actually waited, recorded when they are seated.
+// TableID is the table offered to the party once they have been notified.
+type WaitlistEntry struct {
+	ID                   uint       `json:"id" gorm:"primary_key"`
+	WaitlistID           string     `json:"waitlist_id" gorm:"required;uniqueIndex"`
+	PartyName            string     `json:"party_name" gorm:"required"`
+	PartySize            int        `json:"party_size" gorm:"required"`
+	Phone                string     `json:"phone"`
+	Notes                string     `json:"notes"`
+	Status               string     `json:"status" gorm:"required;index"`
+	QuotedWaitMinutes    int        `json:"quoted_wait_minutes"`
+	EstimatedWaitMinutes int        `json:"estimated_wait_minutes" gorm:"-"`
+	TableID              string     `json:"table_id"`
+	OrderID              string     `json:"order_id"`
+	NotifiedAt           *time.Time `json:"notified_at"`
+	SeatedAt             *time.Time `json:"seated_at"`
+	CancelledAt          *time.Time `json:"cancelled_at"`
+	WaitedMinutes        *int       `json:"waited_minutes"`
+	AddedBy              string     `json:"added_by"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+func (entry *WaitlistEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	if entry.WaitlistID == "" {
+		entry.WaitlistID = uuid.New().String()
+	}
+	return nil
+}
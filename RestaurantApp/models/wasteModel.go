@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Waste reason codes
+const (
+	WasteReasonDropped        = "dropped"
+	WasteReasonExpired        = "expired"
+	WasteReasonSpoiled        = "spoiled"
+	WasteReasonReturned       = "returned"
+	WasteReasonOverproduction = "overproduction"
+	WasteReasonOther          = "other"
+)
+
+// WasteReasons lists the accepted waste reason codes
+var WasteReasons = []string{
+	WasteReasonDropped,
+	WasteReasonExpired,
+	WasteReasonSpoiled,
+	WasteReasonReturned,
+	WasteReasonOverproduction,
+	WasteReasonOther,
+}
+
+// WasteEntry records wasted food or stock. Exactly one of FoodID and StockItemID is set: food is
+// counted in portions and stock items in purchase units. The entry is valued at the cost when it
+// was recorded; Name and UnitCost are captured so later changes do not alter past waste.
+type WasteEntry struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	WasteID     string    `json:"waste_id" gorm:"required;uniqueIndex"`
+	Reason      string    `json:"reason" gorm:"required;index"`
+	FoodID      string    `json:"food_id" gorm:"index"`
+	VariantID   string    `json:"variant_id" gorm:"not null;default:''"`
+	OrderItemID string    `json:"order_item_id"`
+	StockItemID string    `json:"stock_item_id" gorm:"index"`
+	Name        string    `json:"name"`
+	Quantity    Quantity  `json:"quantity" gorm:"required"`
+	UnitCost    Money     `json:"unit_cost" gorm:"not null;default:0"`
+	Cost        Money     `json:"cost" gorm:"not null;default:0"`
+	Currency    string    `json:"currency" gorm:"size:3"`
+	Note        string    `json:"note"`
+	RecordedBy  string    `json:"recorded_by"`
+	WastedAt    time.Time `json:"wasted_at" gorm:"index"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (entry *WasteEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	if entry.WasteID == "" {
+		entry.WasteID = uuid.New().String()
+	}
+	if entry.Currency == "" {
+		entry.Currency = DefaultCurrency()
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func AvailabilityRoutes(incomingRoutes *gin.Engine) {
+	// Public routes - accessible by all users (customers and admins)
+	incomingRoutes.GET("/availability", controllers.GetUnavailableItems())
+	incomingRoutes.GET("/availability/stream", controllers.StreamAvailability())
+
+	// Admin-only routes - restricted to restaurant staff
+	incomingRoutes.PATCH("/foods/:food_id/availability", controllers.UpdateFoodAvailability())
+	incomingRoutes.PATCH("/modifiers/:modifier_id/availability", controllers.UpdateModifierAvailability())
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func BundleRoutes(incomingRoutes *gin.Engine) {
+	// Public routes - accessible by all users (customers and admins)
+	incomingRoutes.GET("/bundles", controllers.GetBundles())
+	incomingRoutes.GET("/bundles/:bundle_id", controllers.GetBundle())
+
+	// Admin-only routes - restricted to restaurant staff
+	incomingRoutes.POST("/bundles", controllers.CreateBundle())
+	incomingRoutes.PATCH("/bundles/:bundle_id", controllers.UpdateBundle())
+	incomingRoutes.DELETE("/bundles/:bundle_id", controllers.DeleteBundle())
+
+	// Bundles on an order (customers can only change their own); bundles are added through the order item endpoints
+	incomingRoutes.GET("/orders/:order_id/bundles", controllers.GetOrderBundles())
+	incomingRoutes.PATCH("/order-bundles/:bundle_line_id", controllers.UpdateOrderBundle())
+	incomingRoutes.DELETE("/order-bundles/:bundle_line_id", controllers.DeleteOrderBundle())
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func CreditNoteRoutes(incomingRoutes *gin.Engine) {
+	// Admin-only routes - restricted to restaurant staff
+	incomingRoutes.GET("/credit-notes", controllers.GetCreditNotes())
+	incomingRoutes.POST("/credit-notes/:credit_note_id/retry", controllers.RetryCreditNoteRefunds())
+
+	// Mixed access routes - permission checked inside controller
+	incomingRoutes.GET("/credit-notes/:credit_note_id", controllers.GetCreditNote())
+	incomingRoutes.GET("/credit-notes/:credit_note_id/document", controllers.GetCreditNoteDocument())
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func FoodVariantRoutes(incomingRoutes *gin.Engine) {
+	// Public routes - accessible by all users (customers and admins)
+	incomingRoutes.GET("/foods/:food_id/variants", controllers.GetFoodVariants())
+
+	// Admin-only routes - restricted to restaurant staff
+	incomingRoutes.POST("/foods/:food_id/variants", controllers.CreateFoodVariant())
+	incomingRoutes.PATCH("/food-variants/:variant_id", controllers.UpdateFoodVariant())
+	incomingRoutes.DELETE("/food-variants/:variant_id", controllers.DeleteFoodVariant())
+}
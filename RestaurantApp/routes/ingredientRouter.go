@@ -0,0 +1,24 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func IngredientRoutes(incomingRoutes *gin.Engine) {
+	// Admin-only routes - restricted to restaurant staff
+	incomingRoutes.GET("/ingredients", controllers.GetIngredients())
+	incomingRoutes.GET("/ingredients/:ingredient_id", controllers.GetIngredient())
+	incomingRoutes.POST("/ingredients", controllers.CreateIngredient())
+	incomingRoutes.PATCH("/ingredients/:ingredient_id", controllers.UpdateIngredient())
+	incomingRoutes.DELETE("/ingredients/:ingredient_id", controllers.DeleteIngredient())
+
+	// Stock movements
+	incomingRoutes.GET("/ingredients/:ingredient_id/movements", controllers.GetStockMovements())
+	incomingRoutes.POST("/ingredients/:ingredient_id/adjustments", controllers.CreateStockAdjustment())
+
+	// Food recipes (bill of materials)
+	incomingRoutes.GET("/foods/:food_id/recipe", controllers.GetFoodRecipe())
+	incomingRoutes.PUT("/foods/:food_id/recipe", controllers.ReplaceFoodRecipe())
+	incomingRoutes.GET("/foods/:food_id/costing", controllers.GetFoodCosting())
+}
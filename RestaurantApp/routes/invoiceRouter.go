@@ -9,11 +9,18 @@ func InvoiceRoutes(incomingRoutes *gin.Engine) {
 	// Admin-only routes - restricted to restaurant staff
 	incomingRoutes.GET("/invoices", controllers.GetInvoices())                  
 	incomingRoutes.POST("/invoices", controllers.CreateInvoice())               
+	incomingRoutes.POST("/invoices/split", controllers.SplitInvoice())
 	incomingRoutes.PATCH("/invoices/:invoice_id", controllers.UpdateInvoice())  
 	incomingRoutes.DELETE("/invoices/:invoice_id", controllers.DeleteInvoice()) 
+	incomingRoutes.POST("/invoices/:invoice_id/payments", controllers.AddInvoicePayment())
+	incomingRoutes.POST("/invoices/:invoice_id/payments/authorize", controllers.AuthorizeInvoicePayment())
+	incomingRoutes.POST("/invoices/:invoice_id/payments/:payment_id/capture", controllers.CaptureInvoicePayment())
+	incomingRoutes.POST("/invoices/:invoice_id/payments/:payment_id/void", controllers.VoidInvoicePayment())
+	incomingRoutes.POST("/invoices/:invoice_id/refunds", controllers.RefundInvoice())
 
 	// Mixed access routes - permission checked inside controller
 	incomingRoutes.GET("/invoices/:invoice_id", controllers.GetInvoice()) 
+	incomingRoutes.GET("/invoices/:invoice_id/payments", controllers.GetInvoicePayments())
 	
 
 	// Customer-specific routes
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func KitchenRoutes(incomingRoutes *gin.Engine) {
+	// Admin-only routes - restricted to kitchen staff
+	incomingRoutes.GET("/kitchen/tickets", controllers.GetKitchenTickets())
+	incomingRoutes.PATCH("/kitchen/tickets/:ticket_id", controllers.UpdateKitchenTicket())
+	incomingRoutes.PATCH("/kitchen/tickets/:ticket_id/items/:ticket_item_id", controllers.UpdateKitchenTicketItem())
+	incomingRoutes.GET("/kitchen/stream", controllers.StreamKitchenFeed())
+}
@@ -10,9 +10,12 @@ func MenuRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/menus", controllers.GetMenus())
 	incomingRoutes.GET("/menus/:menu_id", controllers.GetMenu())
 	incomingRoutes.GET("/menu-categories", controllers.GetMenuCategories())
+	incomingRoutes.GET("/menus/:menu_id/schedules", controllers.GetMenuSchedules())
 
 	// Admin-only routes - restricted to restaurant staff
 	incomingRoutes.POST("/menus", controllers.CreateMenu())
 	incomingRoutes.PATCH("/menus/:menu_id", controllers.UpdateMenu())
 	incomingRoutes.DELETE("/menus/:menu_id", controllers.DeleteMenu())
+	incomingRoutes.POST("/menus/:menu_id/schedules", controllers.CreateMenuSchedule())
+	incomingRoutes.DELETE("/menus/:menu_id/schedules/:schedule_id", controllers.DeleteMenuSchedule())
 }
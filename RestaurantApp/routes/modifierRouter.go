@@ -0,0 +1,19 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func ModifierRoutes(incomingRoutes *gin.Engine) {
+	// Public routes - accessible by all users (customers and admins)
+	incomingRoutes.GET("/foods/:food_id/modifier-groups", controllers.GetModifierGroups())
+
+	// Admin-only routes - restricted to restaurant staff
+	incomingRoutes.POST("/foods/:food_id/modifier-groups", controllers.CreateModifierGroup())
+	incomingRoutes.PATCH("/modifier-groups/:group_id", controllers.UpdateModifierGroup())
+	incomingRoutes.DELETE("/modifier-groups/:group_id", controllers.DeleteModifierGroup())
+	incomingRoutes.POST("/modifier-groups/:group_id/modifiers", controllers.CreateModifier())
+	incomingRoutes.PATCH("/modifiers/:modifier_id", controllers.UpdateModifier())
+	incomingRoutes.DELETE("/modifiers/:modifier_id", controllers.DeleteModifier())
+}
@@ -10,10 +10,13 @@ func OrderRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/orders", controllers.GetOrders())
 	incomingRoutes.PATCH("/orders/:order_id", controllers.UpdateOrder())
 	incomingRoutes.DELETE("/orders/:order_id", controllers.DeleteOrder())
+	incomingRoutes.POST("/orders/:order_id/transfer", controllers.TransferOrder())
 
 	// Mixed access routes - permission checked inside controller
 	incomingRoutes.GET("/orders/:order_id", controllers.GetOrder())
 	incomingRoutes.POST("/orders", controllers.CreateOrder())
+	incomingRoutes.POST("/orders/:order_id/status", controllers.TransitionOrderStatus())
+	incomingRoutes.GET("/orders/:order_id/history", controllers.GetOrderStatusHistory())
 
 	// Customer-specific routes
 	incomingRoutes.GET("/user/orders", controllers.GetUserOrders())
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func PaymentWebhookRoutes(incomingRoutes *gin.Engine) {
+	// Public route - called by the payment provider and authenticated by the webhook signature
+	incomingRoutes.POST("/payments/webhook", controllers.PaymentWebhook())
+}
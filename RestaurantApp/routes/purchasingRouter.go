@@ -0,0 +1,36 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func PurchasingRoutes(incomingRoutes *gin.Engine) {
+	// Admin-only routes - restricted to restaurant staff
+	incomingRoutes.GET("/suppliers", controllers.GetSuppliers())
+	incomingRoutes.GET("/suppliers/:supplier_id", controllers.GetSupplier())
+	incomingRoutes.POST("/suppliers", controllers.CreateSupplier())
+	incomingRoutes.PATCH("/suppliers/:supplier_id", controllers.UpdateSupplier())
+	incomingRoutes.DELETE("/suppliers/:supplier_id", controllers.DeleteSupplier())
+
+	// Stock items sold by a supplier
+	incomingRoutes.GET("/suppliers/:supplier_id/stock-items", controllers.GetStockItems())
+	incomingRoutes.POST("/suppliers/:supplier_id/stock-items", controllers.CreateStockItem())
+	incomingRoutes.PATCH("/stock-items/:stock_item_id", controllers.UpdateStockItem())
+	incomingRoutes.DELETE("/stock-items/:stock_item_id", controllers.DeleteStockItem())
+
+	// Purchase orders
+	incomingRoutes.GET("/purchase-orders", controllers.GetPurchaseOrders())
+	incomingRoutes.GET("/purchase-orders/:purchase_order_id", controllers.GetPurchaseOrder())
+	incomingRoutes.POST("/purchase-orders", controllers.CreatePurchaseOrder())
+	incomingRoutes.PATCH("/purchase-orders/:purchase_order_id", controllers.UpdatePurchaseOrder())
+	incomingRoutes.DELETE("/purchase-orders/:purchase_order_id", controllers.DeletePurchaseOrder())
+	incomingRoutes.GET("/purchase-orders/:purchase_order_id/document", controllers.GetPurchaseOrderDocument())
+	incomingRoutes.POST("/purchase-orders/:purchase_order_id/send", controllers.SendPurchaseOrder())
+	incomingRoutes.POST("/purchase-orders/:purchase_order_id/cancel", controllers.CancelPurchaseOrder())
+
+	// Goods receiving
+	incomingRoutes.GET("/purchase-orders/:purchase_order_id/receipts", controllers.GetGoodsReceipts())
+	incomingRoutes.POST("/purchase-orders/:purchase_order_id/receipts", controllers.ReceiveGoods())
+	incomingRoutes.POST("/goods-receipts/:receipt_id/reverse", controllers.ReverseGoodsReceipt())
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func ReportRoutes(incomingRoutes *gin.Engine) {
+	// Admin-only routes - restricted to restaurant staff
+	incomingRoutes.GET("/reports/sales", controllers.GetSalesReport())
+	incomingRoutes.GET("/reports/low-stock", controllers.GetLowStockReport())
+	incomingRoutes.GET("/reports/waste", controllers.GetWasteReport())
+	incomingRoutes.GET("/reports/food-cost", controllers.GetFoodCostReport())
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func ReservationRoutes(incomingRoutes *gin.Engine) {
+	// Public routes - accessible by all users (customers and admins)
+	incomingRoutes.GET("/reservations/availability", controllers.GetReservationAvailability())
+	incomingRoutes.POST("/reservations", controllers.CreateReservation())
+
+	// Routes for admins or the user who made the reservation
+	incomingRoutes.GET("/reservations/:reservation_id", controllers.GetReservation())
+	incomingRoutes.PATCH("/reservations/:reservation_id", controllers.UpdateReservation())
+	incomingRoutes.POST("/reservations/:reservation_id/status", controllers.UpdateReservationStatus())
+
+	// Admin-only routes - restricted to restaurant staff
+	incomingRoutes.GET("/reservations", controllers.GetReservations())
+}
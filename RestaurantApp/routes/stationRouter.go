@@ -0,0 +1,20 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func StationRoutes(incomingRoutes *gin.Engine) {
+	// Admin-only routes - restricted to kitchen staff
+	incomingRoutes.GET("/stations", controllers.GetStations())
+	incomingRoutes.POST("/stations", controllers.CreateStation())
+	incomingRoutes.PATCH("/stations/:station_id", controllers.UpdateStation())
+	incomingRoutes.DELETE("/stations/:station_id", controllers.DeleteStation())
+	incomingRoutes.GET("/stations/:station_id/queue", controllers.GetStationQueue())
+
+	// Food and menu category routing to stations
+	incomingRoutes.GET("/stations/:station_id/routes", controllers.GetStationRoutes())
+	incomingRoutes.POST("/stations/:station_id/routes", controllers.CreateStationRoute())
+	incomingRoutes.DELETE("/stations/:station_id/routes/:route_id", controllers.DeleteStationRoute())
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func StockCountRoutes(incomingRoutes *gin.Engine) {
+	// Admin-only routes - restricted to restaurant staff
+	incomingRoutes.GET("/stock-counts", controllers.GetStockCounts())
+	incomingRoutes.GET("/stock-counts/:stock_count_id", controllers.GetStockCount())
+	incomingRoutes.POST("/stock-counts", controllers.OpenStockCount())
+	incomingRoutes.POST("/stock-counts/:stock_count_id/close", controllers.CloseStockCount())
+	incomingRoutes.POST("/stock-counts/:stock_count_id/cancel", controllers.CancelStockCount())
+	incomingRoutes.GET("/stock-counts/:stock_count_id/variance", controllers.GetStockCountVariance())
+
+	// Counted quantities, entered from one or more devices
+	incomingRoutes.GET("/stock-counts/:stock_count_id/entries", controllers.GetStockCountEntries())
+	incomingRoutes.PUT("/stock-counts/:stock_count_id/entries", controllers.RecordStockCountEntries())
+	incomingRoutes.DELETE("/stock-counts/:stock_count_id/entries/:entry_id", controllers.DeleteStockCountEntry())
+}
@@ -15,4 +15,10 @@ func TableRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.POST("/tables", controllers.CreateTable())
 	incomingRoutes.PATCH("/tables/:table_id", controllers.UpdateTable())
 	incomingRoutes.DELETE("/tables/:table_id", controllers.DeleteTable())
+	incomingRoutes.GET("/floor-plan", controllers.GetFloorPlan())
+
+	// Tables pushed together for large parties
+	incomingRoutes.GET("/table-groups", controllers.GetTableGroups())
+	incomingRoutes.POST("/table-groups", controllers.CreateTableGroup())
+	incomingRoutes.DELETE("/table-groups/:table_group_id", controllers.ReleaseTableGroup())
 }
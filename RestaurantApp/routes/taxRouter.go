@@ -0,0 +1,19 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func TaxRoutes(incomingRoutes *gin.Engine) {
+	// Admin-only routes - tax categories assigned to foods
+	incomingRoutes.GET("/tax-categories", controllers.GetTaxCategories())
+	incomingRoutes.POST("/tax-categories", controllers.CreateTaxCategory())
+	incomingRoutes.PATCH("/tax-categories/:tax_category_id", controllers.UpdateTaxCategory())
+	incomingRoutes.DELETE("/tax-categories/:tax_category_id", controllers.DeleteTaxCategory())
+
+	// Tax rates charged on the foods of a category
+	incomingRoutes.POST("/tax-categories/:tax_category_id/rates", controllers.CreateTaxRate())
+	incomingRoutes.PATCH("/tax-categories/:tax_category_id/rates/:tax_rate_id", controllers.UpdateTaxRate())
+	incomingRoutes.DELETE("/tax-categories/:tax_category_id/rates/:tax_rate_id", controllers.DeleteTaxRate())
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func WaitlistRoutes(incomingRoutes *gin.Engine) {
+	// Admin-only routes - restricted to restaurant staff
+	incomingRoutes.GET("/waitlist", controllers.GetWaitlist())
+	incomingRoutes.GET("/waitlist/estimate", controllers.GetWaitEstimate())
+	incomingRoutes.POST("/waitlist", controllers.AddToWaitlist())
+	incomingRoutes.POST("/waitlist/:waitlist_id/notify", controllers.NotifyWaitlistEntry())
+	incomingRoutes.POST("/waitlist/:waitlist_id/seat", controllers.SeatWaitlistEntry())
+	incomingRoutes.POST("/waitlist/:waitlist_id/cancel", controllers.CancelWaitlistEntry())
+}
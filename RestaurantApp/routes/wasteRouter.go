@@ -0,0 +1,13 @@
+package routes
+
+import (
+	controllers "github.com/RestaurantApp/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func WasteRoutes(incomingRoutes *gin.Engine) {
+	// Admin-only routes - restricted to restaurant staff
+	incomingRoutes.GET("/waste", controllers.GetWasteEntries())
+	incomingRoutes.POST("/waste", controllers.CreateWasteEntry())
+	incomingRoutes.DELETE("/waste/:waste_id", controllers.DeleteWasteEntry())
+}